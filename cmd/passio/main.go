@@ -8,6 +8,7 @@ import (
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
 	"github.com/jayakrishnanMurali/passio/internal/cmd"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 )
 
 func main() {
@@ -38,6 +39,7 @@ func main() {
 	rootCmd := cmd.NewRootCmd(app)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		cleanup()
+		os.Exit(errs.ExitCode(err))
 	}
 }
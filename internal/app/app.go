@@ -1,23 +1,52 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
 	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/keystore"
 	"github.com/jayakrishnanMurali/passio/internal/storage"
 )
 
+var (
+	// ErrLocked is returned by operations that need passio to be unlocked.
+	ErrLocked = errors.New("passio is locked")
+	// ErrNoKey is returned when passio is unlocked but holds no master key.
+	ErrNoKey = errors.New("master key is not loaded")
+	// ErrDecrypt is returned when an entry's secret cannot be authenticated
+	// with the master key. Whether the data was corrupted or encrypted under
+	// another key cannot be told apart.
+	ErrDecrypt = errors.New("entry may be corrupted or key mismatch")
+)
+
 type App struct {
 	Storage    storage.Storage
 	Encryption crypto.Encryption
 	Config     *Config
+	Clipboard  clipboard.Clipboard
+
+	// KeyStore holds the master key between sessions when use_keychain is set
+	KeyStore keystore.KeyStore
+
+	// Strength overrides the estimator selected by the strength_estimator setting
+	Strength StrengthEstimator
+
+	commonPasswords commonPasswordList
 
 	// Session
+	key          []byte // Master key, only held in memory while unlocked
 	isLocked     bool
 	lastActivity time.Time
+	closeHooks   []func()
 	mu           sync.RWMutex
 }
 
@@ -27,17 +56,27 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	storage, err := storage.NewStorage(config.StorageType, config.DBPath)
+	store, err := storage.NewStorage(config.StorageType, config.DBPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	encryptions := crypto.NewAESEncryption()
+	if err := store.SetNameScope(storage.NameScope(config.NameUniqueness)); err != nil {
+		return nil, fmt.Errorf("failed to apply name uniqueness scope: %w", err)
+	}
+	store.SetLimits(config.EntryLimits())
+
+	encryptions, err := crypto.NewEncryption(config.Cipher)
+	if err != nil {
+		return nil, err
+	}
 
 	app := &App{
-		Storage:      storage,
+		Storage:      store,
 		Encryption:   encryptions,
 		Config:       config,
+		Clipboard:    clipboard.NewSystemClipboard(),
+		KeyStore:     keystore.NewSystemKeyStore(),
 		isLocked:     true,
 		lastActivity: time.Now(),
 	}
@@ -46,7 +85,10 @@ func New() (*App, error) {
 }
 
 func (a *App) IsInitialized() bool {
-	return len(a.Config.MasterHash) > 0
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.Config.HasMasterKey()
 }
 
 func (a *App) Lock() {
@@ -54,22 +96,91 @@ func (a *App) Lock() {
 	defer a.mu.Unlock()
 
 	a.isLocked = true
+	a.wipeKey()
+}
+
+// wipeKey overwrites the master key held in memory and forgets it. The caller
+// must hold the lock.
+func (a *App) wipeKey() {
+	crypto.Wipe(a.key)
+	a.key = nil
 }
 
 func (a *App) Unlock(masterPassword string) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if !a.Config.ValidateMasterPassword(a, masterPassword) {
+	key, err := a.Config.deriveMasterKey(a, masterPassword)
+	if err != nil || !a.Config.VerifyMasterKey(key) {
+		crypto.Wipe(key)
 		return errors.New("invalid master password")
 	}
 
+	return a.unlockWithKey(key)
+}
+
+// unlockWithKey unlocks passio with a verified master key, which it takes
+// ownership of. A master key still stored in the config by an older version
+// is replaced with its verifier. The caller must hold the lock.
+func (a *App) unlockWithKey(key []byte) error {
+	if len(a.Config.MasterVerifier) == 0 {
+		oldHash := a.Config.MasterHash
+		if err := a.Config.SetMasterKey(key, a.Config.Salt); err != nil {
+			a.Config.MasterVerifier, a.Config.MasterHash = nil, oldHash
+			crypto.Wipe(key)
+			return fmt.Errorf("failed to replace the stored master key with a verifier: %w", err)
+		}
+	}
+
+	a.wipeKey()
+	a.key = key
 	a.isLocked = false
 	a.lastActivity = time.Now()
 
 	return nil
 }
 
+// UnlockWithKeyStore unlocks passio with the master key saved in the keystore
+// by RememberKey, without prompting for the master password.
+func (a *App) UnlockWithKeyStore() error {
+	key, err := a.KeyStore.Retrieve(a.keyStoreAccount())
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.Config.VerifyMasterKey(key) {
+		crypto.Wipe(key)
+		return errors.New("key in keystore does not match the vault")
+	}
+
+	return a.unlockWithKey(key)
+}
+
+// RememberKey saves the master key in the keystore so that later sessions can
+// be unlocked with UnlockWithKeyStore. passio must be unlocked.
+func (a *App) RememberKey() error {
+	key, err := a.masterKey()
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(key)
+	return a.KeyStore.Store(a.keyStoreAccount(), key)
+}
+
+// ForgetKey removes the master key from the keystore.
+func (a *App) ForgetKey() error {
+	return a.KeyStore.Delete(a.keyStoreAccount())
+}
+
+// keyStoreAccount names the key of this vault in the keystore, so that vaults
+// with different configs do not share it.
+func (a *App) keyStoreAccount() string {
+	return a.Config.ConfigPath
+}
+
 func (a *App) IsLocked() bool {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -84,6 +195,19 @@ func (a *App) UpdateActivity() {
 	a.lastActivity = time.Now()
 }
 
+// SessionExpiry returns when the session will be locked for inactivity. It
+// reports false if passio is locked or auto-lock is disabled.
+func (a *App) SessionExpiry() (time.Time, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.isLocked || a.Config.AutoLockTimeout <= 0 {
+		return time.Time{}, false
+	}
+
+	return a.lastActivity.Add(time.Duration(a.Config.AutoLockTimeout) * time.Second), true
+}
+
 func (a *App) CheckAutoLock() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -92,29 +216,116 @@ func (a *App) CheckAutoLock() {
 		inactiveTime := time.Since(a.lastActivity)
 		if inactiveTime.Seconds() >= float64(a.Config.AutoLockTimeout) {
 			a.isLocked = true
+			a.wipeKey()
 		}
 	}
 }
 
-func (a *App) DecryptPassword(encryptedPassword []byte) (string, error) {
+// RefreshConfig re-reads and validates the config file so that changes made
+// by another process take effect without a restart. The session is kept,
+// unless the master key has changed, in which case passio is locked. The
+// storage is reopened if its location changed.
+//
+// Commands read Config and Storage without holding the app lock, so
+// RefreshConfig must only be called between commands, never while one runs.
+func (a *App) RefreshConfig() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	config, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		return err
+	}
+
+	if config.StorageType != a.Config.StorageType || config.DBPath != a.Config.DBPath {
+		store, err := storage.NewStorage(config.StorageType, config.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		if err := store.SetNameScope(storage.NameScope(config.NameUniqueness)); err != nil {
+			store.Close()
+			return fmt.Errorf("failed to apply name uniqueness scope: %w", err)
+		}
+
+		if err := a.Storage.Close(); err != nil {
+			store.Close()
+			return fmt.Errorf("failed to close previous storage: %w", err)
+		}
+		a.Storage = store
+	} else if config.NameUniqueness != a.Config.NameUniqueness {
+		if err := a.Storage.SetNameScope(storage.NameScope(config.NameUniqueness)); err != nil {
+			return fmt.Errorf("failed to apply name uniqueness scope: %w", err)
+		}
+	}
+
+	a.Storage.SetLimits(config.EntryLimits())
+
+	if config.Cipher != a.Config.Cipher {
+		encryption, err := crypto.NewEncryption(config.Cipher)
+		if err != nil {
+			return err
+		}
+		a.Encryption = encryption
+	}
+
+	if !bytes.Equal(config.MasterVerifier, a.Config.MasterVerifier) || !bytes.Equal(config.MasterHash, a.Config.MasterHash) {
+		a.isLocked = true
+		a.wipeKey()
+	}
+
+	// Update in place, as the config is shared by pointer
+	*a.Config = *config
+
+	return nil
+}
+
+// masterKey returns a copy of the current master key if passio is unlocked,
+// so that Lock can wipe the key while it is in use. Callers should wipe the
+// copy when done with it.
+func (a *App) masterKey() ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	if a.isLocked {
-		return "", errors.New("passio is locked")
+		return nil, ErrLocked
+	}
+	if len(a.key) == 0 {
+		return nil, ErrNoKey
 	}
 
-	decrypted, err := a.Encryption.Decrypt(encryptedPassword, a.Config.MasterHash)
+	return bytes.Clone(a.key), nil
+}
+
+// DecryptPassword decrypts an entry password encrypted with EncryptPassword.
+// It returns ErrLocked or ErrNoKey when no master key is available, and an
+// error wrapping ErrDecrypt when the ciphertext fails authentication.
+func (a *App) DecryptPassword(encryptedPassword []byte) (string, error) {
+	key, err := a.masterKey()
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt master password: %w", err)
+		return "", err
 	}
+	defer crypto.Wipe(key)
+
+	decrypted, err := a.Encryption.Decrypt(encryptedPassword, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password: %w", ErrDecrypt)
+	}
+	defer crypto.Wipe(decrypted)
 
 	return string(decrypted), nil
 }
 
 func (a *App) EncryptPassword(password string) ([]byte, error) {
-	if a.IsLocked() {
-		return nil, errors.New("passio is locked")
+	key, err := a.masterKey()
+	if err != nil {
+		return nil, err
 	}
+	defer crypto.Wipe(key)
 
-	encrypted, err := a.Encryption.Encrypt([]byte(password), a.Config.MasterHash)
+	plain := []byte(password)
+	defer crypto.Wipe(plain)
+
+	encrypted, err := a.Encryption.Encrypt(plain, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt password: %w", err)
 	}
@@ -122,7 +333,337 @@ func (a *App) EncryptPassword(password string) ([]byte, error) {
 	return encrypted, nil
 }
 
+// Rekey re-derives the master key from newPassword with the given salt and
+// key derivation function and re-encrypts every entry under it. Entries are
+// updated in a single transaction and restored if the new key cannot be saved
+// to the config.
+func (a *App) Rekey(currentPassword, newPassword string, salt []byte, params crypto.KDFParams) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.isLocked {
+		return ErrLocked
+	}
+
+	if !a.Config.ValidateMasterPassword(a, currentPassword) {
+		return errors.New("invalid master password")
+	}
+
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	newKey, err := a.Encryption.DeriveKeyWithParams(newPassword, salt, params)
+	if err != nil {
+		return fmt.Errorf("failed to derive new master key: %w", err)
+	}
+	// The new key is only kept once the vault has been rekeyed
+	rekeyed := false
+	defer func() {
+		if !rekeyed {
+			crypto.Wipe(newKey)
+		}
+	}()
+
+	reencrypted := make([]*storage.Entry, 0, len(entries))
+
+	for _, entry := range entries {
+		updated := *entry
+
+		updated.Password, err = a.reencrypt(entry.Password, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt password for entry %s: %w", entry.Name, err)
+		}
+
+		if len(entry.CustomFields) > 0 {
+			updated.CustomFields, err = a.reencrypt(entry.CustomFields, newKey)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt custom fields for entry %s: %w", entry.Name, err)
+			}
+		}
+
+		if len(entry.SecureNotes) > 0 {
+			updated.SecureNotes, err = a.reencrypt(entry.SecureNotes, newKey)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt notes for entry %s: %w", entry.Name, err)
+			}
+		}
+
+		reencrypted = append(reencrypted, &updated)
+	}
+
+	if err := a.Storage.UpdateSecrets(reencrypted); err != nil {
+		return fmt.Errorf("failed to re-encrypt entries: %w", err)
+	}
+
+	oldVerifier, oldHash, oldSalt, oldSaltLength, oldParams := a.Config.MasterVerifier, a.Config.MasterHash, a.Config.Salt, a.Config.SaltLength, a.Config.KDFParams()
+	a.Config.SetKDFParams(params)
+	if err := a.Config.SetMasterKey(newKey, salt); err != nil {
+		a.Config.MasterVerifier, a.Config.MasterHash, a.Config.Salt, a.Config.SaltLength = oldVerifier, oldHash, oldSalt, oldSaltLength
+		a.Config.SetKDFParams(oldParams)
+		if rerr := a.Storage.UpdateSecrets(entries); rerr != nil {
+			return fmt.Errorf("failed to save new master key: %w (restoring entries also failed: %v)", err, rerr)
+		}
+		return fmt.Errorf("failed to save new master key: %w", err)
+	}
+	a.wipeKey()
+	a.key = newKey
+	rekeyed = true
+
+	return nil
+}
+
+// MigrateStorage copies every entry into a new storage of the given type at
+// dsn and switches the config to it. The new storage must not exist yet and
+// is removed again if the migration fails. It returns the number of entries
+// migrated. Once the config is saved the migration has succeeded, so failing
+// to close the previous storage afterwards is only reported as a warning.
+func (a *App) MigrateStorage(storageType, dsn string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.isLocked {
+		return 0, ErrLocked
+	}
+
+	if _, err := os.Stat(dsn); err == nil {
+		return 0, fmt.Errorf("%w: %s already exists", storage.ErrInvalidOperation, dsn)
+	}
+
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	target, err := storage.NewStorage(storageType, dsn)
+	if err != nil {
+		return 0, err
+	}
+
+	fail := func(err error) (int, error) {
+		target.Close()
+		os.Remove(dsn)
+		return 0, err
+	}
+
+	if err := target.Initialize(); err != nil {
+		return fail(fmt.Errorf("failed to initialize target storage: %w", err))
+	}
+	if err := target.SetNameScope(storage.NameScope(a.Config.NameUniqueness)); err != nil {
+		return fail(fmt.Errorf("failed to apply name uniqueness scope: %w", err))
+	}
+	target.SetLimits(a.Config.EntryLimits())
+
+	for _, entry := range entries {
+		if err := target.AddEntry(entry); err != nil {
+			return fail(fmt.Errorf("failed to migrate entry %s: %w", entry.Name, err))
+		}
+	}
+
+	oldType, oldPath := a.Config.StorageType, a.Config.DBPath
+	a.Config.StorageType, a.Config.DBPath = storageType, dsn
+	if err := a.Config.Save(); err != nil {
+		a.Config.StorageType, a.Config.DBPath = oldType, oldPath
+		return fail(fmt.Errorf("failed to save config: %w", err))
+	}
+
+	previous := a.Storage
+	a.Storage = target
+	if err := previous.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close previous storage: %v\n", err)
+	}
+
+	return len(entries), nil
+}
+
+// reencrypt decrypts data with the current master key and encrypts it with
+// newKey. The caller must hold the lock.
+func (a *App) reencrypt(data, newKey []byte) ([]byte, error) {
+	plain, err := a.Encryption.Decrypt(data, a.key)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Wipe(plain)
+
+	return a.Encryption.Encrypt(plain, newKey)
+}
+
+// EncryptFields encrypts custom fields as a JSON object. An empty map is
+// stored as nil.
+func (a *App) EncryptFields(fields map[string]string) ([]byte, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom fields: %w", err)
+	}
+	defer crypto.Wipe(data)
+
+	key, err := a.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Wipe(key)
+
+	encrypted, err := a.Encryption.Encrypt(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt custom fields: %w", err)
+	}
+
+	return encrypted, nil
+}
+
+// DecryptFields decrypts custom fields encrypted with EncryptFields.
+func (a *App) DecryptFields(encrypted []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	if len(encrypted) == 0 {
+		return fields, nil
+	}
+
+	key, err := a.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Wipe(key)
+
+	data, err := a.Encryption.Decrypt(encrypted, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt custom fields: %w", err)
+	}
+	defer crypto.Wipe(data)
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal custom fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// EntryNotes returns the notes of an entry, decrypting them if they are kept
+// in SecureNotes.
+func (a *App) EntryNotes(entry *storage.Entry) (string, error) {
+	if len(entry.SecureNotes) == 0 {
+		return entry.Notes, nil
+	}
+
+	key, err := a.masterKey()
+	if err != nil {
+		return "", err
+	}
+	defer crypto.Wipe(key)
+
+	notes, err := a.Encryption.Decrypt(entry.SecureNotes, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt notes: %w", err)
+	}
+	defer crypto.Wipe(notes)
+
+	return string(notes), nil
+}
+
+// SetEntryNotes sets the notes of an entry. They are encrypted into
+// SecureNotes if the encrypt_notes setting is on or the entry's notes are
+// encrypted already, and kept in plain text otherwise.
+func (a *App) SetEntryNotes(entry *storage.Entry, notes string) error {
+	if !a.Config.EncryptNotes && len(entry.SecureNotes) == 0 {
+		entry.Notes = notes
+		return nil
+	}
+
+	entry.Notes = ""
+	if notes == "" {
+		entry.SecureNotes = nil
+		return nil
+	}
+
+	key, err := a.masterKey()
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(key)
+
+	plain := []byte(notes)
+	defer crypto.Wipe(plain)
+
+	entry.SecureNotes, err = a.Encryption.Encrypt(plain, key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt notes: %w", err)
+	}
+
+	return nil
+}
+
+// EncryptNotes moves the plain text notes of the given entries into
+// SecureNotes in a single transaction and returns the number of entries
+// changed. Entries without plain text notes are left alone, so it can safely
+// be run again.
+func (a *App) EncryptNotes(entries []*storage.Entry) (int, error) {
+	key, err := a.masterKey()
+	if err != nil {
+		return 0, err
+	}
+	defer crypto.Wipe(key)
+
+	var moved []*storage.Entry
+	for _, entry := range entries {
+		if entry.Notes == "" {
+			continue
+		}
+
+		// Plain text notes next to encrypted ones were written by a version
+		// of passio without encrypted notes, so they are the most recent
+		updated := *entry
+		updated.SecureNotes, err = a.Encryption.Encrypt([]byte(entry.Notes), key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt notes for entry %s: %w", entry.Name, err)
+		}
+		updated.Notes = ""
+
+		moved = append(moved, &updated)
+	}
+
+	if len(moved) == 0 {
+		return 0, nil
+	}
+
+	if err := a.Storage.UpdateSecrets(moved); err != nil {
+		return 0, fmt.Errorf("failed to store encrypted notes: %w", err)
+	}
+
+	return len(moved), nil
+}
+
+// LogAccess records a sensitive operation on an entry if the access log is
+// enabled in the configuration.
+func (a *App) LogAccess(entryName string, action storage.AccessAction) error {
+	if !a.Config.AccessLog {
+		return nil
+	}
+	return a.Storage.LogAccess(entryName, action)
+}
+
+// OnClose registers fn to be run once when the application is closed,
+// including when it is interrupted.
+func (a *App) OnClose(fn func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closeHooks = append(a.closeHooks, fn)
+}
+
 func (a *App) Close() error {
+	a.mu.Lock()
+	hooks := a.closeHooks
+	a.closeHooks = nil
+	a.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
 	if err := a.Storage.Close(); err != nil {
 		return fmt.Errorf("failed to close storage: %w", err)
 	}
@@ -136,10 +677,95 @@ func (a *App) CheckPasswordHealth(password string) map[string]bool {
 		"lowercase":    containsLowercase(password),
 		"numbers":      containsNumbers(password),
 		"specialChars": containsSpecialChars(password),
-		"notCommon":    !isCommonPassword(password),
+		"notCommon":    !a.IsCommonPassword(password),
+	}
+}
+
+// CheckContextualHealth reports whether a password avoids being derived from
+// the entry it protects: its username, its name or the host of its URL.
+func (a *App) CheckContextualHealth(password, username, name, rawURL string) map[string]bool {
+	lower := strings.ToLower(password)
+	host := urlHost(rawURL)
+
+	return map[string]bool{
+		"notUsername": username == "" || lower != strings.ToLower(username),
+		"notName":     name == "" || !strings.Contains(lower, strings.ToLower(name)),
+		"notURLHost":  host == "" || (lower != host && lower != strings.TrimPrefix(host, "www.")),
+	}
+}
+
+// patternRunLength is the shortest run of characters counted as a pattern
+const patternRunLength = 4
+
+// keyboardRows are runs of adjacent keys on a QWERTY keyboard
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// CheckPatternHealth reports whether a password avoids predictable patterns:
+// walks along a keyboard row (qwer, 4321), alphabetical sequences (abcd) and
+// repeated characters (aaaa).
+func (a *App) CheckPatternHealth(password string) map[string]bool {
+	lower := strings.ToLower(password)
+
+	return map[string]bool{
+		"notKeyboardWalk": !containsRun(lower, keyboardRows),
+		"notSequence":     !containsRun(lower, []string{"abcdefghijklmnopqrstuvwxyz"}),
+		"notRepeat":       !containsRepeat(lower),
 	}
 }
 
+// containsRun reports whether s contains patternRunLength consecutive
+// characters of one of the sequences, forwards or backwards.
+func containsRun(s string, sequences []string) bool {
+	for _, sequence := range sequences {
+		reversed := []byte(sequence)
+		for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+			reversed[i], reversed[j] = reversed[j], reversed[i]
+		}
+
+		for _, seq := range []string{sequence, string(reversed)} {
+			for i := 0; i+patternRunLength <= len(seq); i++ {
+				if strings.Contains(s, seq[i:i+patternRunLength]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// containsRepeat reports whether s repeats one character patternRunLength times in a row.
+func containsRepeat(s string) bool {
+	run := 0
+	var last rune
+	for i, r := range s {
+		if i > 0 && r == last {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= patternRunLength {
+			return true
+		}
+		last = r
+	}
+	return false
+}
+
+func urlHost(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
 func containsUppercase(s string) bool {
 	for _, r := range s {
 		if r >= 'A' && r <= 'Z' {
@@ -179,12 +805,47 @@ func containsSpecialChars(s string) bool {
 	return false
 }
 
-func isCommonPassword(password string) bool {
-	// TODO: Check against a list of common passwords from a file
-	commonPasswords := map[string]bool{
-		"password": true,
-		"123456":   true,
-		"qwerty":   true,
+// AgeDays returns the number of days since the entry's password was last
+// changed. Other updates, including 'pm update --touch', do not reset it.
+func (a *App) AgeDays(entry *storage.Entry) float64 {
+	return a.ageDays(entry, time.Now())
+}
+
+func (a *App) ageDays(entry *storage.Entry, now time.Time) float64 {
+	changed := entry.PasswordChangedAt
+	if changed.IsZero() {
+		changed = entry.CreatedAt
 	}
-	return commonPasswords[password]
+	return now.Sub(changed).Hours() / 24
+}
+
+// IsExpired reports whether the entry's password has expired: once its
+// ExpiresAt has passed if set, and otherwise when it is older than the
+// password_expiration setting. Without ExpiresAt, passwords never expire
+// when password_expiration is 0.
+func (a *App) IsExpired(entry *storage.Entry) bool {
+	return a.isExpired(entry, time.Now())
+}
+
+func (a *App) isExpired(entry *storage.Entry, now time.Time) bool {
+	if entry.ExpiresAt != nil {
+		return !entry.ExpiresAt.After(now)
+	}
+	return a.Config.PasswordExpiration > 0 && a.ageDays(entry, now) > float64(a.Config.PasswordExpiration)
+}
+
+// SecurityScore returns a composite vault hygiene score from 0 to 100.
+// Strong passwords account for 40% of the score, unexpired passwords for
+// 30% and unique (not reused) passwords for the remaining 30%.
+func (a *App) SecurityScore(total, weak, expired, reused int) int {
+	if total == 0 {
+		return 100
+	}
+
+	strong := float64(total-weak) / float64(total)
+	fresh := float64(total-expired) / float64(total)
+	unique := float64(total-reused) / float64(total)
+
+	score := strong*40 + fresh*30 + unique*30
+	return int(score + 0.5)
 }
@@ -0,0 +1,985 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/keystore"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// testPassword is the master password of vaults made by newTestApp
+const testPassword = "correct horse battery staple"
+
+// testKDFParams are the cheapest key derivation parameters accepted, which
+// keep the tests fast
+var testKDFParams = crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.KDFIterations}
+
+// newTestApp returns a locked app with an empty vault, whose master password
+// is testPassword, in a temporary home directory.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	a, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	a.Clipboard = clipboard.NewMemoryClipboard()
+	a.KeyStore = keystore.NewMemoryKeyStore()
+
+	salt := make([]byte, crypto.SaltLength)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	key, err := a.Encryption.DeriveKeyWithParams(testPassword, salt, testKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Config.SetKDFParams(testKDFParams)
+	if err := a.Config.SetMasterKey(key, salt); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Storage.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// unlockedTestApp returns newTestApp unlocked with testPassword.
+func unlockedTestApp(t *testing.T) *App {
+	t.Helper()
+	a := newTestApp(t)
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	return a
+}
+
+// addTestEntry adds an entry with the given password to the vault of a.
+func addTestEntry(t *testing.T, a *App, name, password string) *storage.Entry {
+	t.Helper()
+	encrypted, err := a.EncryptPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry(name, "user", encrypted)
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+// decrypt returns the plaintext of an entry password, failing the test if it
+// cannot be decrypted.
+func decrypt(t *testing.T, a *App, ciphertext []byte) string {
+	t.Helper()
+	plain, err := a.DecryptPassword(ciphertext)
+	if err != nil {
+		t.Fatalf("DecryptPassword: %v", err)
+	}
+	return plain
+}
+
+func TestRefreshConfig(t *testing.T) {
+	a := unlockedTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	// Another process changes settings in the config file
+	other, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.ClipboardTimeout = 5
+	other.PasswordLength = 24
+	other.Cipher = crypto.ChaCha20Poly1305
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := a.Config
+	if err := a.RefreshConfig(); err != nil {
+		t.Fatalf("RefreshConfig: %v", err)
+	}
+	if a.Config != config {
+		t.Error("RefreshConfig replaced the shared config instead of updating it")
+	}
+	if a.Config.ClipboardTimeout != 5 || a.Config.PasswordLength != 24 {
+		t.Errorf("clipboard_timeout and password_length = %d and %d, want 5 and 24", a.Config.ClipboardTimeout, a.Config.PasswordLength)
+	}
+	if _, ok := a.Encryption.(*crypto.ChaChaEncryption); !ok {
+		t.Errorf("encryption = %T after reload, want ChaCha20-Poly1305", a.Encryption)
+	}
+
+	// The session survives a reload that leaves the master key alone
+	if a.IsLocked() {
+		t.Fatal("RefreshConfig locked passio although the master key did not change")
+	}
+	entry, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatalf("GetEntry after reload: %v", err)
+	}
+	if got := decrypt(t, a, entry.Password); got != "hunter2" {
+		t.Errorf("password after reload = %q, want hunter2", got)
+	}
+}
+
+func TestRefreshConfigLocksOnNewMasterKey(t *testing.T) {
+	a := unlockedTestApp(t)
+
+	other, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.MasterVerifier = masterKeyVerifier([]byte("another master key"))
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.RefreshConfig(); err != nil {
+		t.Fatalf("RefreshConfig: %v", err)
+	}
+	if !a.IsLocked() {
+		t.Error("passio stayed unlocked after the master key changed")
+	}
+}
+
+func TestRefreshConfigKeepsConfigOnInvalidFile(t *testing.T) {
+	a := unlockedTestApp(t)
+
+	other, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.PasswordLength = 4
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := a.Config.PasswordLength
+	if err := a.RefreshConfig(); err == nil {
+		t.Fatal("RefreshConfig accepted an invalid config")
+	}
+	if a.Config.PasswordLength != before {
+		t.Errorf("password_length = %d after a failed reload, want %d", a.Config.PasswordLength, before)
+	}
+}
+
+func TestCipherDefaultsToAES(t *testing.T) {
+	a := unlockedTestApp(t)
+	if a.Config.Cipher != "" {
+		t.Fatalf("new config sets cipher %q", a.Config.Cipher)
+	}
+	if _, ok := a.Encryption.(*crypto.AESEncryption); !ok {
+		t.Fatalf("encryption = %T with no cipher set, want AES-GCM", a.Encryption)
+	}
+
+	encrypted, err := a.EncryptPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted[0] != crypto.CipherAESGCM {
+		t.Errorf("version byte = %d, want AES-GCM", encrypted[0])
+	}
+}
+
+func TestChangingCipherKeepsOldSecretsReadable(t *testing.T) {
+	a := unlockedTestApp(t)
+	before := addTestEntry(t, a, "github", "hunter2")
+
+	other, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SetConfigValue("cipher", crypto.ChaCha20Poly1305); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RefreshConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := addTestEntry(t, a, "gitlab", "swordfish")
+	if after.Password[0] != crypto.CipherChaCha20Poly1305 {
+		t.Errorf("version byte = %d after switching to ChaCha20-Poly1305", after.Password[0])
+	}
+	if got := decrypt(t, a, before.Password); got != "hunter2" {
+		t.Errorf("AES-GCM secret decrypts to %q", got)
+	}
+	if got := decrypt(t, a, after.Password); got != "swordfish" {
+		t.Errorf("ChaCha20-Poly1305 secret decrypts to %q", got)
+	}
+}
+
+// testMasterKey derives the master key of newTestApp vaults.
+func testMasterKey(t *testing.T, a *App) []byte {
+	t.Helper()
+	key, err := a.Encryption.DeriveKeyWithParams(testPassword, a.Config.Salt, testKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestUnlockNewVault(t *testing.T) {
+	a := newTestApp(t)
+	if !a.IsLocked() {
+		t.Fatal("a new app is unlocked")
+	}
+
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if a.IsLocked() {
+		t.Fatal("still locked after Unlock")
+	}
+	entry := addTestEntry(t, a, "github", "hunter2")
+	if got := decrypt(t, a, entry.Password); got != "hunter2" {
+		t.Errorf("decrypted %q", got)
+	}
+
+	saved, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.MasterVerifier) == 0 || len(saved.MasterHash) != 0 {
+		t.Errorf("saved config has a %d-byte verifier and a %d-byte master key, want only a verifier",
+			len(saved.MasterVerifier), len(saved.MasterHash))
+	}
+}
+
+func TestUnlockRejectsWrongPassword(t *testing.T) {
+	a := newTestApp(t)
+
+	for _, password := range []string{"", "wrong", testPassword + " "} {
+		if err := a.Unlock(password); err == nil {
+			t.Errorf("Unlock(%q) succeeded", password)
+		}
+		if !a.IsLocked() {
+			t.Fatalf("Unlock(%q) unlocked passio", password)
+		}
+		if a.Config.ValidateMasterPassword(a, password) {
+			t.Errorf("ValidateMasterPassword(%q) = true", password)
+		}
+	}
+}
+
+func TestUnlockWithKeyStore(t *testing.T) {
+	a := newTestApp(t)
+
+	// Nothing remembered yet
+	if err := a.UnlockWithKeyStore(); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("UnlockWithKeyStore with an empty keystore: err = %v, want ErrNotFound", err)
+	}
+
+	// A stale key, such as one from before the master password changed
+	if err := a.KeyStore.Store(a.keyStoreAccount(), bytes.Repeat([]byte{1}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.UnlockWithKeyStore(); err == nil || !a.IsLocked() {
+		t.Fatalf("UnlockWithKeyStore with a stale key: err = %v, locked = %v", err, a.IsLocked())
+	}
+
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	entry := addTestEntry(t, a, "github", "hunter2")
+	if err := a.RememberKey(); err != nil {
+		t.Fatalf("RememberKey: %v", err)
+	}
+	a.Lock()
+
+	if err := a.UnlockWithKeyStore(); err != nil {
+		t.Fatalf("UnlockWithKeyStore: %v", err)
+	}
+	if a.IsLocked() {
+		t.Fatal("UnlockWithKeyStore left passio locked")
+	}
+	if got := decrypt(t, a, entry.Password); got != "hunter2" {
+		t.Errorf("decrypted %q with the remembered key, want hunter2", got)
+	}
+
+	if err := a.ForgetKey(); err != nil {
+		t.Fatal(err)
+	}
+	a.Lock()
+	if err := a.UnlockWithKeyStore(); !errors.Is(err, keystore.ErrNotFound) {
+		t.Errorf("UnlockWithKeyStore after ForgetKey: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUnlockMigratesLegacyMasterHash(t *testing.T) {
+	a := newTestApp(t)
+	key := testMasterKey(t, a)
+
+	// Older versions stored the master key itself
+	a.Config.MasterVerifier, a.Config.MasterHash = nil, key
+	if err := a.Config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Unlock("wrong"); err == nil {
+		t.Fatal("a legacy vault unlocked with the wrong password")
+	}
+	if len(a.Config.MasterHash) == 0 {
+		t.Fatal("a failed unlock replaced the legacy master key")
+	}
+
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if len(a.Config.MasterHash) != 0 || !bytes.Equal(a.Config.MasterVerifier, masterKeyVerifier(key)) {
+		t.Error("unlocking did not replace the master key with its verifier")
+	}
+
+	saved, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.MasterHash) != 0 {
+		t.Error("the saved config still holds the master key")
+	}
+	if !saved.VerifyMasterKey(key) {
+		t.Error("the saved verifier does not verify the master key")
+	}
+	data, err := os.ReadFile(a.Config.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(base64.StdEncoding.EncodeToString(key))) {
+		t.Error("the config file still holds the master key")
+	}
+}
+
+func TestConfigDoesNotDecryptEntries(t *testing.T) {
+	a := unlockedTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+
+	saved, err := readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, candidate := range map[string][]byte{
+		"verifier": saved.MasterVerifier,
+		"salt":     saved.Salt,
+	} {
+		if _, err := a.Encryption.Decrypt(entry.Password, candidate); err == nil {
+			t.Errorf("the %s stored in the config decrypts entries", name)
+		}
+	}
+
+	// The verifier is not the key in disguise
+	if bytes.Equal(saved.MasterVerifier, testMasterKey(t, a)) {
+		t.Error("the verifier is the master key")
+	}
+}
+
+func TestDecryptPasswordErrors(t *testing.T) {
+	a := unlockedTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+
+	otherKey := bytes.Repeat([]byte{0x42}, crypto.KeyLength)
+	underOtherKey, err := a.Encryption.Encrypt([]byte("hunter2"), otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(entry.Password)
+	tampered[len(tampered)-1] ^= 0x01
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		want       error
+	}{
+		{"wrong key", underOtherKey, ErrDecrypt},
+		{"tampered ciphertext", tampered, ErrDecrypt},
+		{"truncated ciphertext", entry.Password[:5], ErrDecrypt},
+		{"empty ciphertext", nil, ErrDecrypt},
+	}
+	for _, test := range tests {
+		if _, err := a.DecryptPassword(test.ciphertext); !errors.Is(err, test.want) {
+			t.Errorf("%s: DecryptPassword() error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Unlocked but without a key, which only a bug could cause
+	a.mu.Lock()
+	key := a.key
+	a.key = nil
+	a.mu.Unlock()
+	if _, err := a.DecryptPassword(entry.Password); !errors.Is(err, ErrNoKey) {
+		t.Errorf("missing key: DecryptPassword() error = %v, want %v", err, ErrNoKey)
+	}
+	if _, err := a.EncryptPassword("hunter2"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("missing key: EncryptPassword() error = %v, want %v", err, ErrNoKey)
+	}
+	a.mu.Lock()
+	a.key = key
+	a.mu.Unlock()
+
+	a.Lock()
+	if _, err := a.DecryptPassword(entry.Password); !errors.Is(err, ErrLocked) {
+		t.Errorf("locked: DecryptPassword() error = %v, want %v", err, ErrLocked)
+	}
+	if _, err := a.EncryptPassword("hunter2"); !errors.Is(err, ErrLocked) {
+		t.Errorf("locked: EncryptPassword() error = %v, want %v", err, ErrLocked)
+	}
+}
+
+// heldKey returns the master key buffer held by a, not a copy of it.
+func heldKey(a *App) []byte {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.key
+}
+
+func assertWiped(t *testing.T, key []byte) {
+	t.Helper()
+	if len(key) == 0 {
+		t.Fatal("no key was held")
+	}
+	if !bytes.Equal(key, make([]byte, len(key))) {
+		t.Error("the master key was not zeroed")
+	}
+}
+
+func TestLockWipesKey(t *testing.T) {
+	a := unlockedTestApp(t)
+	key := heldKey(a)
+	if !bytes.Equal(key, testMasterKey(t, a)) {
+		t.Fatal("the held key is not the master key")
+	}
+
+	a.Lock()
+	assertWiped(t, key)
+	if heldKey(a) != nil {
+		t.Error("the app still holds a key once locked")
+	}
+}
+
+func TestAutoLockWipesKey(t *testing.T) {
+	a := unlockedTestApp(t)
+	key := heldKey(a)
+
+	a.Config.AutoLockTimeout = 1
+	a.mu.Lock()
+	a.lastActivity = time.Now().Add(-time.Minute)
+	a.mu.Unlock()
+
+	a.CheckAutoLock()
+	if !a.IsLocked() {
+		t.Fatal("CheckAutoLock did not lock an inactive session")
+	}
+	assertWiped(t, key)
+}
+
+func TestUnlockAgainWipesPreviousKey(t *testing.T) {
+	a := unlockedTestApp(t)
+	key := heldKey(a)
+
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	assertWiped(t, key)
+	if entry := addTestEntry(t, a, "github", "hunter2"); decrypt(t, a, entry.Password) != "hunter2" {
+		t.Error("the new key does not work")
+	}
+}
+
+func TestMasterKeyIsACopy(t *testing.T) {
+	a := unlockedTestApp(t)
+
+	copied, err := a.masterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypto.Wipe(copied)
+
+	// Wiping the copy leaves the held key usable
+	if entry := addTestEntry(t, a, "github", "hunter2"); decrypt(t, a, entry.Password) != "hunter2" {
+		t.Error("wiping a copy of the key broke the held key")
+	}
+}
+
+// Run with -race: encryption and decryption share the cached master key with
+// Lock, Unlock and auto-lock.
+func TestConcurrentKeyUse(t *testing.T) {
+	a := unlockedTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	a.Config.AutoLockTimeout = 3600
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	failures := make(chan error, 16)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				plain, err := a.DecryptPassword(entry.Password)
+				if err == nil && plain != "hunter2" {
+					err = fmt.Errorf("decrypted %q", plain)
+				}
+				if err == nil {
+					_, err = a.EncryptPassword("hunter2")
+				}
+				// A lock may come at any time, but never a wrong or wiped key
+				if err != nil && !errors.Is(err, ErrLocked) {
+					failures <- err
+					return
+				}
+				a.UpdateActivity()
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		a.Lock()
+		a.CheckAutoLock()
+		a.SessionExpiry()
+		if err := a.Unlock(testPassword); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	close(failures)
+	for err := range failures {
+		t.Errorf("concurrent use of the key failed: %v", err)
+	}
+}
+
+func TestLegacyPBKDF2VaultUnlocks(t *testing.T) {
+	a := newTestApp(t)
+	legacyKey := pbkdf2.Key([]byte(testPassword), a.Config.Salt, 4096, crypto.KeyLength, sha256.New)
+
+	// Configs written before the choice of KDF have no kdf settings and hold
+	// the master key itself
+	a.Config.KDF, a.Config.KDFIterations = "", 0
+	a.Config.MasterVerifier, a.Config.MasterHash = nil, legacyKey
+	if err := a.Config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(a.Config.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"kdf`)) {
+		t.Fatalf("legacy config has kdf settings: %s", data)
+	}
+	a.Config, err = readConfigFile(a.Config.ConfigPath, a.Config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Unlock(testPassword); err != nil {
+		t.Fatalf("a legacy vault does not unlock: %v", err)
+	}
+	if !bytes.Equal(heldKey(a), legacyKey) {
+		t.Error("unlocked with a key other than the legacy key")
+	}
+	if a.Config.KDFParams().Normalized().Iterations != crypto.KDFIterations {
+		t.Errorf("legacy vault uses %+v", a.Config.KDFParams().Normalized())
+	}
+}
+
+func TestCustomFieldsRoundTrip(t *testing.T) {
+	a := unlockedTestApp(t)
+
+	tests := []map[string]string{
+		{"pin": "1234"},
+		{"pin": "1234", "recovery code": "abcd-efgh", "empty": ""},
+		{"unicode": "pässwörd ✓"},
+	}
+
+	for _, fields := range tests {
+		encrypted, err := a.EncryptFields(fields)
+		if err != nil {
+			t.Fatalf("EncryptFields(%v): %v", fields, err)
+		}
+		for _, value := range fields {
+			if value != "" && bytes.Contains(encrypted, []byte(value)) {
+				t.Errorf("encrypted fields contain the plaintext %q", value)
+			}
+		}
+
+		decrypted, err := a.DecryptFields(encrypted)
+		if err != nil {
+			t.Fatalf("DecryptFields: %v", err)
+		}
+		if !maps.Equal(decrypted, fields) {
+			t.Errorf("DecryptFields = %v, want %v", decrypted, fields)
+		}
+	}
+
+	// No fields are stored as nothing and read back as an empty map
+	encrypted, err := a.EncryptFields(map[string]string{})
+	if err != nil || encrypted != nil {
+		t.Errorf("EncryptFields of no fields = %v, %v, want nil", encrypted, err)
+	}
+	decrypted, err := a.DecryptFields(nil)
+	if err != nil || decrypted == nil || len(decrypted) != 0 {
+		t.Errorf("DecryptFields(nil) = %v, %v, want an empty map", decrypted, err)
+	}
+
+	if _, err := a.DecryptFields([]byte("not encrypted")); err == nil {
+		t.Error("DecryptFields accepted data that is not encrypted")
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	at := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name       string
+		expiration int
+		entry      storage.Entry
+		want       bool
+	}{
+		{"younger than expiration", 90, storage.Entry{PasswordChangedAt: now.Add(-89 * day)}, false},
+		{"exactly at expiration", 90, storage.Entry{PasswordChangedAt: now.Add(-90 * day)}, false},
+		{"just past expiration", 90, storage.Entry{PasswordChangedAt: now.Add(-90*day - time.Second)}, true},
+		{"expiration disabled", 0, storage.Entry{PasswordChangedAt: now.Add(-1000 * day)}, false},
+		// --touch moves UpdatedAt but not the password change time
+		{"touched but not changed", 90, storage.Entry{UpdatedAt: now, PasswordChangedAt: now.Add(-91 * day)}, true},
+		{"falls back to creation time", 90, storage.Entry{CreatedAt: now.Add(-91 * day)}, true},
+		{"expires_at in the future", 90, storage.Entry{PasswordChangedAt: now.Add(-1000 * day), ExpiresAt: at(now.Add(time.Second))}, false},
+		{"exactly at expires_at", 90, storage.Entry{PasswordChangedAt: now, ExpiresAt: at(now)}, true},
+		{"expires_at with expiration disabled", 0, storage.Entry{PasswordChangedAt: now, ExpiresAt: at(now.Add(-day))}, true},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.Config.PasswordExpiration = tt.expiration
+			if got := a.isExpired(&tt.entry, now); got != tt.want {
+				t.Errorf("isExpired = %v, want %v (age %.2f days)", got, tt.want, a.ageDays(&tt.entry, now))
+			}
+		})
+	}
+}
+
+func TestAgeDaysCountsFromPasswordChange(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	entry := &storage.Entry{
+		CreatedAt:         now.AddDate(0, 0, -100),
+		UpdatedAt:         now,
+		PasswordChangedAt: now.AddDate(0, 0, -30),
+	}
+
+	a := newTestApp(t)
+	if got := a.ageDays(entry, now); got != 30 {
+		t.Errorf("ageDays = %v, want 30", got)
+	}
+}
+
+// recordingEncryption keeps the plaintext passed to Encrypt and returned by
+// Decrypt, so tests can check that callers wipe it.
+type recordingEncryption struct {
+	crypto.Encryption
+	plaintexts [][]byte
+}
+
+func (e *recordingEncryption) Encrypt(data, key []byte) ([]byte, error) {
+	e.plaintexts = append(e.plaintexts, data)
+	return e.Encryption.Encrypt(data, key)
+}
+
+func (e *recordingEncryption) Decrypt(data, key []byte) ([]byte, error) {
+	plain, err := e.Encryption.Decrypt(data, key)
+	e.plaintexts = append(e.plaintexts, plain)
+	return plain, err
+}
+
+func TestEntryNotesWipesPlaintext(t *testing.T) {
+	a := unlockedTestApp(t)
+	a.Config.EncryptNotes = true
+	recorder := &recordingEncryption{Encryption: a.Encryption}
+	a.Encryption = recorder
+
+	entry := storage.NewEntry("github", "user", []byte("ciphertext"))
+	if err := a.SetEntryNotes(entry, "recovery codes"); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := a.EntryNotes(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes != "recovery codes" {
+		t.Fatalf("EntryNotes = %q, want the notes that were set", notes)
+	}
+
+	if len(recorder.plaintexts) != 2 {
+		t.Fatalf("%d plaintexts recorded, want 2", len(recorder.plaintexts))
+	}
+	for i, plain := range recorder.plaintexts {
+		if !bytes.Equal(plain, make([]byte, len(plain))) {
+			t.Errorf("plaintext %d was not zeroed: %q", i, plain)
+		}
+	}
+}
+
+func TestSetEntryNotes(t *testing.T) {
+	tests := []struct {
+		name          string
+		encryptNotes  bool
+		alreadySecure bool
+		notes         string
+		wantPlain     string
+		wantSecure    bool
+	}{
+		{"plain text by default", false, false, "recovery codes", "recovery codes", false},
+		{"encrypted with encrypt_notes", true, false, "recovery codes", "", true},
+		{"kept encrypted without encrypt_notes", false, true, "recovery codes", "", true},
+		{"cleared", true, true, "", "", false},
+	}
+
+	a := unlockedTestApp(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.Config.EncryptNotes = tt.encryptNotes
+			entry := storage.NewEntry("github", "user", []byte("ciphertext"))
+			if tt.alreadySecure {
+				a.Config.EncryptNotes = true
+				if err := a.SetEntryNotes(entry, "old notes"); err != nil {
+					t.Fatal(err)
+				}
+				a.Config.EncryptNotes = tt.encryptNotes
+			}
+
+			if err := a.SetEntryNotes(entry, tt.notes); err != nil {
+				t.Fatal(err)
+			}
+			if entry.Notes != tt.wantPlain || (len(entry.SecureNotes) > 0) != tt.wantSecure {
+				t.Errorf("Notes = %q, SecureNotes set = %v, want %q, %v", entry.Notes, len(entry.SecureNotes) > 0, tt.wantPlain, tt.wantSecure)
+			}
+			if notes, err := a.EntryNotes(entry); err != nil || notes != tt.notes {
+				t.Errorf("EntryNotes = %q, %v, want %q", notes, err, tt.notes)
+			}
+		})
+	}
+}
+
+func TestEncryptNotes(t *testing.T) {
+	a := unlockedTestApp(t)
+	for name, notes := range map[string]string{"github": "recovery codes", "gitlab": ""} {
+		entry := addTestEntry(t, a, name, "hunter2")
+		entry.Notes = notes
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := a.EncryptNotes(entries)
+	if err != nil || moved != 1 {
+		t.Fatalf("EncryptNotes = %d, %v, want 1 entry moved", moved, err)
+	}
+
+	github, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if github.Notes != "" || len(github.SecureNotes) == 0 {
+		t.Errorf("notes of github were not moved: Notes = %q", github.Notes)
+	}
+	if notes, err := a.EntryNotes(github); err != nil || notes != "recovery codes" {
+		t.Errorf("EntryNotes = %q, %v, want the original notes", notes, err)
+	}
+
+	// Running again changes nothing
+	entries, err = a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved, err := a.EncryptNotes(entries); err != nil || moved != 0 {
+		t.Errorf("second EncryptNotes = %d, %v, want nothing moved", moved, err)
+	}
+}
+
+// failingCloseStorage is a storage whose Close fails after closing it.
+type failingCloseStorage struct {
+	storage.Storage
+}
+
+func (s failingCloseStorage) Close() error {
+	s.Storage.Close()
+	return errors.New("close failed")
+}
+
+func TestMigrateStorage(t *testing.T) {
+	for _, closeFails := range []bool{false, true} {
+		t.Run(fmt.Sprintf("close fails %v", closeFails), func(t *testing.T) {
+			a := unlockedTestApp(t)
+			addTestEntry(t, a, "github", "hunter2")
+			if closeFails {
+				a.Storage = failingCloseStorage{a.Storage}
+			}
+
+			dsn := filepath.Join(t.TempDir(), "migrated.db")
+			migrated, err := a.MigrateStorage(string(storage.SQLite), dsn)
+			if err != nil {
+				t.Fatalf("MigrateStorage: %v", err)
+			}
+			if migrated != 1 {
+				t.Errorf("migrated %d entries, want 1", migrated)
+			}
+			if a.Config.DBPath != dsn {
+				t.Errorf("DBPath = %s, want %s", a.Config.DBPath, dsn)
+			}
+
+			// The app uses the new storage even if the old one failed to close
+			if _, isOld := a.Storage.(failingCloseStorage); isOld {
+				t.Fatal("the app still uses the previous storage")
+			}
+			if _, err := a.Storage.GetEntry("github"); err != nil {
+				t.Errorf("GetEntry from the new storage: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateStorageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, a *App, dsn string)
+		wantErr error
+	}{
+		{"target exists", func(t *testing.T, a *App, dsn string) {
+			if err := os.WriteFile(dsn, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}, storage.ErrInvalidOperation},
+		{"locked", func(t *testing.T, a *App, dsn string) {
+			a.Lock()
+		}, ErrLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := unlockedTestApp(t)
+			addTestEntry(t, a, "github", "hunter2")
+			dbPath := a.Config.DBPath
+			dsn := filepath.Join(t.TempDir(), "migrated.db")
+			tt.prepare(t, a, dsn)
+
+			if _, err := a.MigrateStorage(string(storage.SQLite), dsn); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MigrateStorage: err = %v, want %v", err, tt.wantErr)
+			}
+			if a.Config.DBPath != dbPath {
+				t.Errorf("DBPath = %s after a failed migration, want %s", a.Config.DBPath, dbPath)
+			}
+			if _, err := a.Storage.GetEntry("github"); err != nil {
+				t.Errorf("GetEntry after a failed migration: %v", err)
+			}
+		})
+	}
+}
+
+func TestSecurityScore(t *testing.T) {
+	tests := []struct {
+		name                        string
+		total, weak, expired, reuse int
+		want                        int
+	}{
+		{"empty vault", 0, 0, 0, 0, 100},
+		{"all good", 10, 0, 0, 0, 100},
+		{"all weak", 10, 10, 0, 0, 60},
+		{"all expired", 10, 0, 10, 0, 70},
+		{"all reused", 10, 0, 0, 10, 70},
+		{"everything wrong", 4, 4, 4, 4, 0},
+		{"rounded to nearest", 3, 1, 0, 0, 87},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.SecurityScore(tt.total, tt.weak, tt.expired, tt.reuse); got != tt.want {
+				t.Errorf("SecurityScore(%d, %d, %d, %d) = %d, want %d", tt.total, tt.weak, tt.expired, tt.reuse, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckContextualHealth(t *testing.T) {
+	tests := []struct {
+		name                             string
+		password, username, entry, url   string
+		notUsername, notName, notURLHost bool
+	}{
+		{"unrelated", "Xk9#mP2$vL7@", "alice", "github", "https://github.com", true, true, true},
+		{"same as username", "Alice", "alice", "github", "", false, true, true},
+		{"contains entry name", "MyGitHub2024", "alice", "github", "", true, false, true},
+		{"same as URL host", "www.github.com", "alice", "work", "https://www.github.com/login", true, true, false},
+		{"same as host without www", "github.com", "alice", "work", "https://www.github.com", true, true, false},
+		{"no context", "hunter2", "", "", "", true, true, true},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.CheckContextualHealth(tt.password, tt.username, tt.entry, tt.url)
+			if got["notUsername"] != tt.notUsername || got["notName"] != tt.notName || got["notURLHost"] != tt.notURLHost {
+				t.Errorf("CheckContextualHealth = %v, want notUsername %v, notName %v, notURLHost %v",
+					got, tt.notUsername, tt.notName, tt.notURLHost)
+			}
+		})
+	}
+}
+
+func TestCheckPatternHealth(t *testing.T) {
+	tests := []struct {
+		password                                string
+		notKeyboardWalk, notSequence, notRepeat bool
+	}{
+		{"Xk9#mP2$vL7@qR4!", true, true, true},
+		{"myqwertypass", false, true, true},
+		{"pass4321word", false, true, true},
+		{"LKJHgfds", false, true, true},
+		{"zxcv!", false, true, true},
+		{"xyzabcd!", true, false, true},
+		{"DCBA9", true, false, true},
+		{"paaaass", true, true, false},
+		{"1111", true, true, false},
+		// Runs of three are not patterns
+		{"qweXabcX111", true, true, true},
+		{"", true, true, true},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		got := a.CheckPatternHealth(tt.password)
+		if got["notKeyboardWalk"] != tt.notKeyboardWalk || got["notSequence"] != tt.notSequence || got["notRepeat"] != tt.notRepeat {
+			t.Errorf("CheckPatternHealth(%q) = %v, want notKeyboardWalk %v, notSequence %v, notRepeat %v",
+				tt.password, got, tt.notKeyboardWalk, tt.notSequence, tt.notRepeat)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultCommonPasswords is the list of common passwords used unless the
+// common_passwords_path setting names another one
+//
+//go:embed common_passwords.txt
+var defaultCommonPasswords string
+
+// commonPasswordList caches the set of common passwords, loaded on first use
+// and again whenever the configured path changes.
+type commonPasswordList struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	set    map[string]struct{}
+}
+
+// contains reports whether password is in the list at path, or in the
+// embedded default list if path is empty or cannot be read.
+func (l *commonPasswordList) contains(path, password string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded || l.path != path {
+		set, err := loadCommonPasswords(path)
+		if err != nil {
+			set, _ = parseCommonPasswords(strings.NewReader(defaultCommonPasswords))
+		}
+		l.path, l.set, l.loaded = path, set, true
+	}
+
+	_, ok := l.set[strings.ToLower(password)]
+	return ok
+}
+
+// loadCommonPasswords reads the newline-delimited list at path, or the
+// embedded default list if path is empty.
+func loadCommonPasswords(path string) (map[string]struct{}, error) {
+	if path == "" {
+		return parseCommonPasswords(strings.NewReader(defaultCommonPasswords))
+	}
+
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open common passwords list: %w", err)
+	}
+	defer file.Close()
+
+	return parseCommonPasswords(file)
+}
+
+// parseCommonPasswords reads one password per line, lower-cased. Blank lines
+// and lines starting with # are skipped.
+func parseCommonPasswords(r io.Reader) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read common passwords list: %w", err)
+	}
+
+	return set, nil
+}
+
+// IsCommonPassword reports whether password, ignoring case, is in the list
+// of common passwords named by the common_passwords_path setting, or in the
+// embedded default list. The list is read once and cached.
+func (a *App) IsCommonPassword(password string) bool {
+	return a.commonPasswords.contains(a.Config.CommonPasswordsPath, password)
+}
+
+// LoadCommonPasswords reads the list of common passwords at path, or the
+// embedded default list if path is empty, into the cache. Unlike
+// IsCommonPassword, it reports a list that cannot be read.
+func (a *App) LoadCommonPasswords(path string) error {
+	set, err := loadCommonPasswords(path)
+	if err != nil {
+		return err
+	}
+
+	a.commonPasswords.mu.Lock()
+	defer a.commonPasswords.mu.Unlock()
+	a.commonPasswords.path, a.commonPasswords.set, a.commonPasswords.loaded = path, set, true
+	return nil
+}
@@ -1,31 +1,70 @@
 package app
 
 import (
-	"encoding/json"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 )
 
 const (
 	defaultConfigDir  = ".passio"
 	defaultConfigFile = "config.json"
 	defaultDBFile     = "passio.db"
+	defaultBackupDir  = "backups"
 )
 
+const (
+	// GeneratorRandom generates passwords of random characters
+	GeneratorRandom = "random"
+	// GeneratorPassphrase generates diceware passphrases
+	GeneratorPassphrase = "passphrase"
+)
+
+// DefaultMaxImportEntries is the most entries a single import may add unless
+// the max_import_entries setting says otherwise
+const DefaultMaxImportEntries = 100000
+
+// DefaultPassphraseWords is the number of words in generated passphrases
+// unless the passphrase_words setting says otherwise
+const DefaultPassphraseWords = 6
+
 type Config struct {
-	// Password hash and salt
-	MasterHash []byte `json:"master_hash"`
+	// Master key verifier and salt. The master key itself is never stored;
+	// MasterVerifier only lets a derived key be checked.
+	MasterVerifier []byte `json:"master_verifier,omitempty"`
+	// MasterHash is the master key as stored by vaults created before
+	// MasterVerifier. It is replaced by a verifier on the next unlock.
+	MasterHash []byte `json:"master_hash,omitempty"`
 	Salt       []byte `json:"salt"`
+	SaltLength int    `json:"salt_length,omitempty"` // Expected length of Salt in bytes
+
+	// Key derivation function of the master key: "pbkdf2" or "argon2id". An
+	// empty KDF is PBKDF2 with crypto.KDFIterations iterations.
+	KDF            string `json:"kdf,omitempty"`
+	KDFIterations  uint32 `json:"kdf_iterations,omitempty"`  // PBKDF2 iterations or Argon2id passes
+	KDFMemory      uint32 `json:"kdf_memory,omitempty"`      // Argon2id memory in KiB
+	KDFParallelism uint8  `json:"kdf_parallelism,omitempty"` // Argon2id threads
 
 	// Storage
-	StorageType string `json:"storage_type"`
-	DBPath      string `json:"db_path"`
+	StorageType    string `json:"storage_type"`
+	DBPath         string `json:"db_path"`
+	NameUniqueness string `json:"name_uniqueness"` // "global" or "folder"
 
 	// App settings
 	ConfigPath    string `json:"config_path"`
 	LastBackup    string `json:"last_backup"`
 	BackupEnabled bool   `json:"backup_enabled"`
+	BackupDir     string `json:"backup_dir,omitempty"` // Defaults to backups in the config directory
+	ExportDir     string `json:"export_dir,omitempty"` // Defaults to the current directory
 
 	// Security settings
 	PasswordLength        int  `json:"password_length"`
@@ -35,6 +74,31 @@ type Config struct {
 	RequireMasterPassword bool `json:"require_master_password"`
 	BackupEncrypted       bool `json:"backup_encrypted"`
 	PasswordExpiration    int  `json:"password_expiration"`
+	AccessLog             bool `json:"access_log"`
+	UseKeychain           bool `json:"use_keychain"`
+	EncryptNotes          bool `json:"encrypt_notes"`   // Store the notes of new and updated entries encrypted
+	ClipboardPrint        bool `json:"clipboard_print"` // Print values meant for the clipboard when no clipboard is available
+
+	// Cipher encrypts new and updated secrets: "aes-gcm" or "chacha20-poly1305".
+	// Secrets encrypted with either cipher can always be decrypted.
+	Cipher string `json:"cipher,omitempty"`
+
+	// StrengthEstimator rates passwords in audit and stats: "heuristic" or "zxcvbn"
+	StrengthEstimator string `json:"strength_estimator,omitempty"`
+	// CommonPasswordsPath names a newline-delimited list of common passwords
+	// replacing the built-in one
+	CommonPasswordsPath string `json:"common_passwords_path,omitempty"`
+
+	// PasswordGenerator is used by add and update --generate: "random" or "passphrase"
+	PasswordGenerator string `json:"password_generator,omitempty"`
+	PassphraseWords   int    `json:"passphrase_words,omitempty"` // Defaults to DefaultPassphraseWords
+
+	// Limits guarding against malformed imports, in entries and bytes. Zero
+	// uses DefaultMaxImportEntries and storage.DefaultLimits.
+	MaxImportEntries  int `json:"max_import_entries,omitempty"`
+	MaxNameLength     int `json:"max_name_length,omitempty"`
+	MaxPasswordLength int `json:"max_password_length,omitempty"`
+	MaxNotesLength    int `json:"max_notes_length,omitempty"`
 }
 
 func loadConfig() (*Config, error) {
@@ -46,9 +110,19 @@ func loadConfig() (*Config, error) {
 	configPath := filepath.Join(configDir, defaultConfigFile)
 	dbPath := filepath.Join(configDir, defaultDBFile)
 
+	// Use the first config file present in a supported format
+	for _, name := range configFileNames {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			configPath = path
+			break
+		}
+	}
+
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		config := &Config{
 			StorageType:           "sqlite",
+			NameUniqueness:        "global",
 			DBPath:                dbPath,
 			ConfigPath:            configPath,
 			PasswordLength:        16,
@@ -63,13 +137,19 @@ func loadConfig() (*Config, error) {
 		return config, config.Save()
 	}
 
+	return readConfigFile(configPath, dbPath)
+}
+
+// readConfigFile reads and validates the config file at configPath. An empty
+// database path defaults to dbPath.
+func readConfigFile(configPath, dbPath string) (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := unmarshalConfig(data, configFormat(configPath), &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
@@ -77,13 +157,124 @@ func loadConfig() (*Config, error) {
 		config.DBPath = dbPath
 	}
 
-	if config.ConfigPath == "" {
-		config.ConfigPath = configPath
+	// The config file location is where it was loaded from
+	config.ConfigPath = configPath
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	return &config, nil
 }
 
+// EntryLimits returns the maximum sizes of entry fields.
+func (c *Config) EntryLimits() storage.Limits {
+	return storage.Limits{
+		MaxNameLength:     c.MaxNameLength,
+		MaxPasswordLength: c.MaxPasswordLength,
+		MaxNotesLength:    c.MaxNotesLength,
+	}.Normalized()
+}
+
+// ImportLimit returns the most entries a single import may add.
+func (c *Config) ImportLimit() int {
+	if c.MaxImportEntries == 0 {
+		return DefaultMaxImportEntries
+	}
+	return c.MaxImportEntries
+}
+
+// KDFParams returns the key derivation function and parameters of the master key.
+func (c *Config) KDFParams() crypto.KDFParams {
+	return crypto.KDFParams{
+		Algorithm:   c.KDF,
+		Iterations:  c.KDFIterations,
+		Memory:      c.KDFMemory,
+		Parallelism: c.KDFParallelism,
+	}
+}
+
+// SetKDFParams records the key derivation function the master key is derived
+// with. It is saved along with the master key by SetMasterKey.
+func (c *Config) SetKDFParams(params crypto.KDFParams) {
+	params = params.Normalized()
+	c.KDF = params.Algorithm
+	c.KDFIterations = params.Iterations
+	c.KDFMemory = params.Memory
+	c.KDFParallelism = params.Parallelism
+}
+
+// validate checks the settings against the same limits as config set.
+func (c *Config) validate() error {
+	switch {
+	case c.PasswordLength != 0 && c.PasswordLength < 8:
+		return fmt.Errorf("password_length must be at least 8")
+	case c.ClipboardTimeout < 0, c.AutoLockTimeout < 0:
+		return fmt.Errorf("timeout values must be non-negative")
+	case c.PasswordExpiration < 0:
+		return fmt.Errorf("password_expiration must be non-negative")
+	case c.PassphraseWords < 0:
+		return fmt.Errorf("passphrase_words must be positive")
+	case c.MaxImportEntries < 0, c.MaxNameLength < 0, c.MaxPasswordLength < 0, c.MaxNotesLength < 0:
+		return fmt.Errorf("limits must be positive")
+	}
+
+	if _, err := crypto.NewEncryption(c.Cipher); err != nil {
+		return err
+	}
+
+	if err := c.validateSalt(); err != nil {
+		return err
+	}
+
+	if err := c.KDFParams().Validate(); err != nil {
+		return err
+	}
+
+	switch storage.NameScope(c.NameUniqueness) {
+	case "", storage.NameScopeGlobal, storage.NameScopeFolder:
+	default:
+		return fmt.Errorf("name_uniqueness must be global or folder")
+	}
+
+	switch c.StrengthEstimator {
+	case "", EstimatorHeuristic, EstimatorZxcvbn:
+	default:
+		return fmt.Errorf("strength_estimator must be heuristic or zxcvbn")
+	}
+
+	switch c.PasswordGenerator {
+	case "", GeneratorRandom, GeneratorPassphrase:
+	default:
+		return fmt.Errorf("password_generator must be random or passphrase")
+	}
+
+	return nil
+}
+
+// validateSalt checks that the salt of an initialized vault has the recorded
+// length, so a truncated or corrupt salt is reported as such instead of as a
+// wrong master password. Configs written before the length was recorded are
+// expected to hold a salt of crypto.SaltLength bytes.
+func (c *Config) validateSalt() error {
+	if !c.HasMasterKey() {
+		return nil
+	}
+
+	expected := c.SaltLength
+	if expected == 0 {
+		expected = crypto.SaltLength
+	}
+	if expected < crypto.MinSaltLength {
+		return fmt.Errorf("salt_length must be at least %d bytes, got %d", crypto.MinSaltLength, expected)
+	}
+	if len(c.Salt) != expected {
+		return fmt.Errorf("salt is %d bytes but %d bytes are expected; the config file may be corrupt", len(c.Salt), expected)
+	}
+
+	return nil
+}
+
 func (c *Config) Save() error {
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(c.ConfigPath)
@@ -91,8 +282,8 @@ func (c *Config) Save() error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Marshal config to JSON
-	data, err := json.MarshalIndent(c, "", "  ")
+	// Marshal config in the format of the config file
+	data, err := marshalConfig(c, configFormat(c.ConfigPath))
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -105,6 +296,37 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// SaveEdited replaces the config file with data, a hand-edited copy of it in
+// the same format. The file is left untouched if data cannot be parsed, fails
+// validation or changes the master key or salt, which only 'pm rekey' may do.
+func (c *Config) SaveEdited(data []byte) error {
+	var edited Config
+	if err := unmarshalConfig(data, configFormat(c.ConfigPath), &edited); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if edited.DBPath == "" {
+		edited.DBPath = c.DBPath
+	}
+	edited.ConfigPath = c.ConfigPath
+
+	if !bytes.Equal(edited.MasterVerifier, c.MasterVerifier) || !bytes.Equal(edited.MasterHash, c.MasterHash) ||
+		!bytes.Equal(edited.Salt, c.Salt) || edited.SaltLength != c.SaltLength ||
+		edited.KDFParams().Normalized() != c.KDFParams().Normalized() {
+		return fmt.Errorf("master_verifier, salt, salt_length and the kdf settings cannot be edited; use 'pm rekey' to change them")
+	}
+
+	if err := edited.validate(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(c.ConfigPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
 func getConfigDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -115,15 +337,105 @@ func getConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// masterVerifierMessage is authenticated with the master key to produce the
+// stored verifier, from which the key cannot be recovered
+const masterVerifierMessage = "passio master key verifier"
+
+func masterKeyVerifier(masterKey []byte) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(masterVerifierMessage))
+	return mac.Sum(nil)
+}
+
+// HasMasterKey reports whether a master key has been set.
+func (c *Config) HasMasterKey() bool {
+	return len(c.MasterVerifier) > 0 || len(c.MasterHash) > 0
+}
+
+// SetMasterKey records a verifier of masterKey, derived from the master
+// password with salt, and saves the config. The key itself is not stored.
 func (c *Config) SetMasterKey(masterKey, salt []byte) error {
-	c.MasterHash = masterKey
+	c.MasterVerifier = masterKeyVerifier(masterKey)
+	c.MasterHash = nil
 	c.Salt = salt
+	c.SaltLength = len(salt)
 	return c.Save()
 }
 
+// VerifyMasterKey reports whether masterKey is the vault's master key.
+func (c *Config) VerifyMasterKey(masterKey []byte) bool {
+	if len(c.MasterVerifier) > 0 {
+		return hmac.Equal(masterKeyVerifier(masterKey), c.MasterVerifier)
+	}
+	return len(c.MasterHash) > 0 && subtle.ConstantTimeCompare(masterKey, c.MasterHash) == 1
+}
+
+// deriveMasterKey derives the master key from password with the vault's salt
+// and key derivation function.
+func (c *Config) deriveMasterKey(app *App, password string) ([]byte, error) {
+	return app.Encryption.DeriveKeyWithParams(password, c.Salt, c.KDFParams())
+}
+
 func (c *Config) ValidateMasterPassword(app *App, password string) bool {
-	derivedKey := app.Encryption.DeriveKey(password, c.Salt)
-	return string(derivedKey) == string(c.MasterHash)
+	derivedKey, err := c.deriveMasterKey(app, password)
+	if err != nil {
+		return false
+	}
+	return c.VerifyMasterKey(derivedKey)
+}
+
+// BackupDirectory returns the directory backups are written to by default:
+// the backup_dir setting, or else the backups directory next to the config.
+func (c *Config) BackupDirectory() string {
+	if c.BackupDir != "" {
+		return expandHome(c.BackupDir)
+	}
+	return filepath.Join(filepath.Dir(c.ConfigPath), defaultBackupDir)
+}
+
+// ExportDirectory returns the directory exports are written to by default:
+// the export_dir setting, or else the current directory.
+func (c *Config) ExportDirectory() string {
+	if c.ExportDir != "" {
+		return expandHome(c.ExportDir)
+	}
+	return "."
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+// ConfigSettings are the non-secret settings that can be changed with SetConfigValue
+var ConfigSettings = []string{
+	"password_length", "use_special_chars", "clipboard_timeout", "auto_lock_timeout",
+	"require_master_pass", "backup_encrypted", "password_expiration", "name_uniqueness", "access_log",
+	"backup_dir", "export_dir", "strength_estimator", "common_passwords_path", "use_keychain",
+	"encrypt_notes", "clipboard_print", "cipher", "password_generator", "passphrase_words",
+	"max_import_entries", "max_name_length", "max_password_length", "max_notes_length",
+}
+
+// ReadOnlySettings are the key derivation settings, which can only change by
+// re-encrypting the vault
+var ReadOnlySettings = []string{"kdf_algorithm", "kdf_iterations", "kdf_memory", "kdf_parallelism", "key_length", "salt_length"}
+
+// Settings returns the value of every non-secret setting, including the
+// read-only ones, keyed by setting name.
+func (c *Config) Settings() map[string]interface{} {
+	settings := make(map[string]interface{}, len(ConfigSettings)+len(ReadOnlySettings))
+	for _, key := range slices.Concat(ConfigSettings, ReadOnlySettings) {
+		settings[key] = c.GetConfigValue(key)
+	}
+	return settings
 }
 
 func (c *Config) GetConfigValue(key string) interface{} {
@@ -142,6 +454,68 @@ func (c *Config) GetConfigValue(key string) interface{} {
 		return c.BackupEncrypted
 	case "password_expiration":
 		return c.PasswordExpiration
+	case "access_log":
+		return c.AccessLog
+	case "use_keychain":
+		return c.UseKeychain
+	case "encrypt_notes":
+		return c.EncryptNotes
+	case "clipboard_print":
+		return c.ClipboardPrint
+	case "cipher":
+		if c.Cipher == "" {
+			return crypto.AESGCM
+		}
+		return c.Cipher
+	case "kdf_algorithm":
+		return c.KDFParams().Name()
+	case "kdf_iterations":
+		return c.KDFParams().Normalized().Iterations
+	case "kdf_memory":
+		return c.KDFParams().Normalized().Memory
+	case "kdf_parallelism":
+		return c.KDFParams().Normalized().Parallelism
+	case "key_length":
+		return crypto.KeyLength
+	case "salt_length":
+		if c.SaltLength == 0 {
+			return len(c.Salt)
+		}
+		return c.SaltLength
+	case "backup_dir":
+		return c.BackupDirectory()
+	case "export_dir":
+		return c.ExportDirectory()
+	case "name_uniqueness":
+		if c.NameUniqueness == "" {
+			return "global"
+		}
+		return c.NameUniqueness
+	case "common_passwords_path":
+		return c.CommonPasswordsPath
+	case "strength_estimator":
+		if c.StrengthEstimator == "" {
+			return EstimatorHeuristic
+		}
+		return c.StrengthEstimator
+	case "password_generator":
+		if c.PasswordGenerator == "" {
+			return GeneratorRandom
+		}
+		return c.PasswordGenerator
+	case "passphrase_words":
+		if c.PassphraseWords == 0 {
+			return DefaultPassphraseWords
+		}
+		return c.PassphraseWords
+	case "max_import_entries":
+		return c.ImportLimit()
+	case "max_name_length":
+		return c.EntryLimits().MaxNameLength
+	case "max_password_length":
+		return c.EntryLimits().MaxPasswordLength
+	case "max_notes_length":
+		return c.EntryLimits().MaxNotesLength
 	default:
 		return nil
 	}
@@ -191,6 +565,102 @@ func (c *Config) SetConfigValue(key string, value interface{}) error {
 		} else {
 			return fmt.Errorf("invalid value type for password_expiration")
 		}
+	case "access_log":
+		if v, ok := value.(bool); ok {
+			c.AccessLog = v
+		} else {
+			return fmt.Errorf("invalid value type for access_log")
+		}
+	case "use_keychain":
+		if v, ok := value.(bool); ok {
+			c.UseKeychain = v
+		} else {
+			return fmt.Errorf("invalid value type for use_keychain")
+		}
+	case "encrypt_notes":
+		if v, ok := value.(bool); ok {
+			c.EncryptNotes = v
+		} else {
+			return fmt.Errorf("invalid value type for encrypt_notes")
+		}
+	case "clipboard_print":
+		if v, ok := value.(bool); ok {
+			c.ClipboardPrint = v
+		} else {
+			return fmt.Errorf("invalid value type for clipboard_print")
+		}
+	case "cipher":
+		if v, ok := value.(string); ok {
+			c.Cipher = v
+		} else {
+			return fmt.Errorf("invalid value type for cipher")
+		}
+	case "backup_dir":
+		if v, ok := value.(string); ok {
+			c.BackupDir = v
+		} else {
+			return fmt.Errorf("invalid value type for backup_dir")
+		}
+	case "export_dir":
+		if v, ok := value.(string); ok {
+			c.ExportDir = v
+		} else {
+			return fmt.Errorf("invalid value type for export_dir")
+		}
+	case "common_passwords_path":
+		if v, ok := value.(string); ok {
+			c.CommonPasswordsPath = v
+		} else {
+			return fmt.Errorf("invalid value type for common_passwords_path")
+		}
+	case "name_uniqueness":
+		if v, ok := value.(string); ok {
+			c.NameUniqueness = v
+		} else {
+			return fmt.Errorf("invalid value type for name_uniqueness")
+		}
+	case "strength_estimator":
+		if v, ok := value.(string); ok {
+			c.StrengthEstimator = v
+		} else {
+			return fmt.Errorf("invalid value type for strength_estimator")
+		}
+	case "password_generator":
+		if v, ok := value.(string); ok {
+			c.PasswordGenerator = v
+		} else {
+			return fmt.Errorf("invalid value type for password_generator")
+		}
+	case "passphrase_words":
+		if v, ok := value.(int); ok {
+			c.PassphraseWords = v
+		} else {
+			return fmt.Errorf("invalid value type for passphrase_words")
+		}
+	case "max_import_entries":
+		if v, ok := value.(int); ok {
+			c.MaxImportEntries = v
+		} else {
+			return fmt.Errorf("invalid value type for max_import_entries")
+		}
+	case "max_name_length":
+		if v, ok := value.(int); ok {
+			c.MaxNameLength = v
+		} else {
+			return fmt.Errorf("invalid value type for max_name_length")
+		}
+	case "max_password_length":
+		if v, ok := value.(int); ok {
+			c.MaxPasswordLength = v
+		} else {
+			return fmt.Errorf("invalid value type for max_password_length")
+		}
+	case "max_notes_length":
+		if v, ok := value.(int); ok {
+			c.MaxNotesLength = v
+		} else {
+			return fmt.Errorf("invalid value type for max_notes_length")
+		}
 	default:
 		return fmt.Errorf("unknown configuration key: %s", key)
 	}
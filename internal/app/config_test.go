@@ -0,0 +1,225 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+)
+
+func TestBackupDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join(home, ".passio", "backups")
+	if got := config.BackupDirectory(); got != want {
+		t.Errorf("default BackupDirectory() = %s, want %s under the config directory", got, want)
+	}
+	if filepath.Dir(config.BackupDirectory()) != filepath.Dir(config.ConfigPath) {
+		t.Errorf("default backups are not next to the config file %s", config.ConfigPath)
+	}
+
+	config.BackupDir = "~/vault-backups"
+	if got, want := config.BackupDirectory(), filepath.Join(home, "vault-backups"); got != want {
+		t.Errorf("BackupDirectory() with backup_dir ~/vault-backups = %s, want %s", got, want)
+	}
+
+	config.BackupDir = "/srv/backups"
+	if got := config.BackupDirectory(); got != "/srv/backups" {
+		t.Errorf("BackupDirectory() with an absolute backup_dir = %s, want /srv/backups", got)
+	}
+}
+
+func TestExportDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	config := &Config{}
+	if got := config.ExportDirectory(); got != "." {
+		t.Errorf("default ExportDirectory() = %s, want the current directory", got)
+	}
+
+	config.ExportDir = "~/exports"
+	if got, want := config.ExportDirectory(), filepath.Join(home, "exports"); got != want {
+		t.Errorf("ExportDirectory() with export_dir ~/exports = %s, want %s", got, want)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := map[string]string{
+		"~":          home,
+		"~/backups":  filepath.Join(home, "backups"),
+		"~other/dir": "~other/dir",
+		"/abs/path":  "/abs/path",
+		"relative":   "relative",
+	}
+	for path, want := range tests {
+		if got := expandHome(path); got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestKDFSettings(t *testing.T) {
+	tests := []struct {
+		params crypto.KDFParams
+		want   map[string]interface{}
+	}{
+		{
+			crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: 600000},
+			map[string]interface{}{"kdf_algorithm": crypto.KDFAlgorithm, "kdf_iterations": uint32(600000), "key_length": crypto.KeyLength},
+		},
+		{
+			crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: 3, Memory: 65536, Parallelism: 4},
+			map[string]interface{}{"kdf_algorithm": crypto.KDFArgon2id, "kdf_iterations": uint32(3), "kdf_memory": uint32(65536), "kdf_parallelism": uint8(4)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.params.Algorithm, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			config, err := loadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.SetKDFParams(tt.params)
+			config.Salt = make([]byte, crypto.SaltLength)
+
+			settings := config.Settings()
+			for key, want := range tt.want {
+				if got := settings[key]; got != want {
+					t.Errorf("%s = %v (%T), want %v (%T)", key, got, got, want, want)
+				}
+			}
+			if got := settings["salt_length"]; got != crypto.SaltLength {
+				t.Errorf("salt_length = %v, want %d", got, crypto.SaltLength)
+			}
+			for _, key := range ReadOnlySettings {
+				if _, ok := settings[key]; !ok {
+					t.Errorf("Settings() is missing the read-only setting %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		prefix string
+	}{
+		{"config.json", "json", "{"},
+		{"config.toml", "toml", "access_log = true"},
+		{"config.yaml", "yaml", "access_log: true"},
+		{"config.yml", "yaml", "access_log: true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+
+			if got := configFormat(tt.name); got != tt.format {
+				t.Errorf("configFormat(%s) = %s, want %s", tt.name, got, tt.format)
+			}
+
+			saved := &Config{
+				StorageType:        "sqlite",
+				ConfigPath:         filepath.Join(home, ".passio", tt.name),
+				Salt:               bytes.Repeat([]byte{0xa5}, crypto.SaltLength),
+				PasswordLength:     24,
+				ClipboardTimeout:   45,
+				PasswordExpiration: 30,
+				AccessLog:          true,
+				BackupDir:          "/srv/backups",
+			}
+			if err := saved.Save(); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			data, err := os.ReadFile(saved.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), tt.prefix) {
+				t.Errorf("%s does not look like %s:\n%s", tt.name, tt.format, data)
+			}
+
+			// The config file is found by its name alone
+			loaded, err := loadConfig()
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if loaded.ConfigPath != saved.ConfigPath {
+				t.Errorf("loadConfig read %s, want %s", loaded.ConfigPath, saved.ConfigPath)
+			}
+			if !bytes.Equal(loaded.Salt, saved.Salt) || loaded.PasswordLength != 24 || loaded.ClipboardTimeout != 45 ||
+				loaded.PasswordExpiration != 30 || !loaded.AccessLog || loaded.BackupDir != "/srv/backups" {
+				t.Errorf("loadConfig = %+v, want the saved settings %+v", loaded, saved)
+			}
+		})
+	}
+}
+
+func TestValidateSalt(t *testing.T) {
+	verifier := []byte("verifier")
+	tests := []struct {
+		name       string
+		verifier   []byte
+		salt       int
+		saltLength int
+		wantErr    bool
+	}{
+		{"recorded length", verifier, 24, 24, false},
+		{"legacy config without length", verifier, crypto.SaltLength, 0, false},
+		{"truncated salt", verifier, 20, 24, true},
+		{"legacy truncated salt", verifier, 16, 0, true},
+		{"recorded length too short", verifier, 8, 8, true},
+		{"not initialized", nil, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{MasterVerifier: tt.verifier, Salt: make([]byte, tt.salt), SaltLength: tt.saltLength}
+			if err := config.validateSalt(); (err != nil) != tt.wantErr {
+				t.Errorf("validateSalt: err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaltLengthIsRecorded(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SetMasterKey(make([]byte, crypto.KeyLength), make([]byte, 24)); err != nil {
+		t.Fatal(err)
+	}
+	if config.SaltLength != 24 {
+		t.Errorf("SaltLength = %d after SetMasterKey with a 24 byte salt", config.SaltLength)
+	}
+
+	// A config file whose salt no longer matches the length fails to load
+	config.Salt = config.Salt[:20]
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "salt is 20 bytes but 24 bytes are expected") {
+		t.Errorf("loading a config with a truncated salt: err = %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the supported config file names, in order of preference
+// when more than one exists.
+var configFileNames = []string{"config.json", "config.toml", "config.yaml", "config.yml"}
+
+// configFormat returns the config file format for path based on its
+// extension. JSON is used for unknown extensions.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return "toml"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// marshalConfig encodes the config in the given format. TOML and YAML are
+// produced from the JSON encoding so that field names match and binary
+// fields such as the master hash are stored as base64.
+func marshalConfig(c *Config, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil || format == "json" {
+		return data, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case nil:
+			// Neither TOML nor YAML can hold a null value that survives a round-trip
+			delete(fields, key)
+		case float64:
+			if v == math.Trunc(v) {
+				fields[key] = int64(v)
+			}
+		}
+	}
+
+	switch format {
+	case "toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(fields); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "yaml":
+		return yaml.Marshal(fields)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+}
+
+// unmarshalConfig decodes data in the given format into c.
+func unmarshalConfig(data []byte, format string, c *Config) error {
+	if format == "json" {
+		return json.Unmarshal(data, c)
+	}
+
+	var fields map[string]interface{}
+	switch format {
+	case "toml":
+		if err := toml.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported config format: %s", format)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
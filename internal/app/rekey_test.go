@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+const newTestPassword = "new master password"
+
+// addSecretEntry adds an entry whose password, custom fields and notes are
+// all encrypted, named after its password.
+func addSecretEntry(t *testing.T, a *App, password string) {
+	t.Helper()
+	encrypted, err := a.EncryptPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry(password, "user", encrypted)
+	entry.CustomFields, err = a.EncryptFields(map[string]string{"pin": password + "-pin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Config.EncryptNotes = true
+	if err := a.SetEntryNotes(entry, password+" notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// secrets returns every encrypted secret in the vault of a.
+func secrets(t *testing.T, a *App) map[string][]byte {
+	t.Helper()
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := make(map[string][]byte)
+	for _, entry := range entries {
+		all[entry.Name+" password"] = entry.Password
+		all[entry.Name+" fields"] = entry.CustomFields
+		all[entry.Name+" notes"] = entry.SecureNotes
+	}
+	return all
+}
+
+// assertSecretsDecrypt checks that every secret in the vault of a decrypts
+// with key, and that the entries decrypt to what addSecretEntry stored.
+func assertSecretsDecrypt(t *testing.T, a *App, key []byte) {
+	t.Helper()
+	for name, secret := range secrets(t, a) {
+		if _, err := a.Encryption.Decrypt(secret, key); err != nil {
+			t.Errorf("%s does not decrypt: %v", name, err)
+		}
+	}
+
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if got := decrypt(t, a, entry.Password); got != entry.Name {
+			t.Errorf("password of %s = %q", entry.Name, got)
+		}
+		fields, err := a.DecryptFields(entry.CustomFields)
+		if err != nil || fields["pin"] != entry.Name+"-pin" {
+			t.Errorf("fields of %s = %v, %v", entry.Name, fields, err)
+		}
+		notes, err := a.EntryNotes(entry)
+		if err != nil || notes != entry.Name+" notes" {
+			t.Errorf("notes of %s = %q, %v", entry.Name, notes, err)
+		}
+	}
+}
+
+func TestRekey(t *testing.T) {
+	a := unlockedTestApp(t)
+	for _, password := range []string{"hunter2", "swordfish", "letmein"} {
+		addSecretEntry(t, a, password)
+	}
+	oldKey := testMasterKey(t, a)
+
+	salt := bytes.Repeat([]byte{9}, crypto.SaltLength)
+	if err := a.Rekey(testPassword, newTestPassword, salt, testKDFParams); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	newKey, err := a.Encryption.DeriveKeyWithParams(newTestPassword, salt, testKDFParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSecretsDecrypt(t, a, newKey)
+	for name, secret := range secrets(t, a) {
+		if _, err := a.Encryption.Decrypt(secret, oldKey); err == nil {
+			t.Errorf("%s still decrypts with the old key", name)
+		}
+	}
+
+	a.Lock()
+	if err := a.Unlock(testPassword); err == nil {
+		t.Error("the old master password still unlocks")
+	}
+	if err := a.Unlock(newTestPassword); err != nil {
+		t.Fatalf("the new master password does not unlock: %v", err)
+	}
+	assertSecretsDecrypt(t, a, newKey)
+}
+
+func TestRekeyRejectsWrongPassword(t *testing.T) {
+	a := unlockedTestApp(t)
+	addSecretEntry(t, a, "hunter2")
+	before := secrets(t, a)
+
+	if err := a.Rekey("wrong", newTestPassword, a.Config.Salt, testKDFParams); err == nil {
+		t.Fatal("Rekey succeeded with the wrong master password")
+	}
+	assertUnchanged(t, a, before)
+}
+
+func TestRekeyLeavesVaultUnchangedOnBadEntry(t *testing.T) {
+	a := unlockedTestApp(t)
+	addSecretEntry(t, a, "hunter2")
+	addSecretEntry(t, a, "swordfish")
+
+	// The last entry to be re-encrypted cannot be decrypted
+	broken := storage.NewEntry("zzz", "user", []byte("not a ciphertext"))
+	if err := a.Storage.AddEntry(broken); err != nil {
+		t.Fatal(err)
+	}
+	before := secrets(t, a)
+
+	if err := a.Rekey(testPassword, newTestPassword, a.Config.Salt, testKDFParams); err == nil {
+		t.Fatal("Rekey succeeded with an undecryptable entry")
+	}
+	assertUnchanged(t, a, before)
+}
+
+func TestRekeyRestoresEntriesWhenConfigCannotBeSaved(t *testing.T) {
+	a := unlockedTestApp(t)
+	addSecretEntry(t, a, "hunter2")
+	addSecretEntry(t, a, "swordfish")
+	before := secrets(t, a)
+
+	// A directory in place of the config file makes saving it fail
+	configPath := a.Config.ConfigPath
+	if err := os.Remove(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(configPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	salt := bytes.Repeat([]byte{9}, crypto.SaltLength)
+	if err := a.Rekey(testPassword, newTestPassword, salt, crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.KDFIterations + 1}); err == nil {
+		t.Fatal("Rekey succeeded without saving the config")
+	}
+
+	if err := os.Remove(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Config.KDFParams() != testKDFParams {
+		t.Errorf("KDF parameters = %+v after a failed rekey", a.Config.KDFParams())
+	}
+	assertUnchanged(t, a, before)
+}
+
+// assertUnchanged checks that the vault of a still holds the secrets before,
+// under the old master key and password.
+func assertUnchanged(t *testing.T, a *App, before map[string][]byte) {
+	t.Helper()
+	after := secrets(t, a)
+	for name, secret := range before {
+		if !bytes.Equal(after[name], secret) {
+			t.Errorf("%s changed", name)
+		}
+	}
+
+	if a.IsLocked() {
+		t.Fatal("a failed rekey locked passio")
+	}
+	if _, err := a.EncryptPassword("check"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(heldKey(a), testMasterKey(t, a)) {
+		t.Error("the held key is no longer the old master key")
+	}
+
+	a.Lock()
+	if err := a.Unlock(newTestPassword); err == nil {
+		t.Error("the new master password unlocks after a failed rekey")
+	}
+	if err := a.Unlock(testPassword); err != nil {
+		t.Errorf("the old master password no longer unlocks: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+const (
+	// EstimatorHeuristic rates passwords with the checks of CheckPasswordHealth
+	EstimatorHeuristic = "heuristic"
+	// EstimatorZxcvbn rates passwords with the zxcvbn guessability estimate
+	EstimatorZxcvbn = "zxcvbn"
+)
+
+// MaxStrengthScore is the score of the strongest passwords
+const MaxStrengthScore = 4
+
+// MinStrongScore is the lowest score of a password that is not weak
+const MinStrongScore = 3
+
+// StrengthEstimator rates how hard a password is to guess.
+type StrengthEstimator interface {
+	// Estimate returns a score from 0 to MaxStrengthScore and, for passwords
+	// that are not as strong as they could be, the reasons why.
+	Estimate(password string) (score int, feedback []string)
+}
+
+// StrengthEstimator returns the estimator used to rate passwords: Strength if
+// it is set, otherwise the one selected by the strength_estimator setting.
+func (a *App) StrengthEstimator() StrengthEstimator {
+	if a.Strength != nil {
+		return a.Strength
+	}
+
+	if a.Config.StrengthEstimator == EstimatorZxcvbn {
+		return zxcvbnEstimator{}
+	}
+	return heuristicEstimator{app: a}
+}
+
+// IsWeakPassword rates password with the configured estimator and reports
+// whether it scores below MinStrongScore, along with the estimator's feedback.
+func (a *App) IsWeakPassword(password string) (bool, []string) {
+	score, feedback := a.StrengthEstimator().Estimate(password)
+	return score < MinStrongScore, feedback
+}
+
+// heuristicEstimator rates passwords by their length and character classes,
+// and whether they are common. Failing any check makes a password weak.
+type heuristicEstimator struct {
+	app *App
+}
+
+// heuristicChecks are the checks of CheckPasswordHealth in reporting order,
+// with the feedback given when they fail
+var heuristicChecks = []struct{ check, feedback string }{
+	{"length", "too short"},
+	{"uppercase", "no uppercase"},
+	{"lowercase", "no lowercase"},
+	{"numbers", "no numbers"},
+	{"specialChars", "no special characters"},
+	{"notCommon", "common password"},
+}
+
+func (e heuristicEstimator) Estimate(password string) (int, []string) {
+	health := e.app.CheckPasswordHealth(password)
+
+	var feedback []string
+	for _, c := range heuristicChecks {
+		if !health[c.check] {
+			feedback = append(feedback, c.feedback)
+		}
+	}
+
+	if len(feedback) == 0 {
+		return MaxStrengthScore, nil
+	}
+	return max(MinStrongScore-len(feedback), 0), feedback
+}
+
+// zxcvbnEstimator rates passwords by the number of guesses an attacker aware
+// of common passwords, words and patterns would need.
+type zxcvbnEstimator struct{}
+
+func (zxcvbnEstimator) Estimate(password string) (int, []string) {
+	result := zxcvbn.PasswordStrength(password, nil)
+	if result.Score >= MaxStrengthScore {
+		return result.Score, nil
+	}
+	return result.Score, []string{fmt.Sprintf("estimated time to crack: %s", result.CrackTimeDisplay)}
+}
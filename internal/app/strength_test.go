@@ -0,0 +1,123 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+// fixedEstimator gives every password the same score and feedback
+type fixedEstimator struct {
+	score    int
+	feedback []string
+}
+
+func (e fixedEstimator) Estimate(string) (int, []string) {
+	return e.score, e.feedback
+}
+
+func TestStrengthEstimatorSelection(t *testing.T) {
+	tests := []struct {
+		setting    string
+		wantZxcvbn bool
+	}{
+		{"", false},
+		{EstimatorHeuristic, false},
+		{EstimatorZxcvbn, true},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		a.Config.StrengthEstimator = tt.setting
+		got := a.StrengthEstimator()
+		if _, ok := got.(zxcvbnEstimator); ok != tt.wantZxcvbn {
+			t.Errorf("strength_estimator %q: got %T", tt.setting, got)
+		}
+		if _, ok := got.(heuristicEstimator); ok == tt.wantZxcvbn {
+			t.Errorf("strength_estimator %q: got %T", tt.setting, got)
+		}
+	}
+
+	// Strength overrides the setting
+	a.Strength = fixedEstimator{score: 1}
+	if _, ok := a.StrengthEstimator().(fixedEstimator); !ok {
+		t.Errorf("StrengthEstimator ignored Strength, got %T", a.StrengthEstimator())
+	}
+}
+
+func TestHeuristicEstimator(t *testing.T) {
+	tests := []struct {
+		password  string
+		wantScore int
+		wantFeed  []string
+	}{
+		{"Tr0ub4dor&3-xkcd!", MaxStrengthScore, nil},
+		{"tr0ub4dor&3-xkcd!", 2, []string{"no uppercase"}},
+		{"TR0UB4DOR&3-XKCD!", 2, []string{"no lowercase"}},
+		{"Troubador&three-xkcd", 2, []string{"no numbers"}},
+		{"Tr0ub4dor3xkcdXYZ", 2, []string{"no special characters"}},
+		{"Tr0ub&4", 2, []string{"too short"}},
+		{"Password1!", 1, []string{"too short", "common password"}},
+		{"password", 0, []string{"too short", "no uppercase", "no numbers", "no special characters", "common password"}},
+	}
+
+	a := newTestApp(t)
+	a.Config.PasswordLength = 16
+	a.Config.StrengthEstimator = EstimatorHeuristic
+	for _, tt := range tests {
+		score, feedback := a.StrengthEstimator().Estimate(tt.password)
+		if score != tt.wantScore || !slices.Equal(feedback, tt.wantFeed) {
+			t.Errorf("Estimate(%q) = %d, %q, want %d, %q", tt.password, score, feedback, tt.wantScore, tt.wantFeed)
+		}
+	}
+}
+
+func TestZxcvbnEstimator(t *testing.T) {
+	tests := []struct {
+		password string
+		weak     bool
+	}{
+		{"password", true},
+		{"qwerty123", true},
+		{"correct horse battery staple", false},
+		{"vT7#qLp2@zW9!mRx", false},
+	}
+
+	a := newTestApp(t)
+	a.Config.StrengthEstimator = EstimatorZxcvbn
+	for _, tt := range tests {
+		score, feedback := a.StrengthEstimator().Estimate(tt.password)
+		if score < 0 || score > MaxStrengthScore {
+			t.Errorf("Estimate(%q) score = %d, out of range", tt.password, score)
+		}
+		if weak := score < MinStrongScore; weak != tt.weak {
+			t.Errorf("Estimate(%q) score = %d, weak = %v, want %v", tt.password, score, weak, tt.weak)
+		}
+		if (score < MaxStrengthScore) != (len(feedback) > 0) {
+			t.Errorf("Estimate(%q) = %d with feedback %q", tt.password, score, feedback)
+		}
+	}
+}
+
+func TestIsWeakPassword(t *testing.T) {
+	tests := []struct {
+		score int
+		want  bool
+	}{
+		{0, true},
+		{MinStrongScore - 1, true},
+		{MinStrongScore, false},
+		{MaxStrengthScore, false},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		a.Strength = fixedEstimator{score: tt.score, feedback: []string{"reason"}}
+		weak, feedback := a.IsWeakPassword("anything")
+		if weak != tt.want {
+			t.Errorf("IsWeakPassword with score %d = %v, want %v", tt.score, weak, tt.want)
+		}
+		if !slices.Equal(feedback, []string{"reason"}) {
+			t.Errorf("IsWeakPassword with score %d dropped the feedback: %q", tt.score, feedback)
+		}
+	}
+}
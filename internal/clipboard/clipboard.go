@@ -0,0 +1,332 @@
+package clipboard
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// Selection names one of the selections a clipboard can hold text in.
+type Selection string
+
+const (
+	// SelectionClipboard is the regular clipboard, pasted with Ctrl-V
+	SelectionClipboard Selection = "clipboard"
+	// SelectionPrimary is the X11 primary selection, pasted with a middle click
+	SelectionPrimary Selection = "primary"
+)
+
+// ErrSelectionUnsupported is returned for selections a clipboard cannot access.
+var ErrSelectionUnsupported = errors.New("selection is not supported on this system")
+
+// ErrUnavailable is returned when the system clipboard cannot be used
+// because no clipboard tool is installed. Errors wrapping it name the tool
+// to install.
+var ErrUnavailable = errors.New("no clipboard tool is available")
+
+// Selector is implemented by clipboards that can hold text in selections other
+// than the regular clipboard.
+type Selector interface {
+	Selection(sel Selection) (Clipboard, error)
+}
+
+// ForSelection returns the clipboard holding the given selection of cb.
+// Clipboards that do not implement Selector only have SelectionClipboard.
+func ForSelection(cb Clipboard, sel Selection) (Clipboard, error) {
+	if sel == SelectionClipboard {
+		return cb, nil
+	}
+
+	selector, ok := cb.(Selector)
+	if !ok {
+		return nil, ErrSelectionUnsupported
+	}
+	return selector.Selection(sel)
+}
+
+// SystemClipboard uses the clipboard of the operating system.
+type SystemClipboard struct{}
+
+func NewSystemClipboard() *SystemClipboard {
+	return &SystemClipboard{}
+}
+
+// systemUnsupported reports whether the system clipboard has no tool to
+// access it. It is a variable so that the missing tool can be simulated.
+var systemUnsupported = func() bool {
+	return clipboard.Unsupported
+}
+
+// unavailableError wraps ErrUnavailable with the tool to install.
+func unavailableError() error {
+	return fmt.Errorf("%w; %s", ErrUnavailable, installHint())
+}
+
+func (c *SystemClipboard) ReadAll() (string, error) {
+	if systemUnsupported() {
+		return "", unavailableError()
+	}
+	return clipboard.ReadAll()
+}
+
+func (c *SystemClipboard) WriteAll(text string) error {
+	if systemUnsupported() {
+		return unavailableError()
+	}
+	return clipboard.WriteAll(text)
+}
+
+// Selection returns the system clipboard for sel. The primary selection needs
+// wl-clipboard, xclip or xsel.
+func (c *SystemClipboard) Selection(sel Selection) (Clipboard, error) {
+	switch sel {
+	case SelectionClipboard:
+		return c, nil
+	case SelectionPrimary:
+		return newPrimarySelection()
+	default:
+		return nil, ErrSelectionUnsupported
+	}
+}
+
+// MemoryClipboard keeps its contents in memory. It is useful where no
+// system clipboard is available.
+type MemoryClipboard struct {
+	mu      sync.RWMutex
+	text    string
+	primary *MemoryClipboard
+}
+
+func NewMemoryClipboard() *MemoryClipboard {
+	return &MemoryClipboard{}
+}
+
+func (c *MemoryClipboard) ReadAll() (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.text, nil
+}
+
+func (c *MemoryClipboard) WriteAll(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.text = text
+	return nil
+}
+
+// Selection returns the in-memory clipboard for sel. Each selection holds its
+// own text.
+func (c *MemoryClipboard) Selection(sel Selection) (Clipboard, error) {
+	switch sel {
+	case SelectionClipboard:
+		return c, nil
+	case SelectionPrimary:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.primary == nil {
+			c.primary = NewMemoryClipboard()
+		}
+		return c.primary, nil
+	default:
+		return nil, ErrSelectionUnsupported
+	}
+}
+
+// ClearAfter clears the clipboard once timeout has elapsed, but only if it
+// still holds value. If state is non-nil, the clipboard is cleared as soon as
+// a clear is requested with RequestClear, and the clear is abandoned once a
+// later copy replaces state; state is removed when the clear runs. If status
+// is non-nil it is called with the remaining time every second, and with
+// zero once the clipboard has been cleared.
+func ClearAfter(cb Clipboard, value string, timeout time.Duration, state *CopyState, status func(remaining time.Duration)) *PendingClear {
+	pending := &PendingClear{done: make(chan struct{}), now: make(chan struct{})}
+
+	go func() {
+		defer close(pending.done)
+
+		deadline := time.Now().Add(timeout)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		poll := time.NewTicker(statePollInterval)
+		defer poll.Stop()
+
+		if status != nil {
+			status(timeout)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ticker.C:
+				if status != nil {
+					status(time.Until(deadline).Round(time.Second))
+				}
+			case <-poll.C:
+				if state == nil {
+					continue
+				}
+				switch state.check() {
+				case stateReplaced:
+					// The later copy clears the clipboard itself
+					return
+				case stateRemoved:
+					break wait
+				}
+			case <-pending.now:
+				break wait
+			case <-timer.C:
+				break wait
+			}
+		}
+
+		if state != nil {
+			state.Remove()
+		}
+		if cleared, err := ClearIfHolds(cb, value); err != nil || !cleared {
+			return
+		}
+		if status != nil {
+			status(0)
+		}
+	}()
+
+	return pending
+}
+
+// PendingClear is a clear scheduled with ClearAfter.
+type PendingClear struct {
+	done chan struct{}
+	now  chan struct{}
+	once sync.Once
+}
+
+// Done returns a channel that is closed once the clear has been attempted.
+func (p *PendingClear) Done() <-chan struct{} {
+	return p.done
+}
+
+// Now clears the clipboard without waiting for the timeout, if it still
+// holds the copied value, and returns once the clear has been attempted.
+func (p *PendingClear) Now() {
+	p.once.Do(func() { close(p.now) })
+	<-p.done
+}
+
+// ClearIfHolds clears the clipboard if it holds value, and reports whether
+// it did.
+func ClearIfHolds(cb Clipboard, value string) (bool, error) {
+	current, err := cb.ReadAll()
+	if err != nil {
+		return false, fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	if current == "" || current != value {
+		return false, nil
+	}
+
+	if err := cb.WriteAll(""); err != nil {
+		return false, fmt.Errorf("failed to clear clipboard: %w", err)
+	}
+	return true, nil
+}
+
+// statePollInterval is how often ClearAfter checks its CopyState.
+var statePollInterval = 250 * time.Millisecond
+
+// CopyState marks a copy as the last one made, in a state file shared by all
+// processes. The file holds only a random token identifying the copy, never
+// anything derived from the copied value, so the process that made the copy
+// is the only one able to tell whether the clipboard still holds it.
+type CopyState struct {
+	path  string
+	token string
+}
+
+// RecordCopy records a new copy in the state file at path, replacing the
+// copy recorded before it.
+func RecordCopy(path string) (*CopyState, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate clipboard token: %w", err)
+	}
+
+	state := &CopyState{path: path, token: hex.EncodeToString(token)}
+	if err := os.WriteFile(path, []byte(state.token), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write clipboard state: %w", err)
+	}
+	return state, nil
+}
+
+// OpenCopyState returns the state of a copy recorded with RecordCopy by
+// another process, identified by its token.
+func OpenCopyState(path, token string) *CopyState {
+	return &CopyState{path: path, token: token}
+}
+
+// Token returns the token identifying the copy.
+func (s *CopyState) Token() string {
+	return s.token
+}
+
+type stateStatus int
+
+const (
+	stateCurrent stateStatus = iota
+	stateReplaced
+	stateRemoved
+)
+
+// check reports whether the copy is still the last one recorded.
+func (s *CopyState) check() stateStatus {
+	recorded, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return stateRemoved
+	}
+	if err != nil {
+		// Keep waiting for the timeout rather than give up on the clear
+		return stateCurrent
+	}
+	if strings.TrimSpace(string(recorded)) != s.token {
+		return stateReplaced
+	}
+	return stateCurrent
+}
+
+// Remove removes the state file, unless a later copy has replaced it.
+func (s *CopyState) Remove() error {
+	if s.check() != stateCurrent {
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove clipboard state: %w", err)
+	}
+	return nil
+}
+
+// RequestClear asks the process waiting to clear the last copy recorded in
+// the state file at path to clear it now, and reports whether a copy was
+// waiting to be cleared. That process clears the clipboard only if it still
+// holds the copied value.
+func RequestClear(path string) (bool, error) {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to remove clipboard state: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,240 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	statePollInterval = 5 * time.Millisecond
+}
+
+const testSecret = "hunter2"
+
+// waitDone fails the test if pending is not done within a second.
+func waitDone(t *testing.T, pending *PendingClear) {
+	t.Helper()
+	select {
+	case <-pending.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the clear did not run")
+	}
+}
+
+func newCopy(t *testing.T) (*MemoryClipboard, *CopyState) {
+	t.Helper()
+	cb := NewMemoryClipboard()
+	if err := cb.WriteAll(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	state, err := RecordCopy(filepath.Join(t.TempDir(), "clipboard.state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cb, state
+}
+
+func assertClipboard(t *testing.T, cb Clipboard, want string) {
+	t.Helper()
+	if got, _ := cb.ReadAll(); got != want {
+		t.Errorf("clipboard holds %q, want %q", got, want)
+	}
+}
+
+func assertNoState(t *testing.T, state *CopyState) {
+	t.Helper()
+	if _, err := os.Stat(state.path); !os.IsNotExist(err) {
+		t.Errorf("state file still exists: %v", err)
+	}
+}
+
+func TestStateFileDoesNotRevealValue(t *testing.T) {
+	_, state := newCopy(t)
+
+	data, err := os.ReadFile(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != state.Token() {
+		t.Errorf("state file holds %q, want only the token", data)
+	}
+
+	// Two copies of the same value are told apart
+	other, err := RecordCopy(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Token() == state.Token() {
+		t.Error("two copies got the same token")
+	}
+}
+
+func TestClearAfterTimeout(t *testing.T) {
+	cb, state := newCopy(t)
+
+	var statuses []time.Duration
+	pending := ClearAfter(cb, testSecret, 20*time.Millisecond, state, func(remaining time.Duration) {
+		statuses = append(statuses, remaining)
+	})
+	waitDone(t, pending)
+
+	assertClipboard(t, cb, "")
+	assertNoState(t, state)
+	if len(statuses) < 2 || statuses[len(statuses)-1] != 0 {
+		t.Errorf("status calls = %v, want the timeout first and 0 last", statuses)
+	}
+}
+
+func TestClearAfterLeavesNewerContent(t *testing.T) {
+	cb, state := newCopy(t)
+	cb.WriteAll("copied by someone else")
+
+	cleared := false
+	pending := ClearAfter(cb, testSecret, 10*time.Millisecond, state, func(remaining time.Duration) {
+		cleared = cleared || remaining == 0
+	})
+	waitDone(t, pending)
+
+	assertClipboard(t, cb, "copied by someone else")
+	assertNoState(t, state)
+	if cleared {
+		t.Error("reported the clipboard as cleared")
+	}
+}
+
+func TestRequestClear(t *testing.T) {
+	cb, state := newCopy(t)
+	pending := ClearAfter(cb, testSecret, time.Hour, state, nil)
+
+	requested, err := RequestClear(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !requested {
+		t.Error("RequestClear() = false with a copy waiting")
+	}
+	waitDone(t, pending)
+	assertClipboard(t, cb, "")
+
+	requested, err = RequestClear(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested {
+		t.Error("RequestClear() = true with no copy waiting")
+	}
+}
+
+func TestReplacedCopyIsNotCleared(t *testing.T) {
+	cb, state := newCopy(t)
+	pending := ClearAfter(cb, testSecret, time.Hour, state, nil)
+
+	// A later copy of another value takes over the state file
+	cb.WriteAll("newer secret")
+	newer, err := RecordCopy(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDone(t, pending)
+
+	assertClipboard(t, cb, "newer secret")
+	if newer.check() != stateCurrent {
+		t.Error("the replaced copy removed the newer copy's state")
+	}
+}
+
+func TestPendingClearNow(t *testing.T) {
+	cb, state := newCopy(t)
+	pending := ClearAfter(cb, testSecret, time.Hour, state, nil)
+
+	pending.Now()
+	pending.Now()
+
+	assertClipboard(t, cb, "")
+	assertNoState(t, state)
+}
+
+func TestClearIfHolds(t *testing.T) {
+	cb := NewMemoryClipboard()
+	cb.WriteAll("other")
+
+	cleared, err := ClearIfHolds(cb, testSecret)
+	if err != nil || cleared {
+		t.Fatalf("ClearIfHolds() = %v, %v with other content", cleared, err)
+	}
+	assertClipboard(t, cb, "other")
+
+	cb.WriteAll(testSecret)
+	cleared, err = ClearIfHolds(cb, testSecret)
+	if err != nil || !cleared {
+		t.Fatalf("ClearIfHolds() = %v, %v with the value", cleared, err)
+	}
+	assertClipboard(t, cb, "")
+}
+
+// plainClipboard only has the regular clipboard
+type plainClipboard struct {
+	Clipboard
+}
+
+func TestForSelection(t *testing.T) {
+	memory := NewMemoryClipboard()
+	plain := plainClipboard{NewMemoryClipboard()}
+
+	tests := []struct {
+		name    string
+		cb      Clipboard
+		sel     Selection
+		wantErr bool
+	}{
+		{"memory clipboard", memory, SelectionClipboard, false},
+		{"memory primary", memory, SelectionPrimary, false},
+		{"memory unknown selection", memory, "secondary", true},
+		{"plain clipboard", plain, SelectionClipboard, false},
+		{"plain primary", plain, SelectionPrimary, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ForSelection(tt.cb, tt.sel)
+			if tt.wantErr {
+				if !errors.Is(err, ErrSelectionUnsupported) {
+					t.Errorf("ForSelection = %v, %v, want ErrSelectionUnsupported", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForSelection: %v", err)
+			}
+			if (got == tt.cb) != (tt.sel == SelectionClipboard) {
+				t.Errorf("ForSelection returned %v, want the clipboard itself", got)
+			}
+		})
+	}
+}
+
+func TestMemoryClipboardSelectionsAreSeparate(t *testing.T) {
+	cb := NewMemoryClipboard()
+	primary, err := ForSelection(cb, SelectionPrimary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ForSelection(cb, SelectionPrimary); again != primary {
+		t.Error("ForSelection returned a new primary selection each time")
+	}
+
+	if err := primary.WriteAll(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	assertClipboard(t, primary, testSecret)
+	assertClipboard(t, cb, "")
+
+	if err := cb.WriteAll("other"); err != nil {
+		t.Fatal(err)
+	}
+	assertClipboard(t, primary, testSecret)
+}
@@ -0,0 +1,15 @@
+//go:build !(freebsd || linux || netbsd || openbsd || solaris || dragonfly)
+
+package clipboard
+
+// newPrimarySelection reports that there is no primary selection outside X11
+// and Wayland systems.
+func newPrimarySelection() (Clipboard, error) {
+	return nil, ErrSelectionUnsupported
+}
+
+// installHint is only reached where the operating system clipboard failed,
+// as no extra tool is needed outside X11 and Wayland systems.
+func installHint() string {
+	return "the system clipboard is not accessible"
+}
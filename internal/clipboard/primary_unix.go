@@ -0,0 +1,68 @@
+//go:build freebsd || linux || netbsd || openbsd || solaris || dragonfly
+
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// primaryTool is a command line tool that can read and write the primary selection.
+type primaryTool struct {
+	wayland bool
+	paste   []string
+	copy    []string
+}
+
+// primaryTools are tried in order. wl-clipboard is only used under Wayland.
+var primaryTools = []primaryTool{
+	{true, []string{"wl-paste", "--no-newline", "--primary"}, []string{"wl-copy", "--primary"}},
+	{false, []string{"xclip", "-out", "-selection", "primary"}, []string{"xclip", "-in", "-selection", "primary"}},
+	{false, []string{"xsel", "--output", "--primary"}, []string{"xsel", "--input", "--primary"}},
+}
+
+// primarySelection reads and writes the primary selection through a tool.
+type primarySelection struct {
+	tool primaryTool
+}
+
+func newPrimarySelection() (Clipboard, error) {
+	wayland := os.Getenv("WAYLAND_DISPLAY") != ""
+	for _, tool := range primaryTools {
+		if tool.wayland && !wayland {
+			continue
+		}
+		if _, err := exec.LookPath(tool.paste[0]); err != nil {
+			continue
+		}
+		if _, err := exec.LookPath(tool.copy[0]); err != nil {
+			continue
+		}
+		return &primarySelection{tool: tool}, nil
+	}
+	return nil, ErrSelectionUnsupported
+}
+
+// installHint suggests the clipboard tool to install for the running
+// display server.
+func installHint() string {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return "install wl-clipboard to use the clipboard"
+	}
+	return "install xclip or xsel to use the clipboard"
+}
+
+func (c *primarySelection) ReadAll() (string, error) {
+	out, err := exec.Command(c.tool.paste[0], c.tool.paste[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (c *primarySelection) WriteAll(text string) error {
+	cmd := exec.Command(c.tool.copy[0], c.tool.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
@@ -3,43 +3,78 @@ package cmd
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 func newAddCmd(app *app.App) *cobra.Command {
 	var (
-		username string
-		password string
-		url      string
-		notes    string
-		tags     string
-		generate bool
-		length   int
-		special  bool
+		username  string
+		password  string
+		url       string
+		notes     string
+		tags      string
+		generate  bool
+		length    int
+		special   bool
+		writeOnly bool
+		folder    string
+		fields    []string
+		aliases   []string
+		ttl       time.Duration
+		fromClip  bool
+		clearClip bool
+		generator string
+		words     int
 	)
 
 	cmd := &cobra.Command{
 		Use:   "add <name>",
 		Short: "Add a new password entry",
 		Long: `Add a new password entry to the passio.
-If no password is provided, one will be generated using the specified options.`,
+If no password is provided, one will be generated using the specified options.
+The password_generator setting chooses between random passwords and diceware
+passphrases, unless --generator is given.
+
+Use --ttl for temporary credentials, e.g. --ttl 72h. Once the TTL has passed
+the entry is deleted by 'pm prune --expired-ttl'.
+
+Use --from-clipboard to store the password currently on the clipboard, e.g.
+one generated elsewhere, and --clear-clipboard to clear it once stored.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("passio is locked. Please unlock first")
+				return errLocked
+			}
+
+			if ttl < 0 {
+				return errs.InvalidInput("--ttl must be a positive duration")
+			}
+
+			if clearClip && !fromClip {
+				return errs.InvalidInput("--clear-clipboard requires --from-clipboard")
 			}
 
 			name := args[0]
 
+			if fromClip {
+				var err error
+				password, err = readClipboardPassword(app)
+				if err != nil {
+					return err
+				}
+			}
+
 			// Generate password if requested or no password provided
 			if generate || password == "" {
 				var err error
-				password, err = generatePassword(length, special)
+				password, err = generateEntryPassword(app, generator, length, special, words)
 				if err != nil {
-					return fmt.Errorf("failed to generate password: %w", err)
+					return err
 				}
 				fmt.Printf("Generated password: %s\n", password)
 			}
@@ -47,7 +82,22 @@ If no password is provided, one will be generated using the specified options.`,
 			// Encrypt the password
 			encryptedPass, err := app.EncryptPassword(password)
 			if err != nil {
-				return fmt.Errorf("failed to encrypt password: %w", err)
+				return errs.Internal("failed to encrypt password: %w", err)
+			}
+
+			customFields, err := parseFieldFlags(fields)
+			if err != nil {
+				return err
+			}
+			for key, value := range customFields {
+				if value == "" {
+					delete(customFields, key)
+				}
+			}
+
+			encryptedFields, err := app.EncryptFields(customFields)
+			if err != nil {
+				return errs.Internal("failed to encrypt custom fields: %w", err)
 			}
 
 			// Parse tags
@@ -65,16 +115,39 @@ If no password is provided, one will be generated using the specified options.`,
 				Username: username,
 				Password: encryptedPass,
 				URL:      url,
-				Notes:    notes,
 				Tags:     tagList,
+				Type:     storage.EntryTypeLogin,
+				Aliases:  parseAliasFlags(aliases),
+				Folder:   folder,
+
+				CustomFields: encryptedFields,
+			}
+			if err := app.SetEntryNotes(entry, notes); err != nil {
+				return errs.Internal("failed to store notes: %w", err)
+			}
+			if writeOnly {
+				entry.Type = storage.EntryTypeWriteOnly
+			}
+			if ttl > 0 {
+				deleteAt := time.Now().Add(ttl)
+				entry.DeleteAt = &deleteAt
 			}
 
 			// Add entry to storage
 			if err := app.Storage.AddEntry(entry); err != nil {
-				return fmt.Errorf("failed to add entry: %w", err)
+				return storageError("failed to add entry", err)
+			}
+
+			if clearClip {
+				if err := app.Clipboard.WriteAll(""); err != nil {
+					return errs.Internal("entry added, but failed to clear clipboard: %w", err)
+				}
 			}
 
 			fmt.Printf("Successfully added entry: %s\n", name)
+			if entry.DeleteAt != nil {
+				fmt.Printf("Entry expires at %s\n", entry.DeleteAt.Format("2006-01-02 15:04:05"))
+			}
 			return nil
 		},
 	}
@@ -87,7 +160,58 @@ If no password is provided, one will be generated using the specified options.`,
 	cmd.Flags().BoolVarP(&generate, "generate", "g", false, "Generate a password")
 	cmd.Flags().IntVarP(&length, "length", "l", 16, "Length of generated password")
 	cmd.Flags().BoolVarP(&special, "special", "s", true, "Include special characters in generated password")
+	cmd.Flags().StringVar(&generator, "generator", "", "Generate a random password or passphrase (default is the password_generator setting)")
+	cmd.Flags().IntVar(&words, "words", 0, "Number of words in a generated passphrase (default is the passphrase_words setting)")
+	cmd.Flags().StringVar(&folder, "folder", "", "Folder to store the entry in")
+	cmd.Flags().StringArrayVar(&fields, "field", nil, "Custom field as key=value (repeatable)")
+	cmd.Flags().StringArrayVar(&aliases, "alias", nil, "Alternative name the entry can be fetched by (repeatable)")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "Time after which the entry is deleted by 'pm prune --expired-ttl'")
+	cmd.Flags().BoolVar(&writeOnly, "write-only", false, "Store a secret that can be verified but never revealed")
+	cmd.Flags().BoolVar(&fromClip, "from-clipboard", false, "Use the password currently on the clipboard")
+	cmd.Flags().BoolVar(&clearClip, "clear-clipboard", false, "Clear the clipboard once the entry is added (with --from-clipboard)")
+	cmd.MarkFlagsMutuallyExclusive("from-clipboard", "password")
+	cmd.MarkFlagsMutuallyExclusive("from-clipboard", "generate")
 
 	return cmd
+}
 
+// readClipboardPassword returns the value on the clipboard without the line
+// break some clipboard tools append. An empty clipboard is an error.
+func readClipboardPassword(app *app.App) (string, error) {
+	value, err := app.Clipboard.ReadAll()
+	if err != nil {
+		return "", errs.Internal("failed to read clipboard: %w", err)
+	}
+
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return "", errs.InvalidInput("the clipboard is empty")
+	}
+	return value, nil
+}
+
+// parseAliasFlags trims the given aliases and drops empty ones.
+func parseAliasFlags(flags []string) []string {
+	aliases := make([]string, 0, len(flags))
+	for _, alias := range flags {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// parseFieldFlags parses repeated key=value flags into a map. An empty value
+// is kept so that updates can remove the field.
+func parseFieldFlags(flags []string) (map[string]string, error) {
+	fields := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		key, value, ok := strings.Cut(flag, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, errs.InvalidInput("invalid field %q, expected key=value", flag)
+		}
+		fields[key] = value
+	}
+	return fields, nil
 }
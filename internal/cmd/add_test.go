@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestAddFromClipboard(t *testing.T) {
+	tests := []struct {
+		name          string
+		clipboard     string
+		args          []string
+		wantPassword  string
+		wantClipboard string
+		wantCode      int
+	}{
+		{"copied password", "hunter2", []string{"--from-clipboard"}, "hunter2", "hunter2", 0},
+		{"trailing newline", "hunter2\r\n", []string{"--from-clipboard"}, "hunter2", "hunter2\r\n", 0},
+		{"inner spaces kept", " hunter 2", []string{"--from-clipboard"}, " hunter 2", " hunter 2", 0},
+		{"cleared once stored", "hunter2", []string{"--from-clipboard", "--clear-clipboard"}, "hunter2", "", 0},
+		{"empty clipboard", "\n", []string{"--from-clipboard"}, "", "\n", errs.ExitInvalidInput},
+		{"clear without from", "hunter2", []string{"--password", "secret", "--clear-clipboard"}, "", "hunter2", errs.ExitInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			if err := a.Clipboard.WriteAll(tt.clipboard); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := runCommand(t, a, append([]string{"add", "github"}, tt.args...)...)
+			if tt.wantCode != 0 {
+				if errs.ExitCode(err) != tt.wantCode {
+					t.Errorf("add: err = %v, want exit code %d", err, tt.wantCode)
+				}
+				if _, err := a.Storage.GetEntry("github"); err == nil {
+					t.Error("failed add stored the entry")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				entry, err := a.Storage.GetEntry("github")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if password, err := a.DecryptPassword(entry.Password); err != nil || password != tt.wantPassword {
+					t.Errorf("stored password %q, %v, want %q", password, err, tt.wantPassword)
+				}
+			}
+
+			if got, _ := a.Clipboard.ReadAll(); got != tt.wantClipboard {
+				t.Errorf("clipboard holds %q, want %q", got, tt.wantClipboard)
+			}
+		})
+	}
+}
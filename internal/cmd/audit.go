@@ -7,36 +7,70 @@ import (
 	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/spf13/cobra"
 )
 
+type auditIssue struct {
+	Type    string
+	Summary string
+	Detail  string
+}
+
 func newAuditCmd(app *app.App) *cobra.Command {
 	var (
 		checkWeak    bool
 		checkReused  bool
 		checkExpired bool
 		verbose      bool
+		olderThan    string
+		newerThan    string
+		minLength    int
 	)
 
 	cmd := &cobra.Command{
 		Use:   "audit",
 		Short: "Audit password security",
 		Long: `Audit password security by checking for:
-- Weak passwords (less than required length, missing character types)
+- Weak passwords, as rated by the strength_estimator setting (by default: less
+  than required length, missing character types or a common password)
 - Reused passwords across different entries
-- Expired passwords (older than configured expiration period)`,
+- Expired passwords (changed longer ago than the configured expiration period,
+  or past their own expiry date)
+- Contextually weak passwords (equal to the username or URL host, or containing the entry name)
+- Predictable patterns (keyboard walks like qwerty or 123456, abcd sequences, repeats like aaaa)
+
+Use --older-than and --newer-than to only audit entries last modified more or
+less than a duration ago, e.g. --older-than 6m.
+
+Use --min-length to audit against a stricter length than the password_length
+setting, for this run only.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
+			}
+
+			ages, err := parseAgeFilter(olderThan, newerThan)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("min-length") {
+				if minLength < 1 {
+					return errs.InvalidInput("--min-length must be positive")
+				}
+				// The config is not saved by audit, so this only lasts for this run
+				app.Config.PasswordLength = minLength
 			}
 
 			// Get all entries
 			entries, err := app.Storage.ListEntries()
 			if err != nil {
-				return fmt.Errorf("failed to list entries: %w", err)
+				return storageError("failed to list entries", err)
 			}
+			entries = ages.apply(entries, time.Now())
 
-			var issues []string
+			var issues []auditIssue
 			passwordMap := make(map[string][]string) // For checking reused passwords
 
 			// Check each entry
@@ -44,37 +78,59 @@ func newAuditCmd(app *app.App) *cobra.Command {
 				// Decrypt password for checking
 				password, err := app.DecryptPassword(entry.Password)
 				if err != nil {
-					return fmt.Errorf("failed to decrypt password for entry %s: %w", entry.Name, err)
+					return errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
 				}
 
 				// Check weak passwords
 				if checkWeak {
-					health := app.CheckPasswordHealth(password)
-					var weaknesses []string
+					if weak, feedback := app.IsWeakPassword(password); weak {
+						issues = append(issues, auditIssue{
+							Type:    "weak",
+							Summary: fmt.Sprintf("Weak password for %s", entry.Name),
+							Detail:  strings.Join(feedback, ", "),
+						})
+					}
 
-					if !health["length"] {
-						weaknesses = append(weaknesses, "too short")
+					contextual := app.CheckContextualHealth(password, entry.Username, entry.Name, entry.URL)
+					var reasons []string
+
+					if !contextual["notUsername"] {
+						reasons = append(reasons, "same as username")
+					}
+					if !contextual["notName"] {
+						reasons = append(reasons, "contains entry name")
 					}
-					if !health["uppercase"] {
-						weaknesses = append(weaknesses, "no uppercase")
+					if !contextual["notURLHost"] {
+						reasons = append(reasons, "same as URL host")
 					}
-					if !health["lowercase"] {
-						weaknesses = append(weaknesses, "no lowercase")
+
+					if len(reasons) > 0 {
+						issues = append(issues, auditIssue{
+							Type:    "contextual-weak",
+							Summary: fmt.Sprintf("Contextually weak password for %s", entry.Name),
+							Detail:  strings.Join(reasons, ", "),
+						})
 					}
-					if !health["numbers"] {
-						weaknesses = append(weaknesses, "no numbers")
+
+					pattern := app.CheckPatternHealth(password)
+					var patterns []string
+
+					if !pattern["notKeyboardWalk"] {
+						patterns = append(patterns, "keyboard walk")
 					}
-					if !health["specialChars"] {
-						weaknesses = append(weaknesses, "no special characters")
+					if !pattern["notSequence"] {
+						patterns = append(patterns, "alphabetical sequence")
 					}
-					if !health["notCommon"] {
-						weaknesses = append(weaknesses, "common password")
+					if !pattern["notRepeat"] {
+						patterns = append(patterns, "repeated characters")
 					}
 
-					if len(weaknesses) > 0 {
-						issue := fmt.Sprintf("Weak password for %s: %s",
-							entry.Name, strings.Join(weaknesses, ", "))
-						issues = append(issues, issue)
+					if len(patterns) > 0 {
+						issues = append(issues, auditIssue{
+							Type:    "pattern-weak",
+							Summary: fmt.Sprintf("Predictable password pattern for %s", entry.Name),
+							Detail:  strings.Join(patterns, ", "),
+						})
 					}
 				}
 
@@ -84,13 +140,12 @@ func newAuditCmd(app *app.App) *cobra.Command {
 				}
 
 				// Check expired passwords
-				if checkExpired && app.Config.PasswordExpiration > 0 {
-					age := time.Since(entry.UpdatedAt).Hours() / 24
-					if age > float64(app.Config.PasswordExpiration) {
-						issue := fmt.Sprintf("Expired password for %s (%.0f days old)",
-							entry.Name, age)
-						issues = append(issues, issue)
-					}
+				if checkExpired && app.IsExpired(entry) {
+					issues = append(issues, auditIssue{
+						Type:    "expired",
+						Summary: fmt.Sprintf("Expired password for %s", entry.Name),
+						Detail:  fmt.Sprintf("changed %.0f days ago", app.AgeDays(entry)),
+					})
 				}
 			}
 
@@ -99,9 +154,11 @@ func newAuditCmd(app *app.App) *cobra.Command {
 				for _, entries := range passwordMap {
 					if len(entries) > 1 {
 						sort.Strings(entries)
-						issue := fmt.Sprintf("Password reused across entries: %s",
-							strings.Join(entries, ", "))
-						issues = append(issues, issue)
+						issues = append(issues, auditIssue{
+							Type:    "reused",
+							Summary: "Password reused across entries",
+							Detail:  strings.Join(entries, ", "),
+						})
 					}
 				}
 			}
@@ -115,11 +172,10 @@ func newAuditCmd(app *app.App) *cobra.Command {
 			fmt.Printf("Found %d issues:\n", len(issues))
 			for i, issue := range issues {
 				if verbose {
-					fmt.Printf("%d. %s\n", i+1, issue)
+					fmt.Printf("%d. [%s] %s: %s\n", i+1, issue.Type, issue.Summary, issue.Detail)
 				} else {
 					// Print shortened version for non-verbose output
-					parts := strings.SplitN(issue, ":", 2)
-					fmt.Printf("%d. %s\n", i+1, parts[0])
+					fmt.Printf("%d. [%s] %s\n", i+1, issue.Type, issue.Summary)
 				}
 			}
 
@@ -129,9 +185,12 @@ func newAuditCmd(app *app.App) *cobra.Command {
 
 	// Add flags
 	cmd.Flags().BoolVarP(&checkWeak, "weak", "w", true, "Check for weak passwords")
+	cmd.Flags().IntVar(&minLength, "min-length", 0, "Minimum password length for the weak check (overrides password_length)")
 	cmd.Flags().BoolVarP(&checkReused, "reused", "r", true, "Check for reused passwords")
 	cmd.Flags().BoolVarP(&checkExpired, "expired", "e", true, "Check for expired passwords")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed issue descriptions")
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Only audit entries last modified longer ago than this, e.g. 6m")
+	cmd.Flags().StringVar(&newerThan, "newer-than", "", "Only audit entries last modified more recently than this, e.g. 2w")
 
 	return cmd
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+// auditIssues runs pm audit --verbose with args and returns the issues it
+// reports, without their numbers.
+func auditIssues(t *testing.T, a *app.App, args ...string) []string {
+	t.Helper()
+	output, err := runCommand(t, a, append([]string{"audit", "--verbose"}, args...)...)
+	if err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+
+	var issues []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, issue, ok := strings.Cut(line, ". ["); ok {
+			issues = append(issues, "["+issue)
+		}
+	}
+	return issues
+}
+
+// assertIssue fails the test unless issues has an issue starting with want.
+func assertIssue(t *testing.T, issues []string, want string) {
+	t.Helper()
+	if !slices.ContainsFunc(issues, func(issue string) bool { return strings.HasPrefix(issue, want) }) {
+		t.Errorf("no issue %q in:\n%s", want, strings.Join(issues, "\n"))
+	}
+}
+
+func TestAuditContextualWeakness(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	github := addTestEntry(t, a, "github", "Xk9#mP2$vL7@github")
+	github.URL = "https://github.com"
+	if err := a.Storage.UpdateEntry(github); err != nil {
+		t.Fatal(err)
+	}
+	addTestEntry(t, a, "mail", "Xk9#mP2$vL7@qR4!")
+
+	issues := auditIssues(t, a, "--reused=false")
+	assertIssue(t, issues, "[contextual-weak] Contextually weak password for github: contains entry name")
+	for _, issue := range issues {
+		if strings.Contains(issue, "mail") {
+			t.Errorf("unexpected issue for mail: %s", issue)
+		}
+	}
+}
+
+func TestAuditPatternWeakness(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	addTestEntry(t, a, "walk", "Xk9#qwerty@L7")
+	addTestEntry(t, a, "sequence", "Xk9#abcd@L7")
+	addTestEntry(t, a, "repeat", "Xk9#zzzz@L7")
+	addTestEntry(t, a, "everything", "qwerty-abcd-zzzz")
+	addTestEntry(t, a, "random", "Xk9#mP2$vL7@qR4!")
+
+	issues := auditIssues(t, a, "--reused=false")
+	assertIssue(t, issues, "[pattern-weak] Predictable password pattern for walk: keyboard walk")
+	assertIssue(t, issues, "[pattern-weak] Predictable password pattern for sequence: alphabetical sequence")
+	assertIssue(t, issues, "[pattern-weak] Predictable password pattern for repeat: repeated characters")
+	assertIssue(t, issues, "[pattern-weak] Predictable password pattern for everything: keyboard walk, alphabetical sequence, repeated characters")
+	for _, issue := range issues {
+		if strings.Contains(issue, "random") {
+			t.Errorf("unexpected issue for random: %s", issue)
+		}
+	}
+}
+
+func TestAuditMinLength(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	addTestEntry(t, a, "mail", "Xk9#mP2$vL7@qR4!")
+
+	tests := []struct {
+		minLength string
+		wantWeak  bool
+	}{
+		{"", false},
+		{"12", false},
+		{"16", false},
+		{"17", true},
+		{"24", true},
+	}
+
+	for _, tt := range tests {
+		a.Config.PasswordLength = 16
+		args := []string{"--reused=false"}
+		if tt.minLength != "" {
+			args = append(args, "--min-length", tt.minLength)
+		}
+
+		issues := auditIssues(t, a, args...)
+		weak := slices.Contains(issues, "[weak] Weak password for mail: too short")
+		if weak != tt.wantWeak {
+			t.Errorf("audit --min-length %q: weak = %v, want %v, issues:\n%s", tt.minLength, weak, tt.wantWeak, strings.Join(issues, "\n"))
+		}
+	}
+
+	// The override is not saved
+	data, err := os.ReadFile(a.Config.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"password_length": 16`) {
+		t.Errorf("config file changed by audit --min-length:\n%s", data)
+	}
+
+	for _, minLength := range []string{"0", "-1"} {
+		if _, err := runCommand(t, a, "audit", "--min-length", minLength); errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("audit --min-length %s: err = %v, want invalid input", minLength, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -20,23 +21,22 @@ func newBackupCmd(app *app.App) *cobra.Command {
 		Use:   "backup",
 		Short: "Create a backup of the password database",
 		Long: `Create a backup of the password database.
-Backups are encrypted by default and can be compressed.`,
+Backups are encrypted by default and can be compressed.
+
+Without --output-dir, backups are written to the backup_dir setting, which
+defaults to the backups directory next to the config file (~/.passio/backups).`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			// Create backup directory if it doesn't exist
 			if outputDir == "" {
-				homeDir, err := os.UserHomeDir()
-				if err != nil {
-					return fmt.Errorf("failed to get home directory: %w", err)
-				}
-				outputDir = filepath.Join(homeDir, ".pm", "backups")
+				outputDir = app.Config.BackupDirectory()
 			}
 
 			if err := os.MkdirAll(outputDir, 0700); err != nil {
-				return fmt.Errorf("failed to create backup directory: %w", err)
+				return errs.Internal("failed to create backup directory: %w", err)
 			}
 
 			// Generate backup filename
@@ -49,7 +49,7 @@ Backups are encrypted by default and can be compressed.`,
 
 			// Create backup
 			if err := app.Storage.Backup(backupPath); err != nil {
-				return fmt.Errorf("backup failed: %w", err)
+				return storageError("backup failed", err)
 			}
 
 			fmt.Printf("Successfully created backup: %s\n", backupPath)
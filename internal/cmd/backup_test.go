@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupDefaultsToBackupDirectory(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	output, err := runCommand(t, a, "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".passio", "backups")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("backup did not create %s: %v", dir, err)
+	}
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "pm_backup_") {
+		t.Fatalf("backup directory holds %v, want one pm_backup_ file", entries)
+	}
+	if !strings.Contains(output, filepath.Join(dir, entries[0].Name())) {
+		t.Errorf("backup output %q does not name the backup file", output)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("backup directory permissions = %o, want 700", perm)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".pm")); !os.IsNotExist(err) {
+		t.Errorf("backup touched the old ~/.pm directory: %v", err)
+	}
+}
+
+func TestBackupHonoursBackupDir(t *testing.T) {
+	a := newTestApp(t)
+	home, _ := os.UserHomeDir()
+	a.Config.BackupDir = "~/elsewhere"
+
+	if _, err := runCommand(t, a, "backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(home, "elsewhere"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("backup_dir ~/elsewhere holds %v (%v), want one backup", entries, err)
+	}
+}
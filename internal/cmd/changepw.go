@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func newChangePasswordCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "changepw",
+		Short: "Change the master password",
+		Long: `Change the master password. The current master password is verified first,
+then the new one is prompted for twice.
+
+A fresh salt is generated and every entry is re-encrypted under the key derived
+from the new password, keeping the current key derivation function. All
+entries are re-encrypted in a single transaction and rolled back on failure;
+the new salt and verifier are only saved once every entry was re-encrypted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Print("Enter current master password: ")
+			current, err := readPassword()
+			if err != nil {
+				return errs.Internal("failed to read password: %w", err)
+			}
+
+			if app.IsLocked() {
+				if err := app.Unlock(current); err != nil {
+					return errs.InvalidInput("invalid master password")
+				}
+			} else if !app.Config.ValidateMasterPassword(app, current) {
+				return errs.InvalidInput("invalid master password")
+			}
+
+			newPassword, err := promptNewMasterPassword("new master password")
+			if err != nil {
+				return err
+			}
+			if newPassword == current {
+				return errs.InvalidInput("the new master password must differ from the current one")
+			}
+
+			salt, err := generateSalt(crypto.SaltLength)
+			if err != nil {
+				return errs.Internal("failed to generate salt: %w", err)
+			}
+
+			if err := app.Rekey(current, newPassword, salt, app.Config.KDFParams().Normalized()); err != nil {
+				return errs.Internal("failed to change master password: %w", err)
+			}
+
+			// The key in the keychain was derived from the old password
+			if app.Config.UseKeychain {
+				if err := app.RememberKey(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v; the master password will be prompted for next time\n", err)
+				}
+			}
+
+			fmt.Println("Master password changed")
+			return nil
+		},
+	}
+}
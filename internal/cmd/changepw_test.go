@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+)
+
+const newMasterPassword = "a brand new master password"
+
+func TestChangePassword(t *testing.T) {
+	a := newTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	oldSalt := a.Config.Salt
+	stubPasswords(t, testMasterPassword, newMasterPassword, newMasterPassword)
+
+	if _, err := runCommand(t, a, "changepw"); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(a.Config.Salt) == string(oldSalt) {
+		t.Error("changepw kept the old salt")
+	}
+	updated, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(updated.Password) == string(entry.Password) {
+		t.Error("changepw did not re-encrypt the entry")
+	}
+
+	a.Lock()
+	if err := a.Unlock(testMasterPassword); err == nil {
+		t.Error("the old master password still unlocks")
+	}
+	if err := a.Unlock(newMasterPassword); err != nil {
+		t.Fatalf("the new master password does not unlock: %v", err)
+	}
+	if password, err := a.DecryptPassword(updated.Password); err != nil || password != "hunter2" {
+		t.Errorf("DecryptPassword() = %q, %v", password, err)
+	}
+}
+
+func TestChangePasswordRejectsWrongPassword(t *testing.T) {
+	a := newTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	stubPasswords(t, "wrong", newMasterPassword, newMasterPassword)
+
+	if _, err := runCommand(t, a, "changepw"); err == nil {
+		t.Fatal("changepw succeeded with the wrong master password")
+	}
+
+	unchanged, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(unchanged.Password) != string(entry.Password) {
+		t.Error("the entry was re-encrypted")
+	}
+}
+
+func TestChangePasswordRejectsMismatch(t *testing.T) {
+	a := newTestApp(t)
+	stubPasswords(t, testMasterPassword, newMasterPassword, "something else")
+
+	if _, err := runCommand(t, a, "changepw"); err == nil {
+		t.Fatal("changepw accepted a confirmation that does not match")
+	}
+	a.Lock()
+	if err := a.Unlock(testMasterPassword); err != nil {
+		t.Errorf("the master password changed: %v", err)
+	}
+}
+
+func TestChangePasswordOnLegacyVault(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	// Vaults created before the choice of KDF have no kdf settings
+	a.Config.KDF, a.Config.KDFIterations = "", 0
+	if err := a.Config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	a.Lock()
+	if err := a.Unlock(testMasterPassword); err != nil {
+		t.Fatalf("legacy vault does not unlock: %v", err)
+	}
+
+	stubPasswords(t, testMasterPassword, newMasterPassword, newMasterPassword)
+	if _, err := runCommand(t, a, "changepw"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changepw keeps the key derivation cost, now recorded explicitly
+	want := crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.KDFIterations}
+	if got := a.Config.KDFParams(); got != want {
+		t.Errorf("KDF parameters = %+v, want %+v", got, want)
+	}
+	a.Lock()
+	if err := a.Unlock(newMasterPassword); err != nil {
+		t.Fatalf("the new master password does not unlock: %v", err)
+	}
+	entry, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := a.DecryptPassword(entry.Password); err != nil || password != "hunter2" {
+		t.Errorf("DecryptPassword() = %q, %v", password, err)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"golang.org/x/term"
+)
+
+// copyWithClear copies value, described by label, to the given selection of
+// the clipboard and schedules it to be cleared after timeout seconds. Where
+// the primary selection is not supported, the regular clipboard is used
+// instead. With wait, this process clears the clipboard, showing a countdown
+// on a terminal, and the scheduled clear is returned; the caller must keep
+// running until it is done. Otherwise a detached process clears it and nil is
+// returned, as it is when timeout is not positive. When no clipboard tool is
+// installed and the clipboard_print setting is on, value is printed instead.
+func copyWithClear(app *app.App, label, value string, timeout int, sel clipboard.Selection, wait bool) (*clipboard.PendingClear, error) {
+	cb, err := clipboard.ForSelection(app.Clipboard, sel)
+	if errors.Is(err, clipboard.ErrSelectionUnsupported) {
+		fmt.Fprintf(os.Stderr, "The %s selection is not supported on this system; using the clipboard instead\n", sel)
+		cb, sel = app.Clipboard, clipboard.SelectionClipboard
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to access the %s selection: %w", sel, err)
+	}
+
+	if err := cb.WriteAll(value); err != nil {
+		if errors.Is(err, clipboard.ErrUnavailable) && app.Config.ClipboardPrint {
+			fmt.Fprintf(os.Stderr, "Warning: %v; printing it instead\n", err)
+			fmt.Println(value)
+			return nil, nil
+		}
+		if errors.Is(err, clipboard.ErrUnavailable) {
+			return nil, errs.InvalidInput("%v (or run 'pm config set clipboard_print true' to print instead)", err)
+		}
+		return nil, fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	if sel == clipboard.SelectionPrimary {
+		fmt.Printf("%s copied to the primary selection\n", label)
+	} else {
+		fmt.Printf("%s copied to clipboard\n", label)
+	}
+
+	if timeout <= 0 {
+		return nil, nil
+	}
+
+	// Record the copy so 'pm clip-clear' can ask for it to be cleared early
+	state, err := clipboard.RecordCopy(clipboardStatePath(app, sel))
+	if err != nil {
+		return nil, err
+	}
+
+	if !wait {
+		request := clearRequest{
+			Selection: sel,
+			Timeout:   timeout,
+			StatePath: clipboardStatePath(app, sel),
+			Token:     state.Token(),
+			Value:     value,
+		}
+		if err := startClearer(request); err != nil {
+			// Nothing would clear the clipboard, so do not leave value in it
+			clipboard.ClearIfHolds(cb, value)
+			state.Remove()
+			return nil, errs.Internal("failed to start clearing the clipboard in the background (use --wait to clear it from this process): %w", err)
+		}
+		if isTerminal(os.Stdout) {
+			fmt.Printf("Clipboard will be cleared in %s\n", time.Duration(timeout)*time.Second)
+		}
+		return nil, nil
+	}
+
+	var status func(time.Duration)
+	if isTerminal(os.Stdout) {
+		status = clipboardStatus
+	}
+
+	return clipboard.ClearAfter(cb, value, time.Duration(timeout)*time.Second, state, status), nil
+}
+
+// clearRequest is what a detached clearer needs to clear a copy. It is passed
+// on the clearer's standard input, so the copied value is never written to
+// disk or shown in the process list.
+type clearRequest struct {
+	Selection clipboard.Selection `json:"selection"`
+	Timeout   int                 `json:"timeout"`
+	StatePath string              `json:"state_path"`
+	Token     string              `json:"token"`
+	Value     string              `json:"value"`
+}
+
+// startClearer starts a detached 'pm clip-clear --clearer' process that
+// clears the copy described by request. It is a variable so that tests can
+// clear in-process.
+var startClearer = func(request clearRequest) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	clearer := exec.Command(executable, "clip-clear", "--clearer")
+	clearer.SysProcAttr = detachedProcAttr()
+	stdin, err := clearer.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := clearer.Start(); err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(stdin).Encode(request)
+	stdin.Close()
+	if err != nil {
+		clearer.Process.Kill()
+		return err
+	}
+	return clearer.Process.Release()
+}
+
+// runClearer reads a clearRequest from r and clears the copy it describes
+// once its timeout has elapsed or a clear is requested.
+func runClearer(app *app.App, r io.Reader) error {
+	var request clearRequest
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return errs.InvalidInput("invalid clear request: %v", err)
+	}
+
+	cb, err := clipboard.ForSelection(app.Clipboard, request.Selection)
+	if err != nil {
+		return errs.Internal("failed to access the %s selection: %w", request.Selection, err)
+	}
+
+	state := clipboard.OpenCopyState(request.StatePath, request.Token)
+	<-clipboard.ClearAfter(cb, request.Value, time.Duration(request.Timeout)*time.Second, state, nil).Done()
+	return nil
+}
+
+// clipboardStatePath returns the file recording the last copy to sel.
+func clipboardStatePath(app *app.App, sel clipboard.Selection) string {
+	name := "clipboard.state"
+	if sel != clipboard.SelectionClipboard {
+		name = string(sel) + ".state"
+	}
+	return filepath.Join(filepath.Dir(app.Config.ConfigPath), name)
+}
+
+func clipboardStatus(remaining time.Duration) {
+	if remaining <= 0 {
+		fmt.Print("\r\033[KClipboard cleared\n")
+		return
+	}
+	fmt.Printf("\r\033[KClipboard will be cleared in %s", remaining)
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
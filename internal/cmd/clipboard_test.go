@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+)
+
+// captureClearers records the clear requests of detached clearers instead of
+// starting them.
+func captureClearers(t *testing.T) *[]clearRequest {
+	t.Helper()
+	var requests []clearRequest
+	original := startClearer
+	startClearer = func(request clearRequest) error {
+		requests = append(requests, request)
+		return nil
+	}
+	t.Cleanup(func() { startClearer = original })
+	return &requests
+}
+
+func TestCopyStartsDetachedClearer(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	requests := captureClearers(t)
+
+	output, err := runCommand(t, a, "get", "github", "--copy", "--clear-after", "30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("started %d clearers, want 1", len(*requests))
+	}
+	request := (*requests)[0]
+	if request.Value != "hunter2" || request.Timeout != 30 || request.Selection != clipboard.SelectionClipboard {
+		t.Errorf("clear request = %+v", request)
+	}
+	token, err := os.ReadFile(request.StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(token) != request.Token {
+		t.Errorf("state file holds %q, want the clearer's token %q", token, request.Token)
+	}
+
+	// Output is not a terminal, so there is no status line
+	if strings.Contains(output, "cleared") {
+		t.Errorf("get printed %q off a terminal", output)
+	}
+}
+
+func TestGenerateStartsDetachedClearer(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.ClipboardTimeout = 45
+	requests := captureClearers(t)
+
+	output, err := runCommand(t, a, "generate", "--copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("started %d clearers, want 1", len(*requests))
+	}
+	if request := (*requests)[0]; !strings.Contains(output, request.Value) || request.Timeout != 45 {
+		t.Errorf("clear request = %+v for output %q", request, output)
+	}
+}
+
+func TestCopyWithoutTimeoutIsNotCleared(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	requests := captureClearers(t)
+
+	if _, err := runCommand(t, a, "get", "github", "--copy", "--clear-after", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 0 {
+		t.Errorf("started %d clearers with clearing disabled", len(*requests))
+	}
+	if _, err := os.Stat(clipboardStatePath(a, clipboard.SelectionClipboard)); !os.IsNotExist(err) {
+		t.Errorf("recorded a copy that is never cleared: %v", err)
+	}
+	waitForClipboard(t, a, "hunter2")
+}
+
+func TestCopyWaitClearsInProcess(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	requests := captureClearers(t)
+
+	if _, err := runCommand(t, a, "get", "github", "--copy", "--wait", "--clear-after", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 0 {
+		t.Errorf("started %d detached clearers with --wait", len(*requests))
+	}
+	if got, _ := a.Clipboard.ReadAll(); got != "" {
+		t.Errorf("clipboard holds %q once get --wait returned", got)
+	}
+	if _, err := os.Stat(clipboardStatePath(a, clipboard.SelectionClipboard)); !os.IsNotExist(err) {
+		t.Errorf("clipboard state file still exists: %v", err)
+	}
+}
+
+func TestCopyClearsWhenClearerFails(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	original := startClearer
+	startClearer = func(request clearRequest) error { return errors.New("no executable") }
+	t.Cleanup(func() { startClearer = original })
+
+	if _, err := runCommand(t, a, "get", "github", "--copy", "--clear-after", "30"); err == nil {
+		t.Fatal("get --copy succeeded with no way to clear the clipboard")
+	}
+
+	if got, _ := a.Clipboard.ReadAll(); got != "" {
+		t.Errorf("clipboard holds %q with no clearer running", got)
+	}
+	if _, err := os.Stat(clipboardStatePath(a, clipboard.SelectionClipboard)); !os.IsNotExist(err) {
+		t.Errorf("clipboard state file still exists: %v", err)
+	}
+}
+
+// plainClipboard has no primary selection
+type plainClipboard struct {
+	clipboard.Clipboard
+}
+
+func TestClipPrimary(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"get", []string{"get", "github", "--clip-primary", "--clear-after", "30"}},
+		{"generate", []string{"generate", "--clip-primary"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			a.Config.ClipboardTimeout = 30
+			stubPassword(t, testMasterPassword)
+			addTestEntry(t, a, "github", "hunter2")
+			requests := captureClearers(t)
+
+			output, err := runCommand(t, a, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(output, "copied to the primary selection") {
+				t.Errorf("%s printed %q", tt.name, output)
+			}
+
+			primary, err := clipboard.ForSelection(a.Clipboard, clipboard.SelectionPrimary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copied, _ := primary.ReadAll()
+			if copied == "" {
+				t.Error("primary selection is empty")
+			}
+			if got, _ := a.Clipboard.ReadAll(); got != "" {
+				t.Errorf("clipboard holds %q, want it untouched", got)
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("started %d clearers, want 1", len(*requests))
+			}
+			request := (*requests)[0]
+			if request.Selection != clipboard.SelectionPrimary || request.Value != copied ||
+				request.StatePath != clipboardStatePath(a, clipboard.SelectionPrimary) {
+				t.Errorf("clear request = %+v", request)
+			}
+		})
+	}
+}
+
+func TestClipPrimaryFallsBackToClipboard(t *testing.T) {
+	a := newTestApp(t)
+	a.Clipboard = plainClipboard{clipboard.NewMemoryClipboard()}
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	requests := captureClearers(t)
+
+	output, err := runCommand(t, a, "get", "github", "--clip-primary", "--clear-after", "30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "copied to clipboard") {
+		t.Errorf("get printed %q", output)
+	}
+	if got, _ := a.Clipboard.ReadAll(); got != "hunter2" {
+		t.Errorf("clipboard holds %q, want hunter2", got)
+	}
+	if len(*requests) != 1 || (*requests)[0].Selection != clipboard.SelectionClipboard {
+		t.Errorf("clear requests = %+v, want one for the clipboard", *requests)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func newClipClearCmd(app *app.App) *cobra.Command {
+	var clearer bool
+
+	cmd := &cobra.Command{
+		Use:   "clip-clear",
+		Short: "Clear a password copied by passio from the clipboard",
+		Long: `Clear the last value copied by passio without waiting for the clipboard
+timeout. The passio process waiting to clear it does so right away, but only
+if the clipboard still holds that value; anything copied since then is left
+untouched. The primary selection is cleared the same way where it is supported.
+
+passio never records what it copied, so only copies still waiting to be
+cleared can be cleared this way.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if clearer {
+				return runClearer(app, cmd.InOrStdin())
+			}
+
+			requested, err := clipboard.RequestClear(clipboardStatePath(app, clipboard.SelectionClipboard))
+			if err != nil {
+				return errs.Internal("failed to clear clipboard: %w", err)
+			}
+
+			if _, err := clipboard.ForSelection(app.Clipboard, clipboard.SelectionPrimary); err == nil {
+				requestedPrimary, err := clipboard.RequestClear(clipboardStatePath(app, clipboard.SelectionPrimary))
+				if err != nil {
+					return errs.Internal("failed to clear primary selection: %w", err)
+				}
+				requested = requested || requestedPrimary
+			}
+
+			if !requested {
+				fmt.Println("No value copied by passio is waiting to be cleared; clipboard left untouched")
+				return nil
+			}
+
+			fmt.Println("Clipboard cleared, unless it no longer holds the value copied by passio")
+			return nil
+		},
+	}
+
+	// Run by copying commands to clear the clipboard once they have exited
+	cmd.Flags().BoolVar(&clearer, "clearer", false, "Clear the copy described on standard input once its timeout elapses")
+	cmd.Flags().MarkHidden("clearer")
+
+	return cmd
+}
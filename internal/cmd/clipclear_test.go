@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+)
+
+// waitForClipboard fails the test if the clipboard of a does not hold want
+// within a second.
+func waitForClipboard(t *testing.T, a *app.App, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := a.Clipboard.ReadAll()
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("clipboard holds %q, want %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClipClearClearsCopiedPassword(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	if _, err := runCommand(t, a, "get", "github", "--copy", "--clear-after", "3600"); err != nil {
+		t.Fatal(err)
+	}
+	waitForClipboard(t, a, "hunter2")
+
+	state, err := os.ReadFile(clipboardStatePath(a, clipboard.SelectionClipboard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(state), "hunter2") {
+		t.Error("the clipboard state file holds the password")
+	}
+
+	output, err := runCommand(t, a, "clip-clear")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Clipboard cleared") {
+		t.Errorf("clip-clear printed %q", output)
+	}
+	waitForClipboard(t, a, "")
+
+	if _, err := os.Stat(clipboardStatePath(a, clipboard.SelectionClipboard)); !os.IsNotExist(err) {
+		t.Errorf("clipboard state file still exists: %v", err)
+	}
+}
+
+func TestClipClearLeavesLaterCopies(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	if _, err := runCommand(t, a, "get", "github", "--copy", "--clear-after", "3600"); err != nil {
+		t.Fatal(err)
+	}
+	a.Clipboard.WriteAll("copied from elsewhere")
+
+	if _, err := runCommand(t, a, "clip-clear"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the clearer time to act on the request
+	time.Sleep(100 * time.Millisecond)
+	waitForClipboard(t, a, "copied from elsewhere")
+}
+
+func TestClipClearWithNothingCopied(t *testing.T) {
+	a := newTestApp(t)
+
+	output, err := runCommand(t, a, "clip-clear")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "left untouched") {
+		t.Errorf("clip-clear printed %q", output)
+	}
+}
+
+func TestClipClearClearsPrimarySelection(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	primary, err := clipboard.ForSelection(a.Clipboard, clipboard.SelectionPrimary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCommand(t, a, "get", "github", "--clip-primary", "--clear-after", "3600"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := primary.ReadAll(); got != "hunter2" {
+		t.Fatalf("primary selection holds %q, want hunter2", got)
+	}
+
+	if _, err := runCommand(t, a, "clip-clear"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for got, _ := primary.ReadAll(); got != ""; got, _ = primary.ReadAll() {
+		if time.Now().After(deadline) {
+			t.Fatalf("primary selection holds %q after clip-clear", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(clipboardStatePath(a, clipboard.SelectionPrimary)); !os.IsNotExist(err) {
+		t.Errorf("primary selection state file still exists: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// colorEnabled reports whether cmd may write terminal colors and other
+// styling to f. It is the single place this is decided: styling is off with
+// the --no-color flag, when the NO_COLOR environment variable is set to a
+// non-empty value (https://no-color.org), and when f is not a terminal.
+func colorEnabled(cmd *cobra.Command, f *os.File) bool {
+	if noColor, err := cmd.Flags().GetBool("no-color"); err == nil && noColor {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(f)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestColorEnabled(t *testing.T) {
+	notTerminal, err := os.Create(t.TempDir() + "/output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notTerminal.Close()
+
+	terminal := openTerminal(t)
+
+	tests := []struct {
+		name     string
+		noColor  bool
+		env      string
+		terminal bool
+		want     bool
+	}{
+		{"terminal", false, "", true, true},
+		{"--no-color", true, "", true, false},
+		{"NO_COLOR", false, "1", true, false},
+		{"NO_COLOR with any value", false, "false", true, false},
+		{"not a terminal", false, "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := notTerminal
+			if tt.terminal {
+				if terminal == nil || !isTerminal(terminal) {
+					t.Skip("no terminal available")
+				}
+				f = terminal
+			}
+			t.Setenv("NO_COLOR", tt.env)
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("no-color", false, "")
+			if tt.noColor {
+				cmd.Flags().Set("no-color", "true")
+			}
+
+			if got := colorEnabled(cmd, f); got != tt.want {
+				t.Errorf("colorEnabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHighlightIsPlainOffTerminal(t *testing.T) {
+	a := newTestApp(t)
+	addSearchEntry(t, a, "github", "alice")
+
+	for _, args := range [][]string{
+		{"search", "git", "--highlight"},
+		{"search", "git", "--highlight", "--no-color"},
+	} {
+		output, err := runCommand(t, a, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(output, "github") || strings.Contains(output, "\033[") {
+			t.Errorf("%v printed %q", args, output)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func newCompactCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "compact",
+		Short: "Reclaim unused space in the password database",
+		Long: `Rebuild the password database to reclaim space left behind by deleted entries.
+Reports the database size before and after compaction.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			before, after, err := app.Storage.Compact()
+			if err != nil {
+				return errs.Internal("compact failed: %w", err)
+			}
+
+			fmt.Printf("Database size before: %s\n", formatBytes(before))
+			fmt.Printf("Database size after: %s\n", formatBytes(after))
+			fmt.Printf("Reclaimed: %s\n", formatBytes(before-after))
+			return nil
+		},
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
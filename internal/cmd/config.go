@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -14,22 +22,37 @@ func newConfigCmd(app *app.App) *cobra.Command {
 		Use:   "config",
 		Short: "Manage configuration settings",
 		Long: `Manage configuration settings for the password manager.
-Use 'get' to view settings and 'set' to modify them.`,
+Use 'get' to view settings and 'set' to modify them, or 'edit' to edit the
+config file in your editor.`,
 	}
 
 	cmd.AddCommand(newConfigGetCmd(app))
 	cmd.AddCommand(newConfigSetCmd(app))
+	cmd.AddCommand(newConfigEditCmd(app))
 
 	return cmd
 }
 
 func newConfigGetCmd(app *app.App) *cobra.Command {
-	return &cobra.Command{
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
 		Use:   "get [setting]",
 		Short: "Get configuration settings",
 		Long: `Get the current value of a configuration setting.
-If no setting is specified, all settings are displayed.`,
+If no setting is specified, all settings are displayed.
+Use --json to print all non-secret settings as a single JSON object.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				if len(args) > 0 {
+					return errs.InvalidInput("--json prints all settings and takes no setting name")
+				}
+
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(app.Config.Settings())
+			}
+
 			if len(args) == 0 {
 				// Display all settings
 				fmt.Println("Current configuration:")
@@ -40,6 +63,30 @@ If no setting is specified, all settings are displayed.`,
 				fmt.Printf("require_master_pass: %v\n", app.Config.RequireMasterPassword)
 				fmt.Printf("backup_encrypted: %v\n", app.Config.BackupEncrypted)
 				fmt.Printf("password_expiration: %d days\n", app.Config.PasswordExpiration)
+				fmt.Printf("name_uniqueness: %v\n", app.Config.GetConfigValue("name_uniqueness"))
+				fmt.Printf("access_log: %v\n", app.Config.AccessLog)
+				fmt.Printf("backup_dir: %v\n", app.Config.BackupDirectory())
+				fmt.Printf("export_dir: %v\n", app.Config.ExportDirectory())
+				fmt.Printf("strength_estimator: %v\n", app.Config.GetConfigValue("strength_estimator"))
+				fmt.Printf("common_passwords_path: %v\n", app.Config.GetConfigValue("common_passwords_path"))
+				fmt.Printf("use_keychain: %v\n", app.Config.UseKeychain)
+				fmt.Printf("encrypt_notes: %v\n", app.Config.EncryptNotes)
+				fmt.Printf("clipboard_print: %v\n", app.Config.ClipboardPrint)
+				fmt.Printf("cipher: %v\n", app.Config.GetConfigValue("cipher"))
+				fmt.Printf("password_generator: %v\n", app.Config.GetConfigValue("password_generator"))
+				fmt.Printf("passphrase_words: %v\n", app.Config.GetConfigValue("passphrase_words"))
+				fmt.Printf("max_import_entries: %v\n", app.Config.GetConfigValue("max_import_entries"))
+				fmt.Printf("max_name_length: %v bytes\n", app.Config.GetConfigValue("max_name_length"))
+				fmt.Printf("max_password_length: %v bytes\n", app.Config.GetConfigValue("max_password_length"))
+				fmt.Printf("max_notes_length: %v bytes\n", app.Config.GetConfigValue("max_notes_length"))
+				fmt.Printf("kdf_algorithm: %v (read-only)\n", app.Config.GetConfigValue("kdf_algorithm"))
+				fmt.Printf("kdf_iterations: %v (read-only)\n", app.Config.GetConfigValue("kdf_iterations"))
+				if app.Config.KDF == "argon2id" {
+					fmt.Printf("kdf_memory: %v KiB (read-only)\n", app.Config.GetConfigValue("kdf_memory"))
+					fmt.Printf("kdf_parallelism: %v (read-only)\n", app.Config.GetConfigValue("kdf_parallelism"))
+				}
+				fmt.Printf("key_length: %v bytes (read-only)\n", app.Config.GetConfigValue("key_length"))
+				fmt.Printf("salt_length: %v bytes (read-only)\n", app.Config.GetConfigValue("salt_length"))
 				return nil
 			}
 
@@ -47,13 +94,17 @@ If no setting is specified, all settings are displayed.`,
 			setting := args[0]
 			value := app.Config.GetConfigValue(setting)
 			if value == nil {
-				return fmt.Errorf("unknown setting: %s", setting)
+				return errs.InvalidInput("unknown setting: %s", setting)
 			}
 
 			fmt.Printf("%s: %v\n", setting, value)
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output all settings as JSON")
+
+	return cmd
 }
 
 func newConfigSetCmd(app *app.App) *cobra.Command {
@@ -68,7 +119,28 @@ Available settings:
   - auto_lock_timeout: Time in seconds of inactivity before auto-lock (int)
   - require_master_pass: Whether to require master password for sensitive operations (bool)
   - backup_encrypted: Whether to encrypt backup files (bool)
-  - password_expiration: Number of days before passwords are considered expired (int)`,
+  - password_expiration: Number of days before passwords are considered expired (int)
+  - name_uniqueness: Whether entry names are unique "global"ly or per "folder" (string)
+  - access_log: Whether to record entry access in the access log (bool)
+  - backup_dir: Directory backups are written to by default (string)
+  - export_dir: Directory exports are written to by default (string)
+  - strength_estimator: How audit and stats rate passwords, "heuristic" or "zxcvbn" (string)
+  - common_passwords_path: Newline-delimited list of common passwords replacing the
+    built-in one, or "" for the built-in list (string)
+  - use_keychain: Whether to keep the master key in the OS keychain so unlock needs no password (bool)
+  - encrypt_notes: Whether to encrypt the notes of new and updated entries (bool)
+  - clipboard_print: Whether --copy prints the value when no clipboard tool is installed (bool)
+  - cipher: Cipher new and updated secrets are encrypted with, "aes-gcm" or
+    "chacha20-poly1305"; secrets encrypted with either remain readable (string)
+  - password_generator: What add and update --generate create, "random" passwords or diceware "passphrase"s (string)
+  - passphrase_words: Number of words in generated passphrases (int)
+  - max_import_entries: Most entries a single import may add (int)
+  - max_name_length, max_password_length, max_notes_length: Maximum size in bytes
+    of entry names, passwords and notes, encrypted ones as stored (int)
+
+The key derivation settings kdf_algorithm, kdf_iterations, kdf_memory,
+kdf_parallelism, key_length and salt_length are read-only, as changing them
+requires re-encrypting the vault with 'pm rekey'.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			setting := args[0]
@@ -79,43 +151,97 @@ Available settings:
 
 			// Parse value based on setting type
 			switch setting {
-			case "password_length", "clipboard_timeout", "auto_lock_timeout", "password_expiration":
+			case "password_length", "clipboard_timeout", "auto_lock_timeout", "password_expiration", "passphrase_words",
+				"max_import_entries", "max_name_length", "max_password_length", "max_notes_length":
 				value, err = strconv.Atoi(valueStr)
 				if err != nil {
-					return fmt.Errorf("invalid integer value: %s", valueStr)
+					return errs.InvalidInput("invalid integer value: %s", valueStr)
 				}
-			case "use_special_chars", "require_master_pass", "backup_encrypted":
+			case "use_special_chars", "require_master_pass", "backup_encrypted", "access_log", "use_keychain", "encrypt_notes", "clipboard_print":
 				valueLower := strings.ToLower(valueStr)
 				if valueLower == "true" || valueLower == "1" || valueLower == "yes" {
 					value = true
 				} else if valueLower == "false" || valueLower == "0" || valueLower == "no" {
 					value = false
 				} else {
-					return fmt.Errorf("invalid boolean value: %s", valueStr)
+					return errs.InvalidInput("invalid boolean value: %s", valueStr)
 				}
+			case "name_uniqueness", "strength_estimator", "password_generator", "cipher":
+				value = strings.ToLower(valueStr)
+			case "backup_dir", "export_dir", "common_passwords_path":
+				value = valueStr
+			case "kdf_algorithm", "kdf_iterations", "kdf_memory", "kdf_parallelism", "key_length", "salt_length":
+				return errs.InvalidInput("%s is read-only. Key derivation can only change by re-encrypting the vault with 'pm rekey'", setting)
 			default:
-				return fmt.Errorf("unknown setting: %s", setting)
+				return errs.InvalidInput("unknown setting: %s", setting)
 			}
 
 			// Validate values
 			switch setting {
 			case "password_length":
 				if v := value.(int); v < 8 {
-					return fmt.Errorf("password length must be at least 8")
+					return errs.InvalidInput("password length must be at least 8")
 				}
 			case "clipboard_timeout", "auto_lock_timeout":
 				if v := value.(int); v < 0 {
-					return fmt.Errorf("timeout values must be non-negative")
+					return errs.InvalidInput("timeout values must be non-negative")
 				}
 			case "password_expiration":
 				if v := value.(int); v < 0 {
-					return fmt.Errorf("expiration days must be non-negative")
+					return errs.InvalidInput("expiration days must be non-negative")
+				}
+			case "name_uniqueness":
+				if v := value.(string); v != string(storage.NameScopeGlobal) && v != string(storage.NameScopeFolder) {
+					return errs.InvalidInput("name uniqueness must be global or folder")
+				}
+			case "common_passwords_path":
+				if err := app.LoadCommonPasswords(value.(string)); err != nil {
+					return errs.InvalidInput("%v", err)
+				}
+			case "cipher":
+				if _, err := crypto.NewEncryption(value.(string)); err != nil {
+					return errs.InvalidInput("%v", err)
+				}
+			case "strength_estimator":
+				if v := value.(string); v != "heuristic" && v != "zxcvbn" {
+					return errs.InvalidInput("strength estimator must be heuristic or zxcvbn")
+				}
+			case "password_generator":
+				if v := value.(string); v != "random" && v != "passphrase" {
+					return errs.InvalidInput("password generator must be random or passphrase")
+				}
+			case "passphrase_words":
+				if v := value.(int); v < 1 {
+					return errs.InvalidInput("passphrase words must be positive")
+				}
+			case "max_import_entries", "max_name_length", "max_password_length", "max_notes_length":
+				if v := value.(int); v < 1 {
+					return errs.InvalidInput("limits must be positive")
+				}
+			}
+
+			// Apply the name scope to the database before saving it
+			if setting == "name_uniqueness" {
+				if err := app.Storage.SetNameScope(storage.NameScope(value.(string))); err != nil {
+					return storageError("failed to change name uniqueness", err)
 				}
 			}
 
 			// Update configuration
 			if err := app.Config.SetConfigValue(setting, value); err != nil {
-				return fmt.Errorf("failed to update configuration: %w", err)
+				return errs.Internal("failed to update configuration: %w", err)
+			}
+
+			app.Storage.SetLimits(app.Config.EntryLimits())
+			if setting == "cipher" {
+				app.Encryption, _ = crypto.NewEncryption(app.Config.Cipher)
+			}
+
+			// The key is saved on the next unlock, but must not outlive the setting
+			if setting == "use_keychain" && !value.(bool) {
+				if err := app.ForgetKey(); err != nil {
+					return errs.Internal("failed to remove key from keychain: %w", err)
+				}
 			}
 
 			fmt.Printf("Successfully updated %s to %v\n", setting, value)
@@ -123,3 +249,81 @@ Available settings:
 		},
 	}
 }
+
+func newConfigEditCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the config file in your editor",
+		Long: `Open a copy of the config file in $VISUAL or $EDITOR (vi by default). When the
+editor exits, the edited copy is validated and only saved if it can be parsed,
+its values are within the limits of 'config set' and the master key and salt
+are unchanged. Otherwise the config file is left as it was.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath := app.Config.ConfigPath
+			original, err := os.ReadFile(configPath)
+			if err != nil {
+				return errs.Internal("failed to read config file: %w", err)
+			}
+
+			// Edit a private copy so the config is never left half-written
+			tmp, err := os.CreateTemp(filepath.Dir(configPath), "config-edit-*"+filepath.Ext(configPath))
+			if err != nil {
+				return errs.Internal("failed to create temporary file: %w", err)
+			}
+			defer os.Remove(tmp.Name())
+
+			_, err = tmp.Write(original)
+			if cerr := tmp.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return errs.Internal("failed to write temporary file: %w", err)
+			}
+
+			if err := runEditor(tmp.Name()); err != nil {
+				return errs.Internal("editor failed, no changes were saved: %w", err)
+			}
+
+			edited, err := os.ReadFile(tmp.Name())
+			if err != nil {
+				return errs.Internal("failed to read edited config: %w", err)
+			}
+
+			if bytes.Equal(edited, original) {
+				fmt.Println("No changes made")
+				return nil
+			}
+
+			if err := app.Config.SaveEdited(edited); err != nil {
+				return errs.InvalidInput("invalid config, no changes were saved: %w", err)
+			}
+
+			if err := app.RefreshConfig(); err != nil {
+				return errs.Internal("failed to apply edited config: %w", err)
+			}
+
+			fmt.Println("Configuration updated")
+			return nil
+		},
+	}
+}
+
+// runEditor opens path in the user's editor and waits for it to exit. The
+// editor command may include arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestConfigKDFSettingsAreReadOnly(t *testing.T) {
+	for _, setting := range app.ReadOnlySettings {
+		t.Run(setting, func(t *testing.T) {
+			a := newTestApp(t)
+			before, err := os.ReadFile(a.Config.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := runCommand(t, a, "config", "get", setting)
+			if err != nil {
+				t.Fatalf("config get %s: %v", setting, err)
+			}
+			if want := fmt.Sprintf("%s: %v\n", setting, a.Config.GetConfigValue(setting)); output != want {
+				t.Errorf("config get %s printed %q, want %q", setting, output, want)
+			}
+
+			if _, err := runCommand(t, a, "config", "set", setting, "1"); errs.ExitCode(err) != errs.ExitInvalidInput {
+				t.Errorf("config set %s: err = %v, want invalid input", setting, err)
+			}
+			after, err := os.ReadFile(a.Config.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(after) != string(before) {
+				t.Errorf("config set %s changed the config file", setting)
+			}
+		})
+	}
+
+	a := newTestApp(t)
+	output, err := runCommand(t, a, "config", "get")
+	if err != nil {
+		t.Fatalf("config get: %v", err)
+	}
+	lines := strings.Split(output, "\n")
+	for _, setting := range []string{"kdf_algorithm", "kdf_iterations", "key_length", "salt_length"} {
+		listed := slices.ContainsFunc(lines, func(line string) bool {
+			return strings.HasPrefix(line, setting+": ") && strings.HasSuffix(line, "(read-only)")
+		})
+		if !listed {
+			t.Errorf("config get does not list %s as read-only:\n%s", setting, output)
+		}
+	}
+}
+
+func TestConfigGetJSON(t *testing.T) {
+	a := newTestApp(t)
+	output, err := runCommand(t, a, "config", "get", "--json")
+	if err != nil {
+		t.Fatalf("config get --json: %v", err)
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &settings); err != nil {
+		t.Fatalf("config get --json printed invalid JSON: %v", err)
+	}
+	for _, key := range slices.Concat(app.ConfigSettings, app.ReadOnlySettings) {
+		if _, ok := settings[key]; !ok {
+			t.Errorf("config get --json is missing %s", key)
+		}
+	}
+	if len(settings) != len(app.ConfigSettings)+len(app.ReadOnlySettings) {
+		t.Errorf("config get --json printed %d settings, want %d", len(settings), len(app.ConfigSettings)+len(app.ReadOnlySettings))
+	}
+
+	// Key material is never printed
+	for _, key := range []string{"salt", "master_hash", "master_verifier"} {
+		if _, ok := settings[key]; ok {
+			t.Errorf("config get --json printed %s", key)
+		}
+	}
+
+	if _, err := runCommand(t, a, "config", "get", "--json", "cipher"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("config get --json with a setting name: err = %v, want invalid input", err)
+	}
+}
+
+// stubEditor makes config edit run the shell commands in script, with the
+// file to edit as $1.
+func stubEditor(t *testing.T, script string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", path)
+}
+
+func TestConfigEdit(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantOutput string
+		wantCode   int
+		wantLength int
+	}{
+		{"unchanged", "true", "No changes made", 0, 16},
+		{"changed", `sed -i 's/"password_length": 16/"password_length": 24/' "$1"`, "Configuration updated", 0, 24},
+		{"not parsable", `echo '{' >> "$1"`, "", errs.ExitInvalidInput, 16},
+		{"invalid value", `sed -i 's/"password_length": 16/"password_length": 4/' "$1"`, "", errs.ExitInvalidInput, 16},
+		{"salt changed", `sed -i 's/"salt": "[^"]*"/"salt": "AAAAAAAAAAAAAAAAAAAAAA=="/' "$1"`, "", errs.ExitInvalidInput, 16},
+		{"editor failed", `sed -i 's/"password_length": 16/"password_length": 24/' "$1"; exit 1`, "", errs.ExitInternal, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			before, err := os.ReadFile(a.Config.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stubEditor(t, tt.script)
+
+			output, err := runCommand(t, a, "config", "edit")
+			if tt.wantCode == 0 && err != nil {
+				t.Fatalf("config edit: %v", err)
+			}
+			if tt.wantCode != 0 && errs.ExitCode(err) != tt.wantCode {
+				t.Fatalf("config edit: err = %v, want exit code %d", err, tt.wantCode)
+			}
+			if !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("config edit printed %q, want %q", output, tt.wantOutput)
+			}
+			if a.Config.PasswordLength != tt.wantLength {
+				t.Errorf("password_length = %d, want %d", a.Config.PasswordLength, tt.wantLength)
+			}
+
+			after, err := os.ReadFile(a.Config.ConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := string(after) != string(before); changed != (tt.wantLength != 16) {
+				t.Errorf("config file changed = %v:\n%s", changed, after)
+			}
+
+			// The temporary copy is removed
+			leftover, _ := filepath.Glob(filepath.Join(filepath.Dir(a.Config.ConfigPath), "config-edit-*"))
+			if len(leftover) != 0 {
+				t.Errorf("temporary copies left behind: %v", leftover)
+			}
+		})
+	}
+}
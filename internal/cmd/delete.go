@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +20,7 @@ Use --force to skip confirmation prompt.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			name := args[0]
@@ -37,7 +38,10 @@ Use --force to skip confirmation prompt.`,
 			}
 
 			if err := app.Storage.DeleteEntry(name); err != nil {
-				return fmt.Errorf("failed to delete entry: %w", err)
+				return storageError("failed to delete entry", err)
+			}
+			if err := logAccess(app, name, storage.AccessDelete); err != nil {
+				return err
 			}
 
 			fmt.Printf("Successfully deleted entry: %s\n", name)
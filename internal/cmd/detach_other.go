@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package cmd
+
+import "syscall"
+
+// Processes cannot be detached, so they are started as usual
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
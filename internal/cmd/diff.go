@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd(app *app.App) *cobra.Command {
+	var (
+		vault   bool
+		show    bool
+		decrypt bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <export-a> <export-b>",
+		Short: "Compare two exports",
+		Long: `Compare the entries of two JSON or CSV exports and report the entries only in
+the first, only in the second, and the fields of those that differ.
+Use --vault to compare the current vault against a single export, e.g. one
+taken as a backup.
+
+Differing values are only printed with --show, as they include passwords. It
+asks for the master password again when require_master_pass is set, and
+records a reveal in the access log for every entry whose values are printed.
+The passwords of write-only entries are never decrypted or printed: they are
+compared by their encrypted value and reported as changed at most.
+Encrypted exports need the vault to be unlocked. Use --decrypt to be prompted
+for the master password of an export from another vault.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if vault {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sides [2]map[string]*diffEntry
+			var labels [2]string
+
+			files := args
+			if vault {
+				if app.IsLocked() {
+					return errLocked
+				}
+
+				entries, err := loadVaultForDiff(app)
+				if err != nil {
+					return err
+				}
+				sides[0], labels[0] = entries, "vault"
+				files = []string{"", args[0]}
+			}
+
+			for i, filename := range files {
+				if filename == "" {
+					continue
+				}
+
+				entries, err := loadExportForDiff(app, filename, decrypt)
+				if err != nil {
+					return err
+				}
+				sides[i], labels[i] = entries, filename
+			}
+
+			result := diffEntries(sides[0], sides[1])
+			if result.empty() {
+				fmt.Println("No differences")
+				return nil
+			}
+			if show && len(result.changed) > 0 {
+				if err := verifyMasterPassword(app); err != nil {
+					return err
+				}
+			}
+
+			if len(result.onlyA) > 0 {
+				fmt.Printf("Only in %s:\n", labels[0])
+				for _, name := range result.onlyA {
+					fmt.Printf("- %s\n", name)
+				}
+			}
+			if len(result.onlyB) > 0 {
+				fmt.Printf("Only in %s:\n", labels[1])
+				for _, name := range result.onlyB {
+					fmt.Printf("- %s\n", name)
+				}
+			}
+			if len(result.changed) > 0 {
+				fmt.Println("Changed:")
+				for _, change := range result.changed {
+					fmt.Printf("- %s: %s\n", change.name, strings.Join(change.fields, ", "))
+					if !show {
+						continue
+					}
+					if err := logAccess(app, change.name, storage.AccessReveal); err != nil {
+						return err
+					}
+					a, b := sides[0][change.name], sides[1][change.name]
+					for _, field := range change.fields {
+						if field == "password" && (a.writeOnly || b.writeOnly) {
+							fmt.Printf("    %s: (write-only)\n", field)
+							continue
+						}
+						fmt.Printf("    %s: %q -> %q\n", field, a.values[field], b.values[field])
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&vault, "vault", false, "Compare the current vault against a single export")
+	cmd.Flags().BoolVar(&show, "show", false, "Show the differing values, including passwords")
+	cmd.Flags().BoolVarP(&decrypt, "decrypt", "d", false, "Prompt for the master password of exports from another vault")
+
+	return cmd
+}
+
+// diffFields are the compared fields of an entry, in reporting order. Custom
+// fields follow as "field_" plus their name.
+var diffFields = []string{"username", "password", "url", "notes", "tags", "type", "folder"}
+
+// diffEntry holds the decrypted values of an entry, keyed by field name.
+// The password of a write-only entry is never decrypted: its value is a
+// digest of the encrypted password instead, see writeOnlyPassword.
+type diffEntry struct {
+	values    map[string]string
+	writeOnly bool
+}
+
+// writeOnlyPassword returns the value a write-only entry's password is
+// compared by, which identifies the stored bytes without revealing them.
+func writeOnlyPassword(stored []byte) string {
+	sum := sha256.Sum256(stored)
+	return "write-only:" + hex.EncodeToString(sum[:])
+}
+
+func newDiffEntry(username, password, url, notes string, tags []string, entryType, folder string, fields map[string]string) *diffEntry {
+	if entryType == "" {
+		entryType = string(storage.EntryTypeLogin)
+	}
+	tags = slices.Clone(tags)
+	slices.Sort(tags)
+
+	values := map[string]string{
+		"username": username,
+		"password": password,
+		"url":      url,
+		"notes":    notes,
+		"tags":     strings.Join(tags, ","),
+		"type":     entryType,
+		"folder":   folder,
+	}
+	for key, value := range fields {
+		values["field_"+key] = value
+	}
+	return &diffEntry{values: values, writeOnly: entryType == string(storage.EntryTypeWriteOnly)}
+}
+
+// diffChange lists the fields that differ for an entry present on both sides.
+type diffChange struct {
+	name   string
+	fields []string
+}
+
+type diffResult struct {
+	onlyA   []string
+	onlyB   []string
+	changed []diffChange
+}
+
+func (r *diffResult) empty() bool {
+	return len(r.onlyA) == 0 && len(r.onlyB) == 0 && len(r.changed) == 0
+}
+
+// diffEntries classifies the entries of a and b by name into those only in
+// a, only in b, and those in both with differing fields, each sorted by name.
+func diffEntries(a, b map[string]*diffEntry) *diffResult {
+	result := &diffResult{}
+
+	for name, entryA := range a {
+		entryB, ok := b[name]
+		if !ok {
+			result.onlyA = append(result.onlyA, name)
+			continue
+		}
+		if fields := changedFields(entryA, entryB); len(fields) > 0 {
+			result.changed = append(result.changed, diffChange{name: name, fields: fields})
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			result.onlyB = append(result.onlyB, name)
+		}
+	}
+
+	slices.Sort(result.onlyA)
+	slices.Sort(result.onlyB)
+	slices.SortFunc(result.changed, func(x, y diffChange) int {
+		return strings.Compare(x.name, y.name)
+	})
+	return result
+}
+
+// changedFields returns the names of the fields whose values differ.
+func changedFields(a, b *diffEntry) []string {
+	var custom []string
+	for key := range a.values {
+		if strings.HasPrefix(key, "field_") {
+			custom = append(custom, key)
+		}
+	}
+	for key := range b.values {
+		if _, ok := a.values[key]; !ok && strings.HasPrefix(key, "field_") {
+			custom = append(custom, key)
+		}
+	}
+	slices.Sort(custom)
+
+	var fields []string
+	for _, field := range slices.Concat(diffFields, custom) {
+		if field == "password" && a.writeOnly != b.writeOnly {
+			// Only one side holds a digest, so the values are incomparable
+			fields = append(fields, field)
+			continue
+		}
+		if a.values[field] != b.values[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// loadExportForDiff reads an export with the import parsers and decrypts its
+// passwords, notes and custom fields. The passwords of write-only entries are
+// left encrypted.
+func loadExportForDiff(app *app.App, filename string, decrypt bool) (map[string]*diffEntry, error) {
+	format, err := detectImportFormat(filename)
+	if err != nil {
+		return nil, errs.InvalidInput("failed to detect format of %s: %w", filename, err)
+	}
+
+	var data *ExportData
+	switch format {
+	case "json":
+		data, err = importJSON(filename)
+	case "csv":
+		data, _, err = importCSV(filename, false)
+	}
+	if err != nil {
+		return nil, errs.InvalidInput("failed to read %s: %w", filename, err)
+	}
+
+	var sourceKey []byte
+	if data.Encrypted {
+		if app.IsLocked() {
+			return nil, errLocked
+		}
+		sourceKey, err = resolveSourceKey(app, data, decrypt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make(map[string]*diffEntry, len(data.Entries))
+	for _, entry := range data.Entries {
+		if data.Encrypted {
+			decrypted, err := decryptExportEntry(app, entry, sourceKey)
+			if err != nil {
+				return nil, errs.Internal("failed to decrypt entry %s in %s: %w", entry.Name, filename, err)
+			}
+			entry = decrypted
+		}
+		password := string(entry.Password)
+		if entry.Type == string(storage.EntryTypeWriteOnly) {
+			password = writeOnlyPassword(entry.Password)
+		}
+		entries[entry.Name] = newDiffEntry(entry.Username, password, entry.URL, entry.Notes, entry.Tags, entry.Type, entry.Folder, entry.Fields)
+	}
+	return entries, nil
+}
+
+// decryptExportEntry returns a copy of an encrypted export entry with its
+// password, notes and custom fields decrypted, with sourceKey if set and the
+// vault's key otherwise. The password of a write-only entry stays encrypted.
+func decryptExportEntry(app *app.App, entry *ExportEntry, sourceKey []byte) (*ExportEntry, error) {
+	decryptValue := func(data []byte) ([]byte, error) {
+		if sourceKey == nil {
+			password, err := app.DecryptPassword(data)
+			return []byte(password), err
+		}
+		return app.Encryption.Decrypt(data, sourceKey)
+	}
+
+	decrypted := *entry
+
+	if entry.Type != string(storage.EntryTypeWriteOnly) {
+		password, err := decryptValue(entry.Password)
+		if err != nil {
+			return nil, err
+		}
+		decrypted.Password = password
+	}
+
+	if len(entry.EncryptedNotes) > 0 {
+		notes, err := decryptValue(entry.EncryptedNotes)
+		if err != nil {
+			return nil, err
+		}
+		decrypted.Notes = string(notes)
+	}
+
+	if len(entry.EncryptedFields) > 0 {
+		plain, err := decryptValue(entry.EncryptedFields)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(plain, &decrypted.Fields); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal custom fields: %w", err)
+		}
+	}
+
+	return &decrypted, nil
+}
+
+// loadVaultForDiff decrypts the entries of the current vault, except for the
+// passwords of write-only entries.
+func loadVaultForDiff(app *app.App) (map[string]*diffEntry, error) {
+	stored, err := app.Storage.ListEntries()
+	if err != nil {
+		return nil, storageError("failed to list entries", err)
+	}
+
+	entries := make(map[string]*diffEntry, len(stored))
+	for _, entry := range stored {
+		password := writeOnlyPassword(entry.Password)
+		if !entry.IsWriteOnly() {
+			password, err = app.DecryptPassword(entry.Password)
+			if err != nil {
+				return nil, errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
+			}
+		}
+		fields, err := app.DecryptFields(entry.CustomFields)
+		if err != nil {
+			return nil, errs.Internal("failed to decrypt custom fields for entry %s: %w", entry.Name, err)
+		}
+		notes, err := app.EntryNotes(entry)
+		if err != nil {
+			return nil, errs.Internal("failed to decrypt notes for entry %s: %w", entry.Name, err)
+		}
+		entries[entry.Name] = newDiffEntry(entry.Username, password, entry.URL, notes, entry.Tags, string(entry.Type), entry.Folder, fields)
+	}
+	return entries, nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// diffTestVault returns a vault with a login and a write-only entry, and an
+// encrypted export of it taken before both passwords were changed.
+func diffTestVault(t *testing.T) (*app.App, string) {
+	t.Helper()
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+	writeOnly := addTestEntry(t, a, "api", "old-token")
+	writeOnly.Type = storage.EntryTypeWriteOnly
+	if err := a.Storage.UpdateEntry(writeOnly); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	if _, err := runCommand(t, a, "export", "--output", path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	output, err := runCommand(t, a, "diff", "--vault", path)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !strings.Contains(output, "No differences") {
+		t.Fatalf("diff of a fresh export:\n%s", output)
+	}
+
+	for name, password := range map[string]string{"github": "hunter3", "api": "new-token"} {
+		entry, err := a.Storage.GetEntry(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Password, err = a.EncryptPassword(password); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a, path
+}
+
+func TestDiffNeverPrintsWriteOnlyPasswords(t *testing.T) {
+	a, path := diffTestVault(t)
+
+	stubPassword(t, testMasterPassword)
+	output, err := runCommand(t, a, "diff", "--vault", path, "--show")
+	if err != nil {
+		t.Fatalf("diff --show: %v", err)
+	}
+
+	for _, want := range []string{"- api: password", "password: (write-only)", `"hunter3" -> "hunter2"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	for _, secret := range []string{"old-token", "new-token"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("output contains the write-only password %q:\n%s", secret, output)
+		}
+	}
+}
+
+func TestDiffShowRequiresMasterPassword(t *testing.T) {
+	a, path := diffTestVault(t)
+	a.Config.RequireMasterPassword = true
+	a.Config.AccessLog = true
+
+	stubPassword(t, "wrong password")
+	output, err := runCommand(t, a, "diff", "--vault", path, "--show")
+	if errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Fatalf("diff --show with a wrong master password: err = %v, want invalid input", err)
+	}
+	if strings.Contains(output, "hunter") {
+		t.Errorf("diff printed values without the master password:\n%s", output)
+	}
+
+	stubPassword(t, testMasterPassword)
+	if _, err := runCommand(t, a, "diff", "--vault", path, "--show"); err != nil {
+		t.Fatalf("diff --show: %v", err)
+	}
+
+	records, err := a.Storage.ListAccessLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var revealed []string
+	for _, record := range records {
+		if record.Action == storage.AccessReveal {
+			revealed = append(revealed, record.EntryName)
+		}
+	}
+	slices.Sort(revealed)
+	if !slices.Equal(revealed, []string{"api", "github"}) {
+		t.Fatalf("revealed entries = %v, want api and github", revealed)
+	}
+}
+
+func TestDiffWithoutShowIsNotLogged(t *testing.T) {
+	a, path := diffTestVault(t)
+	a.Config.RequireMasterPassword = true
+	a.Config.AccessLog = true
+
+	stubPassword(t, "never asked for")
+	output, err := runCommand(t, a, "diff", "--vault", path)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !strings.Contains(output, "- github: password") {
+		t.Errorf("diff output:\n%s", output)
+	}
+
+	records, err := a.Storage.ListAccessLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("access log = %v, want nothing without --show", records)
+	}
+}
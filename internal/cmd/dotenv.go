@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newDotenvCmd(app *app.App) *cobra.Command {
+	var (
+		outputFile string
+		keyTmpl    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dotenv <tag-or-filter>",
+		Short: "Write matching entries to a dotenv file",
+		Long: `Write KEY=value lines for entries tagged with, or whose name contains, the
+given filter to a dotenv file readable only by the current user. Every
+written password is recorded as a reveal in the access log.
+
+By default the key is the entry name converted into an environment variable
+name. Use --template to build keys from entry fields, for example:
+
+  pm dotenv work --template '{{.Name}}_PASSWORD'
+
+Available template fields: .Name, .Username, .URL`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			var tmpl *template.Template
+			if keyTmpl != "" {
+				var err error
+				tmpl, err = template.New("key").Parse(keyTmpl)
+				if err != nil {
+					return errs.InvalidInput("invalid key template: %w", err)
+				}
+			}
+
+			entries, err := matchEntries(app, args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 {
+				return errs.NotFound("no entries match %s", args[0])
+			}
+
+			if err := verifyMasterPassword(app); err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			for _, entry := range entries {
+				key, err := dotenvKey(entry, tmpl)
+				if err != nil {
+					return errs.InvalidInput("failed to build key for entry %s: %w", entry.Name, err)
+				}
+
+				password, err := app.DecryptPassword(entry.Password)
+				if err != nil {
+					return errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
+				}
+				if err := logAccess(app, entry.Name, storage.AccessReveal); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(&buf, "%s=%s\n", key, dotenvQuote(password))
+			}
+
+			if err := writeFileRestricted(outputFile, buf.Bytes()); err != nil {
+				return errs.Internal("failed to write dotenv file: %w", err)
+			}
+
+			fmt.Printf("Successfully wrote %d entries to %s\n", len(entries), outputFile)
+			return nil
+		},
+	}
+
+	// Add flags
+	cmd.Flags().StringVarP(&outputFile, "output", "o", ".env", "Output file path")
+	cmd.Flags().StringVar(&keyTmpl, "template", "", "Template used to build each key from entry fields")
+
+	return cmd
+}
+
+func dotenvKey(entry *storage.Entry, tmpl *template.Template) (string, error) {
+	if tmpl == nil {
+		return envName(entry.Name), nil
+	}
+
+	var key strings.Builder
+	if err := tmpl.Execute(&key, entry); err != nil {
+		return "", err
+	}
+
+	return envName(key.String()), nil
+}
+
+// dotenvQuote double-quotes values that dotenv parsers would otherwise
+// split or interpret.
+func dotenvQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\r\"'#$\\=`") {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// writeFileRestricted writes data to path, making sure the file is only
+// readable and writable by the current user even if it already existed.
+func writeFileRestricted(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Chmod(0600); err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	return err
+}
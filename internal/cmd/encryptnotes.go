@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newEncryptNotesCmd(app *app.App) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "encrypt-notes [name...]",
+		Short: "Encrypt the plain text notes of entries",
+		Long: `Move the plain text notes of the named entries, or of every entry with --all,
+into encrypted storage. All entries are updated in a single transaction, and
+entries whose notes are already encrypted are left alone, so the command can
+safely be run again.
+
+With --all the encrypt_notes setting is also turned on, so that the notes of
+new and updated entries are encrypted as well.
+
+Afterwards the search index is rebuilt and the database compacted, so no copy
+of the plain text notes is left behind. Encrypted notes are not searched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if all == (len(args) > 0) {
+				return errs.InvalidInput("name the entries whose notes to encrypt, or use --all")
+			}
+
+			var entries []*storage.Entry
+			if all {
+				var err error
+				entries, err = app.Storage.ListEntries()
+				if err != nil {
+					return storageError("failed to list entries", err)
+				}
+			} else {
+				for _, name := range args {
+					entry, err := app.Storage.GetEntry(name)
+					if err != nil {
+						return storageError("failed to get entry", err)
+					}
+					entries = append(entries, entry)
+				}
+			}
+
+			moved, err := app.EncryptNotes(entries)
+			if err != nil {
+				return storageError("failed to encrypt notes", err)
+			}
+
+			if moved > 0 {
+				// Drop the plain text from the search index and from free pages
+				if err := app.Storage.Reindex(); err != nil {
+					return storageError("failed to rebuild indexes", err)
+				}
+				if _, _, err := app.Storage.Compact(); err != nil {
+					return errs.Internal("failed to compact database: %w", err)
+				}
+			}
+
+			if all && !app.Config.EncryptNotes {
+				if err := app.Config.SetConfigValue("encrypt_notes", true); err != nil {
+					return errs.Internal("failed to update configuration: %w", err)
+				}
+			}
+
+			fmt.Printf("Encrypted the notes of %d entries\n", moved)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Encrypt the notes of every entry and of entries added later")
+
+	return cmd
+}
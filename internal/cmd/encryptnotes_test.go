@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestEncryptNotes(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	for name, notes := range map[string]string{"github": "recovery codes", "gitlab": "backup codes", "bank": ""} {
+		entry := addTestEntry(t, a, name, "hunter2")
+		entry.Notes = notes
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := searchResults(t, a, "codes"); !slices.Equal(got, []string{"github", "gitlab"}) {
+		t.Fatalf("search codes before encrypting = %v", got)
+	}
+
+	tests := []struct {
+		args      []string
+		wantMoved string
+	}{
+		{[]string{"github"}, "Encrypted the notes of 1 entries"},
+		{[]string{"--all"}, "Encrypted the notes of 1 entries"},
+		// Already encrypted notes are left alone
+		{[]string{"--all"}, "Encrypted the notes of 0 entries"},
+	}
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"encrypt-notes"}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("encrypt-notes %v: %v", tt.args, err)
+		}
+		if !strings.Contains(output, tt.wantMoved) {
+			t.Errorf("encrypt-notes %v printed %q, want %q", tt.args, output, tt.wantMoved)
+		}
+	}
+
+	for _, name := range []string{"github", "gitlab"} {
+		entry, err := a.Storage.GetEntry(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Notes != "" || len(entry.SecureNotes) == 0 {
+			t.Errorf("notes of %s are still in plain text: %q", name, entry.Notes)
+		}
+	}
+	if !a.Config.EncryptNotes {
+		t.Error("encrypt-notes --all did not turn on encrypt_notes")
+	}
+
+	// Encrypted notes are shown, but no longer searched
+	output, err := runCommand(t, a, "get", "github", "--show-notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Notes: recovery codes") {
+		t.Errorf("get --show-notes printed %q", output)
+	}
+	if got := searchResults(t, a, "codes"); len(got) != 0 {
+		t.Errorf("search codes after encrypting = %v, want nothing", got)
+	}
+
+	// Notes of new entries are encrypted too
+	if _, err := runCommand(t, a, "add", "email", "--password", "hunter2", "--notes", "pin 1234"); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := a.Storage.GetEntry("email"); err != nil || entry.Notes != "" || len(entry.SecureNotes) == 0 {
+		t.Errorf("notes of a new entry were not encrypted: %+v, %v", entry, err)
+	}
+
+	if _, err := runCommand(t, a, "encrypt-notes"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("encrypt-notes without names: err = %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newEnvCmd(app *app.App) *cobra.Command {
+	var prefix string
+
+	cmd := &cobra.Command{
+		Use:   "env <tag-or-filter>",
+		Short: "Print export statements for matching entries",
+		Long: `Print shell export statements for entries tagged with, or whose name contains,
+the given filter. The output is meant to be evaluated by the shell:
+
+  eval "$(pm env work)"
+
+Entry names are converted into valid environment variable names.
+Every printed password is recorded as a reveal in the access log.
+WARNING: secrets loaded into the environment are visible to child processes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			entries, err := matchEntries(app, args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 {
+				return errs.NotFound("no entries match %s", args[0])
+			}
+
+			if err := verifyMasterPassword(app); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(os.Stderr, "Warning: exported secrets are visible to every process started from this shell")
+
+			for _, entry := range entries {
+				password, err := app.DecryptPassword(entry.Password)
+				if err != nil {
+					return errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
+				}
+				if err := logAccess(app, entry.Name, storage.AccessReveal); err != nil {
+					return err
+				}
+
+				fmt.Printf("export %s=%s\n", envName(prefix+entry.Name), shellQuote(password))
+			}
+
+			return nil
+		},
+	}
+
+	// Add flags
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Prefix added to every variable name")
+
+	return cmd
+}
+
+// matchEntries returns the revealable entries tagged with filter or whose
+// name contains it, ignoring case.
+func matchEntries(app *app.App, filter string) ([]*storage.Entry, error) {
+	entries, err := app.Storage.ListEntries()
+	if err != nil {
+		return nil, storageError("failed to list entries", err)
+	}
+
+	filterLower := strings.ToLower(filter)
+	matched := make([]*storage.Entry, 0)
+
+	for _, entry := range entries {
+		if entry.IsWriteOnly() {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(entry.Name), filterLower) || hasTag(entry.Tags, filterLower) {
+			matched = append(matched, entry)
+		}
+	}
+
+	return matched, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.ToLower(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// envName converts name into a valid environment variable identifier:
+// upper case letters, digits and underscores, not starting with a digit.
+func envName(name string) string {
+	var b strings.Builder
+	lastUnderscore := false
+
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			b.WriteByte('_')
+			lastUnderscore = true
+		}
+	}
+
+	result := strings.Trim(b.String(), "_")
+	if result == "" || (result[0] >= '0' && result[0] <= '9') {
+		result = "_" + result
+	}
+
+	return result
+}
+
+// shellQuote quotes s for safe use in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestEnvCommandsRequireMasterPassword(t *testing.T) {
+	for _, command := range []string{"env", "dotenv"} {
+		t.Run(command, func(t *testing.T) {
+			a := newTestApp(t)
+			a.Config.RequireMasterPassword = true
+			a.Config.AccessLog = true
+			addTestEntry(t, a, "work-github", "hunter2")
+			addTestEntry(t, a, "work-gitlab", "hunter3")
+
+			args := []string{command, "work"}
+			if command == "dotenv" {
+				args = append(args, "--output", filepath.Join(t.TempDir(), ".env"))
+			}
+
+			stubPassword(t, "wrong password")
+			if _, err := runCommand(t, a, args...); errs.ExitCode(err) != errs.ExitInvalidInput {
+				t.Fatalf("%s with a wrong master password: err = %v, want invalid input", command, err)
+			}
+
+			// A single prompt covers every matching entry
+			stubPasswords(t, testMasterPassword)
+			if _, err := runCommand(t, a, args...); err != nil {
+				t.Fatalf("%s: %v", command, err)
+			}
+
+			records, err := a.Storage.ListAccessLog()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("access log = %v, want a reveal per entry", records)
+			}
+			for _, record := range records {
+				if record.Action != storage.AccessReveal {
+					t.Errorf("access log records %s for %s, want reveal", record.Action, record.EntryName)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"github", "GITHUB"},
+		{"work-github", "WORK_GITHUB"},
+		{"my.api key", "MY_API_KEY"},
+		{"--db--", "DB"},
+		{"a//b", "A_B"},
+		{"1password", "_1PASSWORD"},
+		{"ünïcode", "N_CODE"},
+		{"---", "_"},
+	}
+
+	for _, tt := range tests {
+		if got := envName(tt.name); got != tt.want {
+			t.Errorf("envName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"hunter2", `'hunter2'`},
+		{"", `''`},
+		{"it's", `'it'\''s'`},
+		{"$(rm -rf /)", `'$(rm -rf /)'`},
+		{"a b\tc", "'a b\tc'"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.value); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "work-github", "hunter2")
+	addTestEntry(t, a, "personal-mail", "it's secret")
+	tagged := addTestEntry(t, a, "db", "p@ss")
+	tagged.Tags = []string{"work"}
+	if err := a.Storage.UpdateEntry(tagged); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"work"}, []string{"export DB='p@ss'", "export WORK_GITHUB='hunter2'"}},
+		{[]string{"mail"}, []string{`export PERSONAL_MAIL='it'\''s secret'`}},
+		{[]string{"WORK", "--prefix", "app_"}, []string{"export APP_DB='p@ss'", "export APP_WORK_GITHUB='hunter2'"}},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"env"}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("env %v: %v", tt.args, err)
+		}
+		got := strings.Split(strings.TrimSpace(output), "\n")
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("env %v printed %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := runCommand(t, a, "env", "nothing"); errs.ExitCode(err) != errs.ExitNotFound {
+		t.Errorf("env without matches: err = %v, want not found", err)
+	}
+}
+
+func TestDotenvQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"hunter2", "hunter2"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME", `"\$HOME"`},
+		{`back\slash`, `"back\\slash"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"a=b#c", `"a=b#c"`},
+	}
+
+	for _, tt := range tests {
+		if got := dotenvQuote(tt.value); got != tt.want {
+			t.Errorf("dotenvQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDotenv(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	github := addTestEntry(t, a, "work-github", "hunter2")
+	github.Username = "alice"
+	if err := a.Storage.UpdateEntry(github); err != nil {
+		t.Fatal(err)
+	}
+	addTestEntry(t, a, "work-db", "p@ss word")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "WORK_DB=\"p@ss word\"\nWORK_GITHUB=hunter2\n"},
+		{[]string{"--template", "{{.Name}}_PASSWORD"}, "WORK_DB_PASSWORD=\"p@ss word\"\nWORK_GITHUB_PASSWORD=hunter2\n"},
+		{[]string{"--template", "{{.Username}}"}, "USER=\"p@ss word\"\nALICE=hunter2\n"},
+	}
+
+	for _, tt := range tests {
+		// An existing file readable by others is restricted when overwritten
+		path := filepath.Join(t.TempDir(), ".env")
+		if err := os.WriteFile(path, []byte("OLD=value\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := runCommand(t, a, append([]string{"dotenv", "work", "--output", path}, tt.args...)...); err != nil {
+			t.Fatalf("dotenv %v: %v", tt.args, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("dotenv %v wrote %q, want %q", tt.args, data, tt.want)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("dotenv %v wrote a file with mode %o, want 600", tt.args, perm)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), ".env")
+	if _, err := runCommand(t, a, "dotenv", "work", "--output", output, "--template", "{{.Missing}}"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("dotenv with a template naming an unknown field: err = %v, want invalid input", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("dotenv wrote %s despite failing", output)
+	}
+}
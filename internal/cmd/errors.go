@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// errLocked is returned by commands that require passio to be unlocked.
+var errLocked = errs.Locked("password manager is locked. Please unlock first")
+
+// storageError wraps a storage error with msg and classifies it by kind.
+func storageError(msg string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrEntryNotFound):
+		return errs.NotFound("%s: %w", msg, err)
+	case errors.Is(err, storage.ErrEntryExists),
+		errors.Is(err, storage.ErrEntryAmbiguous):
+		return errs.Conflict("%s: %w", msg, err)
+	case errors.Is(err, storage.ErrInvalidEntry),
+		errors.Is(err, storage.ErrEntryNameIsReq),
+		errors.Is(err, storage.ErrEntryPasswordIsReq),
+		errors.Is(err, storage.ErrInvalidOperation),
+		errors.Is(err, storage.ErrEntryTooLarge),
+		errors.Is(err, storage.ErrCorrupt):
+		return errs.InvalidInput("%s: %w", msg, err)
+	default:
+		return errs.Internal("%s: %w", msg, err)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestStorageErrorExitCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{storage.ErrEntryNotFound, errs.ExitNotFound},
+		{storage.ErrEntryExists, errs.ExitConflict},
+		{storage.ErrEntryAmbiguous, errs.ExitConflict},
+		{storage.ErrEntryNameIsReq, errs.ExitInvalidInput},
+		{storage.ErrEntryTooLarge, errs.ExitInvalidInput},
+		{storage.ErrCorrupt, errs.ExitInvalidInput},
+		{fmt.Errorf("disk I/O error"), errs.ExitInternal},
+		{fmt.Errorf("lookup: %w", storage.ErrEntryNotFound), errs.ExitNotFound},
+	}
+	for _, test := range tests {
+		if got := errs.ExitCode(storageError("failed", test.err)); got != test.want {
+			t.Errorf("exit code for %v = %d, want %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"get", "missing"}, errs.ExitNotFound},
+		{[]string{"get", "github", "--format", "xml"}, errs.ExitInvalidInput},
+		{[]string{"delete", "missing", "--force"}, errs.ExitNotFound},
+	}
+	for _, test := range tests {
+		_, err := runCommand(t, a, test.args...)
+		if got := errs.ExitCode(err); got != test.want {
+			t.Errorf("pm %v exit code = %d (%v), want %d", test.args, got, err, test.want)
+		}
+	}
+
+	a.Lock()
+	_, err := runCommand(t, a, "get", "github")
+	if got := errs.ExitCode(err); got != errs.ExitLocked {
+		t.Errorf("exit code when locked = %d (%v), want %d", got, err, errs.ExitLocked)
+	}
+}
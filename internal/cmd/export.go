@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -17,40 +21,121 @@ type ExportData struct {
 	ExportDate time.Time      `json:"export_date"`
 	Entries    []*ExportEntry `json:"entries"`
 	Encrypted  bool           `json:"encrypted"`
+	Salt       []byte         `json:"salt,omitempty"` // Key derivation salt of the source vault
+	// KDF is the key derivation function of the source vault. Exports without
+	// it were made by vaults using PBKDF2.
+	KDF *crypto.KDFParams `json:"kdf,omitempty"`
 }
 
 type ExportEntry struct {
-	Name      string    `json:"name"`
-	Username  string    `json:"username"`
-	Password  []byte    `json:"password"`
-	URL       string    `json:"url"`
-	Notes     string    `json:"notes"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Username string   `json:"username"`
+	Password []byte   `json:"password"`
+	URL      string   `json:"url"`
+	Notes    string   `json:"notes"`
+	Tags     []string `json:"tags"`
+	Type     string   `json:"type,omitempty"`
+	Folder   string   `json:"folder,omitempty"`
+
+	// Custom fields are exported decrypted or as an encrypted JSON object
+	Fields          map[string]string `json:"fields,omitempty"`
+	EncryptedFields []byte            `json:"encrypted_fields,omitempty"`
+	EncryptedNotes  []byte            `json:"encrypted_notes,omitempty"` // Notes kept encrypted in the vault
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	// Zero in exports made before it was recorded, when imports fall back
+	// to the creation time
+	PasswordChangedAt time.Time  `json:"password_changed_at"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	DeleteAt          *time.Time `json:"delete_at,omitempty"` // TTL set with 'pm add --ttl'
 }
 
 func newExportCmd(app *app.App) *cobra.Command {
 	var (
-		outputFile string
-		decrypt    bool
-		format     string
+		outputFile    string
+		decrypt       bool
+		format        string
+		stripMetadata bool
+		columnSpec    string
+		split         int
+		toStdout      bool
+		yes           bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "export",
 		Short: "Export password entries",
 		Long: `Export password entries to a file in JSON or CSV format.
-Passwords can be exported in encrypted or decrypted form.`,
+Passwords can be exported in encrypted or decrypted form. A decrypted export
+must be confirmed, unless --yes is given, and is never written into a
+world-writable directory.
+Without --output, the export is written to the export_dir setting, which
+defaults to the current directory.
+
+Use --columns to choose and order the CSV columns, e.g. --columns "Name,Username,URL".
+Available columns: ` + strings.Join(csvColumnNames, ", ") + `.
+CSV exports start with a # comment line recording the passio version and the
+export date, which 'pm import' skips. 'pm import' reads the columns back by
+their header names, so any selection that includes Name can be imported.
+
+Use --split N to write the entries to several files of at most N entries each,
+e.g. pm_export_part1.json, pm_export_part2.json. Each part can be imported on
+its own, and a manifest listing the parts is written next to them.
+
+Use --stdout or --output - to write the export to standard output instead of a
+file, e.g. to pipe a decrypted export straight into an encryption tool without
+it touching the disk. Messages are then written to standard error.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
+			}
+
+			columns := csvColumnNames
+			if columnSpec != "" {
+				if format != "csv" {
+					return errs.InvalidInput("--columns can only be used with the csv format")
+				}
+
+				var err error
+				columns, err = parseCSVColumns(columnSpec)
+				if err != nil {
+					return err
+				}
+			}
+
+			if split < 0 {
+				return errs.InvalidInput("--split must be a positive number of entries")
+			}
+
+			if toStdout {
+				outputFile = "-"
+			}
+			if outputFile == "-" && split > 0 {
+				return errs.InvalidInput("--split cannot be used when exporting to standard output")
+			}
+
+			// Keep standard output clean for the export itself
+			messages := os.Stdout
+			if outputFile == "-" {
+				messages = os.Stderr
+			}
+
+			if decrypt {
+				if !yes && !confirmDecryptedExport(messages) {
+					fmt.Fprintln(messages, "Export cancelled")
+					return nil
+				}
+
+				if err := verifyMasterPassword(app); err != nil {
+					return err
+				}
 			}
 
 			// Get all entries
 			entries, err := app.Storage.ListEntries()
 			if err != nil {
-				return fmt.Errorf("failed to list entries: %w", err)
+				return storageError("failed to list entries", err)
 			}
 
 			// Prepare export data
@@ -60,28 +145,65 @@ Passwords can be exported in encrypted or decrypted form.`,
 				Encrypted:  !decrypt,
 				Entries:    make([]*ExportEntry, 0, len(entries)),
 			}
+			if !decrypt {
+				exportData.Salt = app.Config.Salt
+				kdf := app.Config.KDFParams().Normalized()
+				exportData.KDF = &kdf
+			}
 
 			// Process entries
+			var writeOnlySkipped int
 			for _, entry := range entries {
+				// Write-only secrets are never written out in plain text
+				if decrypt && entry.IsWriteOnly() {
+					writeOnlySkipped++
+					continue
+				}
+
 				exportEntry := &ExportEntry{
 					Name:      entry.Name,
+					Aliases:   entry.Aliases,
 					Username:  entry.Username,
 					URL:       entry.URL,
-					Notes:     entry.Notes,
 					Tags:      entry.Tags,
-					CreatedAt: entry.CreatedAt,
-					UpdatedAt: entry.UpdatedAt,
+					Type:      string(entry.Type),
+					Folder:    entry.Folder,
+					ExpiresAt: entry.ExpiresAt,
+					DeleteAt:  entry.DeleteAt,
+				}
+
+				// Notes and timestamps are left empty when stripping metadata
+				if !stripMetadata {
+					exportEntry.Notes = entry.Notes
+					if decrypt {
+						notes, err := app.EntryNotes(entry)
+						if err != nil {
+							return errs.Internal("failed to decrypt notes for entry %s: %w", entry.Name, err)
+						}
+						exportEntry.Notes = notes
+					} else {
+						exportEntry.EncryptedNotes = entry.SecureNotes
+					}
+					exportEntry.CreatedAt = entry.CreatedAt
+					exportEntry.UpdatedAt = entry.UpdatedAt
+					exportEntry.PasswordChangedAt = entry.PasswordChangedAt
 				}
 
 				if decrypt {
 					// Decrypt password if requested
 					password, err := app.DecryptPassword(entry.Password)
 					if err != nil {
-						return fmt.Errorf("failed to decrypt password for entry %s: %w", entry.Name, err)
+						return errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
 					}
 					exportEntry.Password = []byte(password)
+
+					exportEntry.Fields, err = app.DecryptFields(entry.CustomFields)
+					if err != nil {
+						return errs.Internal("failed to decrypt custom fields for entry %s: %w", entry.Name, err)
+					}
 				} else {
 					exportEntry.Password = entry.Password
+					exportEntry.EncryptedFields = entry.CustomFields
 				}
 
 				exportData.Entries = append(exportData.Entries, exportEntry)
@@ -89,30 +211,57 @@ Passwords can be exported in encrypted or decrypted form.`,
 
 			// Create output directory if it doesn't exist
 			if outputFile == "" {
-				outputFile = fmt.Sprintf("pm_export_%s.%s",
-					time.Now().Format("20060102_150405"), format)
+				outputFile = filepath.Join(app.Config.ExportDirectory(), fmt.Sprintf("pm_export_%s.%s",
+					time.Now().Format("20060102_150405"), format))
 			}
-			if err := os.MkdirAll(filepath.Dir(outputFile), 0700); err != nil {
-				return fmt.Errorf("failed to create output directory: %w", err)
+			if outputFile != "-" {
+				if err := os.MkdirAll(filepath.Dir(outputFile), 0700); err != nil {
+					return errs.Internal("failed to create output directory: %w", err)
+				}
+
+				if decrypt {
+					if err := checkNotWorldWritable(filepath.Dir(outputFile)); err != nil {
+						return err
+					}
+				}
 			}
 
-			// Export based on format
+			var write func(filename string, data *ExportData) error
 			switch format {
 			case "json":
-				if err := exportJSON(outputFile, exportData); err != nil {
-					return err
-				}
+				write = exportJSON
 			case "csv":
-				if err := exportCSV(outputFile, exportData); err != nil {
-					return err
+				write = func(filename string, data *ExportData) error {
+					return exportCSV(filename, data, columns)
 				}
 			default:
-				return fmt.Errorf("unsupported format: %s", format)
+				return errs.InvalidInput("unsupported format: %s", format)
 			}
 
-			fmt.Printf("Successfully exported %d entries to %s\n", len(entries), outputFile)
+			// Export based on format, optionally split into parts
+			if split > 0 {
+				manifest, manifestFile, err := exportSplit(outputFile, exportData, split, write)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(messages, "Successfully exported %d entries to %d parts, listed in %s\n",
+					len(exportData.Entries), len(manifest.Parts), manifestFile)
+			} else {
+				if err := write(outputFile, exportData); err != nil {
+					return err
+				}
+
+				destination := outputFile
+				if outputFile == "-" {
+					destination = "standard output"
+				}
+				fmt.Fprintf(messages, "Successfully exported %d entries to %s\n", len(exportData.Entries), destination)
+			}
+			if writeOnlySkipped > 0 {
+				fmt.Fprintf(messages, "Skipped %d write-only entries which cannot be exported decrypted\n", writeOnlySkipped)
+			}
 			if !decrypt {
-				fmt.Println("Passwords were exported in encrypted form")
+				fmt.Fprintln(messages, "Passwords were exported in encrypted form")
 			}
 
 			return nil
@@ -120,17 +269,68 @@ Passwords can be exported in encrypted or decrypted form.`,
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path, or - for standard output")
+	cmd.Flags().BoolVar(&toStdout, "stdout", false, "Write the export to standard output")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation before exporting decrypted passwords")
 	cmd.Flags().BoolVarP(&decrypt, "decrypt", "d", false, "Export decrypted passwords (warning: sensitive!)")
 	cmd.Flags().StringVarP(&format, "format", "f", "json", "Export format (json or csv)")
+	cmd.Flags().BoolVar(&stripMetadata, "strip-metadata", false, "Omit notes and timestamps from exported entries")
+	cmd.Flags().StringVar(&columnSpec, "columns", "", "Comma-separated CSV columns to export, in order")
+	cmd.Flags().IntVar(&split, "split", 0, "Split the export into files of at most this many entries")
 
 	return cmd
 }
 
-func exportJSON(filename string, data *ExportData) error {
+// confirmDecryptedExport warns that passwords are about to be written in
+// plain text and asks the user to confirm, printing to w.
+func confirmDecryptedExport(w io.Writer) bool {
+	fmt.Fprintln(w, "WARNING: The export will contain your passwords in plain text.")
+	fmt.Fprintln(w, "Anyone who can read it has access to every exported account.")
+	fmt.Fprint(w, "Export decrypted passwords? [y/N]: ")
+
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
+
+// checkNotWorldWritable refuses directories that any user can write to, where
+// others could replace or tamper with a decrypted export.
+func checkNotWorldWritable(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return errs.Internal("failed to check output directory: %w", err)
+	}
+	if info.Mode().Perm()&0002 != 0 {
+		return errs.InvalidInput("refusing to write decrypted passwords into world-writable directory %s", dir)
+	}
+	return nil
+}
+
+// createExportFile creates filename for writing an export, readable only by
+// the current user. The filename - stands for standard output.
+func createExportFile(filename string) (io.WriteCloser, error) {
+	if filename == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+
 	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to create export file: %w", err)
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	return file, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func exportJSON(filename string, data *ExportData) error {
+	file, err := createExportFile(filename)
+	if err != nil {
+		return err
 	}
 	defer file.Close()
 
@@ -143,32 +343,141 @@ func exportJSON(filename string, data *ExportData) error {
 	return nil
 }
 
-func exportCSV(filename string, data *ExportData) error {
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+// ExportManifest lists the parts of a split export so they can be reassembled.
+type ExportManifest struct {
+	Version    string                `json:"version"`
+	ExportDate time.Time             `json:"export_date"`
+	Entries    int                   `json:"entries"`
+	Parts      []*ExportManifestPart `json:"parts"`
+}
+
+type ExportManifestPart struct {
+	File    string `json:"file"`
+	Entries int    `json:"entries"`
+	SHA256  string `json:"sha256"`
+}
+
+// exportSplit writes data in parts of at most size entries using write, named
+// after outputFile with a _partN suffix, followed by a manifest of the parts.
+// Each part carries the export metadata so it can be imported on its own.
+func exportSplit(outputFile string, data *ExportData, size int, write func(string, *ExportData) error) (*ExportManifest, string, error) {
+	ext := filepath.Ext(outputFile)
+	base := strings.TrimSuffix(outputFile, ext)
+
+	manifest := &ExportManifest{
+		Version:    data.Version,
+		ExportDate: data.ExportDate,
+		Entries:    len(data.Entries),
+	}
+
+	for start := 0; start < len(data.Entries) || start == 0; start += size {
+		end := min(start+size, len(data.Entries))
+
+		part := *data
+		part.Entries = data.Entries[start:end]
+
+		filename := fmt.Sprintf("%s_part%d%s", base, len(manifest.Parts)+1, ext)
+		if err := write(filename, &part); err != nil {
+			return nil, "", err
+		}
+
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read export part: %w", err)
+		}
+
+		manifest.Parts = append(manifest.Parts, &ExportManifestPart{
+			File:    filepath.Base(filename),
+			Entries: len(part.Entries),
+			SHA256:  fmt.Sprintf("%x", sha256.Sum256(contents)),
+		})
+	}
+
+	manifestFile := base + "_manifest.json"
+	file, err := os.OpenFile(manifestFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to create export file: %w", err)
+		return nil, "", fmt.Errorf("failed to create export manifest: %w", err)
 	}
 	defer file.Close()
 
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to encode export manifest: %w", err)
+	}
+
+	return manifest, manifestFile, nil
+}
+
+// csvColumnNames are the CSV export columns in their default order
+var csvColumnNames = []string{"Name", "Username", "Password", "URL", "Notes", "Tags", "Created", "Updated"}
+
+// csvColumns formats the value of each CSV column for an entry
+var csvColumns = map[string]func(entry *ExportEntry) string{
+	"Name":     func(e *ExportEntry) string { return escapeCSV(e.Name) },
+	"Username": func(e *ExportEntry) string { return escapeCSV(e.Username) },
+	"Password": func(e *ExportEntry) string { return escapeCSV(string(e.Password)) },
+	"URL":      func(e *ExportEntry) string { return escapeCSV(e.URL) },
+	"Notes":    func(e *ExportEntry) string { return escapeCSV(e.Notes) },
+	"Tags":     func(e *ExportEntry) string { return escapeCSV(joinTags(e.Tags)) },
+	"Created":  func(e *ExportEntry) string { return formatCSVTime(e.CreatedAt) },
+	"Updated":  func(e *ExportEntry) string { return formatCSVTime(e.UpdatedAt) },
+}
+
+// parseCSVColumns parses a comma-separated list of column names, matched
+// case-insensitively, into their canonical names.
+func parseCSVColumns(spec string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(spec, ",") {
+		column, ok := csvColumnName(name)
+		if !ok {
+			return nil, errs.InvalidInput("unknown column: %s (available: %s)", strings.TrimSpace(name), strings.Join(csvColumnNames, ", "))
+		}
+
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// csvColumnName returns the canonical name of a CSV column, matched
+// case-insensitively and ignoring surrounding spaces.
+func csvColumnName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	for _, known := range csvColumnNames {
+		if strings.EqualFold(name, known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+func exportCSV(filename string, data *ExportData, columns []string) error {
+	file, err := createExportFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Record where the file came from; importers skip comment lines
+	comment := fmt.Sprintf("# Exported by passio %s on %s\n", version, data.ExportDate.Format(time.RFC3339))
+	if _, err := io.WriteString(file, comment); err != nil {
+		return fmt.Errorf("failed to write CSV comment: %w", err)
+	}
+
 	// Write CSV header
-	header := "Name,Username,Password,URL,Notes,Tags,Created,Updated\n"
-	if _, err := file.WriteString(header); err != nil {
+	header := strings.Join(columns, ",") + "\n"
+	if _, err := io.WriteString(file, header); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write entries
+	values := make([]string, len(columns))
 	for _, entry := range data.Entries {
-		line := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s\n",
-			escapeCSV(entry.Name),
-			escapeCSV(entry.Username),
-			escapeCSV(string(entry.Password)),
-			escapeCSV(entry.URL),
-			escapeCSV(entry.Notes),
-			escapeCSV(joinTags(entry.Tags)),
-			entry.CreatedAt.Format(time.RFC3339),
-			entry.UpdatedAt.Format(time.RFC3339),
-		)
-		if _, err := file.WriteString(line); err != nil {
+		for i, column := range columns {
+			values[i] = csvColumns[column](entry)
+		}
+		line := strings.Join(values, ",") + "\n"
+		if _, err := io.WriteString(file, line); err != nil {
 			return fmt.Errorf("failed to write CSV line: %w", err)
 		}
 	}
@@ -176,13 +485,22 @@ func exportCSV(filename string, data *ExportData) error {
 	return nil
 }
 
+// escapeCSV quotes s if needed, including values starting with # that would
+// otherwise be read back as a comment line.
 func escapeCSV(s string) string {
-	if strings.ContainsAny(s, ",\"\n") {
+	if strings.ContainsAny(s, ",\"\n") || strings.HasPrefix(strings.TrimSpace(s), "#") {
 		return fmt.Sprintf("\"%s\"", strings.ReplaceAll(s, "\"", "\"\""))
 	}
 	return s
 }
 
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 func joinTags(tags []string) string {
 	return strings.Join(tags, ";")
 }
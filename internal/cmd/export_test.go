@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestExportStripMetadata(t *testing.T) {
+	for _, encryptNotes := range []bool{false, true} {
+		a := newTestApp(t)
+		a.Config.EncryptNotes = encryptNotes
+		entry := addTestEntry(t, a, "github", "hunter2")
+		if err := a.SetEntryNotes(entry, "recovery codes"); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(t.TempDir(), "export.json")
+		if _, err := runCommand(t, a, "export", "--output", path, "--strip-metadata"); err != nil {
+			t.Fatalf("export --strip-metadata: %v", err)
+		}
+
+		data, err := importJSON(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Entries) != 1 {
+			t.Fatalf("exported %d entries, want 1", len(data.Entries))
+		}
+		exported := data.Entries[0]
+		if exported.Notes != "" || len(exported.EncryptedNotes) > 0 {
+			t.Errorf("encrypt_notes %v: notes were exported", encryptNotes)
+		}
+		if !exported.CreatedAt.IsZero() || !exported.UpdatedAt.IsZero() || !exported.PasswordChangedAt.IsZero() {
+			t.Errorf("encrypt_notes %v: timestamps were exported", encryptNotes)
+		}
+		if exported.Name != "github" || exported.Username != "user" || len(exported.Password) == 0 {
+			t.Errorf("encrypt_notes %v: exported entry lost its credentials: %+v", encryptNotes, exported)
+		}
+	}
+}
+
+func TestExportSplit(t *testing.T) {
+	tests := []struct {
+		format string
+		split  int
+		parts  []int
+	}{
+		{"json", 2, []int{2, 2, 1}},
+		{"json", 5, []int{5}},
+		{"json", 10, []int{5}},
+		{"csv", 3, []int{3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s split %d", tt.format, tt.split), func(t *testing.T) {
+			a := newTestApp(t)
+			stubPassword(t, testMasterPassword)
+			var names []string
+			for i := range 5 {
+				name := fmt.Sprintf("entry%d", i)
+				addTestEntry(t, a, name, "hunter2")
+				names = append(names, name)
+			}
+
+			dir := t.TempDir()
+			args := []string{"export", "--output", filepath.Join(dir, "export."+tt.format), "--format", tt.format, "--split", strconv.Itoa(tt.split)}
+			if tt.format == "csv" {
+				args = append(args, "--decrypt", "--yes")
+			}
+			if _, err := runCommand(t, a, args...); err != nil {
+				t.Fatalf("export --split: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "export_manifest.json"))
+			if err != nil {
+				t.Fatalf("reading the manifest: %v", err)
+			}
+			var manifest ExportManifest
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Entries != len(names) || len(manifest.Parts) != len(tt.parts) {
+				t.Fatalf("manifest lists %d entries in %d parts, want %d in %d", manifest.Entries, len(manifest.Parts), len(names), len(tt.parts))
+			}
+
+			// Every part matches its checksum and can be imported on its own
+			target := newTestApp(t)
+			for i, part := range manifest.Parts {
+				if want := fmt.Sprintf("export_part%d.%s", i+1, tt.format); part.File != want || part.Entries != tt.parts[i] {
+					t.Errorf("part %d is %s with %d entries, want %s with %d", i+1, part.File, part.Entries, want, tt.parts[i])
+				}
+				contents, err := os.ReadFile(filepath.Join(dir, part.File))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sum := fmt.Sprintf("%x", sha256.Sum256(contents)); sum != part.SHA256 {
+					t.Errorf("part %s has checksum %s, manifest says %s", part.File, sum, part.SHA256)
+				}
+				if _, err := runCommand(t, target, "import", filepath.Join(dir, part.File)); err != nil {
+					t.Fatalf("importing %s: %v", part.File, err)
+				}
+			}
+			if got := vaultNames(t, target); !slices.Equal(got, names) {
+				t.Errorf("importing every part restored %v, want %v", got, names)
+			}
+		})
+	}
+}
+
+func TestExportToStdout(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	for _, args := range [][]string{
+		{"--stdout"},
+		{"-o", "-"},
+		{"--output", "-", "--decrypt", "--yes"},
+	} {
+		output, err := runCommand(t, a, append([]string{"export"}, args...)...)
+		if err != nil {
+			t.Fatalf("export %v: %v", args, err)
+		}
+
+		// Standard output holds nothing but the export
+		var data ExportData
+		if err := json.Unmarshal([]byte(output), &data); err != nil {
+			t.Fatalf("export %v printed more than the export: %v\n%s", args, err, output)
+		}
+		if len(data.Entries) != 1 || data.Entries[0].Name != "github" {
+			t.Errorf("export %v wrote %d entries, want github", args, len(data.Entries))
+		}
+	}
+
+	output, err := runCommand(t, a, "export", "--stdout", "--format", "csv")
+	if err != nil {
+		t.Fatalf("export --stdout --format csv: %v", err)
+	}
+	if !strings.HasPrefix(output, "# Exported by passio") {
+		t.Errorf("export --stdout --format csv printed:\n%s", output)
+	}
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("export --stdout --format csv printed invalid CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "github" {
+		t.Errorf("export --stdout --format csv printed records %q", records)
+	}
+
+	if _, err := runCommand(t, a, "export", "--stdout", "--split", "10"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("export --stdout --split: err = %v, want invalid input", err)
+	}
+}
+
+func TestConfirmDecryptedExport(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"sure\n", false},
+	}
+
+	for _, tt := range tests {
+		stubStdin(t, tt.input)
+		var prompt strings.Builder
+		if got := confirmDecryptedExport(&prompt); got != tt.want {
+			t.Errorf("confirmDecryptedExport(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(prompt.String(), "plain text") {
+			t.Errorf("confirmDecryptedExport(%q) did not warn: %q", tt.input, prompt.String())
+		}
+	}
+}
+
+func TestExportDecryptedConfirmation(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	dir := t.TempDir()
+
+	// Declining writes nothing
+	declined := filepath.Join(dir, "declined.json")
+	stubStdin(t, "n\n")
+	output, err := runCommand(t, a, "export", "--decrypt", "-o", declined)
+	if err != nil {
+		t.Fatalf("export --decrypt: %v", err)
+	}
+	if !strings.Contains(output, "Export cancelled") {
+		t.Errorf("declined export printed %q", output)
+	}
+	if _, err := os.Stat(declined); !os.IsNotExist(err) {
+		t.Errorf("declined export wrote %s", declined)
+	}
+
+	// Confirming writes the export
+	confirmed := filepath.Join(dir, "confirmed.json")
+	stubStdin(t, "y\n")
+	if _, err := runCommand(t, a, "export", "--decrypt", "-o", confirmed); err != nil {
+		t.Fatalf("export --decrypt: %v", err)
+	}
+	if _, err := os.Stat(confirmed); err != nil {
+		t.Errorf("confirmed export was not written: %v", err)
+	}
+}
+
+func TestExportDecryptedRefusesWorldWritableDirectory(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+
+	shared := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"--decrypt", "--yes"}, true},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(shared, "export.json")
+		args := append([]string{"export", "-o", file}, tt.args...)
+		_, err := runCommand(t, a, args...)
+		if tt.wantErr {
+			if errs.ExitCode(err) != errs.ExitInvalidInput {
+				t.Errorf("%v: err = %v, want invalid input", args, err)
+			}
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("%v wrote %s", args, file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"math/big"
 	"strings"
 
-	"github.com/atotto/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/sethvargo/go-diceware/diceware"
 	"github.com/spf13/cobra"
 )
 
-func newGenerateCmd() *cobra.Command {
+func newGenerateCmd(app *app.App) *cobra.Command {
 	var (
 		length      int
 		special     bool
@@ -19,17 +22,25 @@ func newGenerateCmd() *cobra.Command {
 		lowercase   bool
 		noAmbiguous bool
 		copy        bool
+		clipPrimary bool
 		count       int
+		passphrase  bool
+		words       int
 	)
 
 	cmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generate a random password",
 		Long: `Generate one or more random passwords with specified options.
-By default, generates a single password with all character types enabled.`,
+By default, generates a single password with all character types enabled.
+Use --passphrase to generate diceware passphrases instead.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if passphrase && words < 1 {
+				return errs.InvalidInput("number of words must be positive")
+			}
+
 			if length < 1 {
-				return fmt.Errorf("password length must be positive")
+				return errs.InvalidInput("password length must be positive")
 			}
 
 			if !special && !numbers && !uppercase && !lowercase {
@@ -40,22 +51,36 @@ By default, generates a single password with all character types enabled.`,
 				lowercase = true
 			}
 
+			var first string
 			for i := 0; i < count; i++ {
-				password, err := generatePasswordWithOptions(length, special, numbers, uppercase, lowercase, noAmbiguous)
+				var password string
+				var err error
+				if passphrase {
+					password, err = generatePassphrase(words)
+				} else {
+					password, err = generatePasswordWithOptions(length, special, numbers, uppercase, lowercase, noAmbiguous)
+				}
 				if err != nil {
-					return fmt.Errorf("failed to generate password: %w", err)
+					return errs.Internal("failed to generate password: %w", err)
 				}
 
-				if copy && i == 0 {
-					if err := clipboard.WriteAll(password); err != nil {
-						return fmt.Errorf("failed to copy to clipboard: %w", err)
-					}
-					fmt.Println("Password copied to clipboard")
+				if i == 0 {
+					first = password
 				}
 
 				fmt.Println(password)
 			}
 
+			if copy || clipPrimary {
+				selection := clipboard.SelectionClipboard
+				if clipPrimary {
+					selection = clipboard.SelectionPrimary
+				}
+				if _, err := copyWithClear(app, "Password", first, app.Config.ClipboardTimeout, selection, false); err != nil {
+					return err
+				}
+			}
+
 			return nil
 
 		},
@@ -68,7 +93,10 @@ By default, generates a single password with all character types enabled.`,
 	cmd.Flags().BoolVarP(&lowercase, "lowercase", "w", true, "Include lowercase letters")
 	cmd.Flags().BoolVar(&noAmbiguous, "no-ambiguous", false, "Exclude ambiguous characters (1/l, 0/O, etc.)")
 	cmd.Flags().BoolVarP(&copy, "copy", "c", false, "Copy first generated password to clipboard")
+	cmd.Flags().BoolVar(&clipPrimary, "clip-primary", false, "Copy first generated password to the primary selection")
 	cmd.Flags().IntVarP(&count, "count", "t", 1, "Number of passwords to generate")
+	cmd.Flags().BoolVarP(&passphrase, "passphrase", "p", false, "Generate a diceware passphrase")
+	cmd.Flags().IntVar(&words, "words", 6, "Number of words in a generated passphrase")
 
 	return cmd
 }
@@ -77,6 +105,48 @@ func generatePassword(length int, special bool) (string, error) {
 	return generatePasswordWithOptions(length, special, true, true, true, false)
 }
 
+// generateEntryPassword generates a password for add and update --generate
+// with generator, or the password_generator setting if it is empty. Random
+// passwords use length and special; passphrases have words words, or as many
+// as the passphrase_words setting if words is 0.
+func generateEntryPassword(app *app.App, generator string, length int, special bool, words int) (string, error) {
+	if generator == "" {
+		generator = app.Config.GetConfigValue("password_generator").(string)
+	}
+	if words == 0 {
+		words = app.Config.GetConfigValue("passphrase_words").(int)
+	}
+
+	var password string
+	var err error
+	switch generator {
+	case "random":
+		password, err = generatePassword(length, special)
+	case "passphrase":
+		if words < 1 {
+			return "", errs.InvalidInput("number of words must be positive")
+		}
+		password, err = generatePassphrase(words)
+	default:
+		return "", errs.InvalidInput("unsupported generator: %s (use random or passphrase)", generator)
+	}
+	if err != nil {
+		return "", errs.Internal("failed to generate password: %w", err)
+	}
+	return password, nil
+}
+
+// generatePassphrase returns a diceware passphrase of the given number of
+// words drawn from the EFF large wordlist, joined with hyphens.
+func generatePassphrase(words int) (string, error) {
+	list, err := diceware.Generate(words)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate passphrase: %w", err)
+	}
+
+	return strings.Join(list, "-"), nil
+}
+
 func generatePasswordWithOptions(length int, special, numbers, uppercase, lowercase, noAmbiguous bool) (string, error) {
 	var chars string
 
@@ -112,24 +182,34 @@ func generatePasswordWithOptions(length int, special, numbers, uppercase, lowerc
 		return "", fmt.Errorf("no character sets selected")
 	}
 
+	for {
+		password, err := generateRaw(length, chars)
+		if err != nil {
+			return "", err
+		}
+
+		// Retry until the password contains every selected character type
+		if validatePassword(password, special, numbers, uppercase, lowercase) {
+			return password, nil
+		}
+	}
+}
+
+// generateRaw returns length characters drawn uniformly from charset without
+// checking which character types the result contains.
+func generateRaw(length int, charset string) (string, error) {
 	var password strings.Builder
 	password.Grow(length)
 
 	for i := 0; i < length; i++ {
-		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
 		if err != nil {
 			return "", fmt.Errorf("failed to generate random number: %w", err)
 		}
-		password.WriteByte(chars[n.Int64()])
-	}
-
-	result := password.String()
-	if !validatePassword(result, special, numbers, uppercase, lowercase) {
-		// If validation fails, generate a new password
-		return generatePasswordWithOptions(length, special, numbers, uppercase, lowercase, noAmbiguous)
+		password.WriteByte(charset[n.Int64()])
 	}
 
-	return result, nil
+	return password.String(), nil
 }
 
 func validatePassword(password string, special, numbers, uppercase, lowercase bool) bool {
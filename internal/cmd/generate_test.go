@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestGeneratePasswordWithOptions(t *testing.T) {
+	tests := []struct {
+		name                                   string
+		special, numbers, uppercase, lowercase bool
+		noAmbiguous                            bool
+	}{
+		{"all classes", true, true, true, true, false},
+		{"no special", false, true, true, true, false},
+		{"letters only", false, false, true, true, false},
+		{"no ambiguous", true, true, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 50 {
+				password, err := generatePasswordWithOptions(8, tt.special, tt.numbers, tt.uppercase, tt.lowercase, tt.noAmbiguous)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(password) != 8 {
+					t.Fatalf("password %q has length %d, want 8", password, len(password))
+				}
+				if !validatePassword(password, tt.special, tt.numbers, tt.uppercase, tt.lowercase) {
+					t.Fatalf("password %q lacks a selected character type", password)
+				}
+				if !tt.special && strings.ContainsAny(password, "!@#$%^&*()_+-=[]{}|;:,.<>?") {
+					t.Fatalf("password %q has special characters", password)
+				}
+				if tt.noAmbiguous && strings.ContainsAny(password, "IlO01") {
+					t.Fatalf("password %q has ambiguous characters", password)
+				}
+			}
+		})
+	}
+
+	if _, err := generatePasswordWithOptions(8, false, false, false, false, false); err == nil {
+		t.Error("generated a password without any character set")
+	}
+}
+
+// isPassphrase reports whether password looks like a diceware passphrase of at
+// least words words. A few words of the wordlist contain hyphens themselves.
+func isPassphrase(password string, words int) bool {
+	return strings.Trim(password, "abcdefghijklmnopqrstuvwxyz-") == "" && strings.Count(password, "-") >= words-1
+}
+
+func TestGenerateEntryPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		settingGen     string
+		settingWords   int
+		generator      string
+		words          int
+		wantPassphrase bool
+		wantWords      int
+		wantErr        bool
+	}{
+		{"random by default", "", 0, "", 0, false, 0, false},
+		{"passphrase setting", "passphrase", 0, "", 0, true, 6, false},
+		{"passphrase_words setting", "passphrase", 8, "", 0, true, 8, false},
+		{"--generator overrides the setting", "passphrase", 0, "random", 0, false, 0, false},
+		{"--words overrides the setting", "passphrase", 8, "", 3, true, 3, false},
+		{"--generator passphrase", "", 0, "passphrase", 4, true, 4, false},
+		{"unknown generator", "", 0, "xkcd", 0, false, 0, true},
+		{"negative words", "", 0, "passphrase", -1, false, 0, true},
+	}
+
+	a := newTestApp(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.Config.PasswordGenerator = tt.settingGen
+			a.Config.PassphraseWords = tt.settingWords
+
+			password, err := generateEntryPassword(a, tt.generator, 20, true, tt.words)
+			if tt.wantErr {
+				if errs.ExitCode(err) != errs.ExitInvalidInput {
+					t.Errorf("err = %v, want invalid input", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantPassphrase {
+				if !isPassphrase(password, tt.wantWords) {
+					t.Errorf("generated %q, want a passphrase of %d words", password, tt.wantWords)
+				}
+			} else if len(password) != 20 {
+				t.Errorf("generated %q, want a random password of length 20", password)
+			}
+		})
+	}
+}
+
+func TestAddAndUpdateUsePasswordGenerator(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+
+	for _, args := range [][]string{
+		{"config", "set", "password_generator", "passphrase"},
+		{"config", "set", "passphrase_words", "5"},
+		{"add", "github"},
+		{"add", "gitlab", "--generator", "random", "--length", "24"},
+	} {
+		if _, err := runCommand(t, a, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	password := func(name string) string {
+		t.Helper()
+		entry, err := a.Storage.GetEntry(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		password, err := a.DecryptPassword(entry.Password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return password
+	}
+	if got := password("github"); !isPassphrase(got, 5) {
+		t.Errorf("add generated %q, want a passphrase of 5 words", got)
+	}
+	if got := password("gitlab"); len(got) != 24 {
+		t.Errorf("add --generator random generated %q, want 24 characters", got)
+	}
+
+	if _, err := runCommand(t, a, "update", "gitlab", "--generate", "--words", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := password("gitlab"); !isPassphrase(got, 3) {
+		t.Errorf("update --generate generated %q, want a passphrase of 3 words", got)
+	}
+
+	if _, err := runCommand(t, a, "config", "set", "password_generator", "xkcd"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("config set password_generator xkcd: err = %v, want invalid input", err)
+	}
+}
+
+func TestGenerateRaw(t *testing.T) {
+	password, err := generateRaw(64, "ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != 64 || strings.Trim(password, "ab") != "" {
+		t.Errorf("generateRaw(64, \"ab\") = %q", password)
+	}
+}
+
+// BenchmarkGenerate compares drawing characters with generateRaw against
+// generating a validated password, which retries until every character type
+// is present.
+func BenchmarkGenerate(b *testing.B) {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!@#$%^&*()_+-=[]{}|;:,.<>?"
+
+	for _, length := range []int{8, 16, 64} {
+		b.Run(fmt.Sprintf("raw/%d", length), func(b *testing.B) {
+			for range b.N {
+				if _, err := generateRaw(length, charset); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("validated/%d", length), func(b *testing.B) {
+			for range b.N {
+				if _, err := generatePasswordWithOptions(length, true, true, true, true, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,89 +1,473 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 func newGetCmd(app *app.App) *cobra.Command {
 	var (
 		copyToClipboard bool
+		clipPrimary     bool
+		copyTOTP        bool
+		raw             bool
+		noNewline       bool
 		showPassword    bool
 		showNotes       bool
+		clearAfter      int
+		wait            bool
+		view            bool
+		showFields      bool
+		mask            bool
+		maskRatio       float64
+		format          string
+		folder          string
+		showCreated     bool
+		showModified    bool
+		relativeTimes   bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "get <name>",
 		Short: "Retrieve a password entry",
 		Long: `Retrieve a password entry by name. 
-By default, only shows username and URL. Use flags to show additional information.`,
+By default, only shows username and URL. Use flags to show additional information.
+
+If the name or alias matches several entries, e.g. with per-folder name
+uniqueness, you are asked to pick one on a terminal. Otherwise the matching
+entries are listed; use --folder to choose between them.
+
+Use --mask to check you have the right entry without exposing the whole
+password: only its first and last two characters are shown, and at least
+--mask-ratio of it stays hidden. Passwords shorter than 8 characters are
+fully masked. Like --show-password, it asks for the master password again
+when require_master_pass is set and is recorded in the access log.
+
+Use --format json, dotenv or ini to print the entry in a form other tools can
+read. The password, notes and custom fields are only included when requested
+with --show-password, --show-notes and --show-fields.
+
+The creation and modification times are shown unless hidden with
+--show-created=false or --show-modified=false. Use --relative to show them
+relative to now, e.g. "3 days ago".
+
+Use --raw in scripts to print only the password followed by a newline, or
+without one with --no-newline. Nothing else is printed or copied, but the
+reveal is still recorded in the access log.
+
+Use --view to show the password in the terminal's alternate screen, so it
+does not remain in the scrollback once a key is pressed.
+
+Use --clip-primary to copy the password to the primary selection, pasted with
+a middle click, instead of the clipboard. Where there is no primary selection
+the clipboard is used.
+
+Use --copy-totp to copy the current TOTP code computed from the entry's "totp"
+custom field instead of the password. 'pm login' copies both in turn.
+
+The clipboard is cleared after the clipboard_timeout setting or --clear-after
+seconds by a passio process left running in the background. With --copy
+--wait the command instead stays in the foreground, showing a countdown, until
+the clipboard has been cleared. Interrupting it with Ctrl-C clears the
+clipboard right away.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
+			}
+
+			// Copying to the primary selection is still copying
+			copyToClipboard = copyToClipboard || clipPrimary
+
+			if wait && !copyToClipboard && !copyTOTP {
+				return errs.InvalidInput("--wait can only be used with --copy, --clip-primary or --copy-totp")
+			}
+
+			switch format {
+			case "text", "json", "dotenv", "ini":
+			default:
+				return errs.InvalidInput("unsupported format: %s (use text, json, dotenv or ini)", format)
+			}
+			if mask && format != "text" {
+				return errs.InvalidInput("--mask can only be used with the text format")
+			}
+
+			if noNewline && !raw {
+				return errs.InvalidInput("--no-newline can only be used with --raw")
+			}
+
+			if maskRatio < 0 || maskRatio > 1 {
+				return errs.InvalidInput("--mask-ratio must be between 0 and 1")
 			}
 
 			name := args[0]
 
-			// Get entry from storage
-			entry, err := app.Storage.GetEntry(name)
+			// Get entry from storage, asking which one is meant if several match
+			entry, err := resolveEntry(app, name, folder)
 			if err != nil {
-				return fmt.Errorf("failed to get entry: %w", err)
+				return err
 			}
 
-			var password string
-			if showPassword || copyToClipboard {
-				password, err = app.DecryptPassword(entry.Password)
+			reveal := showPassword || copyToClipboard || view || raw
+			if entry.IsWriteOnly() && (reveal || mask) {
+				return errs.InvalidInput("entry %s is write-only and cannot be revealed. Use 'pm verify %s' to check a value", entry.Name, entry.Name)
+			}
+
+			if raw {
+				if err := verifyMasterPassword(app); err != nil {
+					return err
+				}
+				if err := logAccess(app, entry.Name, storage.AccessReveal); err != nil {
+					return err
+				}
+
+				password, err := app.DecryptPassword(entry.Password)
 				if err != nil {
-					return fmt.Errorf("failed to decrypt password: %w", err)
+					return errs.Internal("failed to decrypt password: %w", err)
+				}
+
+				if !noNewline {
+					password += "\n"
 				}
+				fmt.Print(password)
+				return nil
 			}
 
-			if copyToClipboard {
-				if err := clipboard.WriteAll(password); err != nil {
-					return fmt.Errorf("failed to copy to clipboard: %w", err)
+			var code string
+			if copyTOTP {
+				totp, err := entryTOTP(app, entry)
+				if err != nil {
+					return err
 				}
-				fmt.Println("Password copied to clipboard")
+				code = totp.Code(time.Now())
+			}
 
-				// Clear clipboard after timeout
-				if timeout := app.Config.ClipboardTimeout; timeout > 0 {
-					go func() {
-						time.Sleep(time.Duration(timeout) * time.Second)
-						clipboard.WriteAll("")
-					}()
+			// Masked passwords, notes and custom fields are as secret as the password
+			var password string
+			if reveal || mask || copyTOTP || showNotes || showFields {
+				if err := verifyMasterPassword(app); err != nil {
+					return err
+				}
+
+				// Only revealing secrets is recorded, not viewing metadata
+				if err := logAccess(app, entry.Name, storage.AccessReveal); err != nil {
+					return err
 				}
 			}
 
-			fmt.Printf("Name: %s\n", entry.Name)
-			if entry.Username != "" {
-				fmt.Printf("Username: %s\n", entry.Username)
+			if reveal || mask {
+				password, err = app.DecryptPassword(entry.Password)
+				if err != nil {
+					return errs.Internal("failed to decrypt password: %w", err)
+				}
 			}
-			if entry.URL != "" {
-				fmt.Printf("URL: %s\n", entry.URL)
+
+			var customFields map[string]string
+			if showFields && len(entry.CustomFields) > 0 {
+				customFields, err = app.DecryptFields(entry.CustomFields)
+				if err != nil {
+					return errs.Internal("failed to decrypt custom fields: %w", err)
+				}
 			}
-			if showPassword {
-				fmt.Printf("Password: %s\n", password)
+
+			var notes string
+			if showNotes {
+				notes, err = app.EntryNotes(entry)
+				if err != nil {
+					return errs.Internal("failed to decrypt notes: %w", err)
+				}
 			}
-			if showNotes && entry.Notes != "" {
-				fmt.Printf("Notes: %s\n", entry.Notes)
+
+			if format != "text" {
+				record := newEntryRecord(entry, customFields)
+				if showPassword {
+					record.Password = password
+				}
+				if showNotes {
+					record.Notes = notes
+				}
+				if err := writeEntryRecord(os.Stdout, record, format); err != nil {
+					return errs.Internal("failed to write entry: %w", err)
+				}
+			} else {
+				fmt.Printf("Name: %s\n", entry.Name)
+				if len(entry.Aliases) > 0 {
+					fmt.Printf("Aliases: %s\n", strings.Join(entry.Aliases, ", "))
+				}
+				if entry.Folder != "" {
+					fmt.Printf("Folder: %s\n", entry.Folder)
+				}
+				if entry.Username != "" {
+					fmt.Printf("Username: %s\n", entry.Username)
+				}
+				if entry.URL != "" {
+					fmt.Printf("URL: %s\n", entry.URL)
+				}
+				if showPassword {
+					fmt.Printf("Password: %s\n", password)
+				}
+				if mask {
+					fmt.Printf("Password: %s\n", maskPassword(password, maskRatio))
+				}
+				if showNotes && notes != "" {
+					fmt.Printf("Notes: %s\n", notes)
+				}
+				if len(customFields) > 0 {
+					fmt.Println("Fields:")
+					for _, key := range sortedKeys(customFields) {
+						fmt.Printf("  %s: %s\n", key, customFields[key])
+					}
+				}
+				if entry.IsWriteOnly() {
+					fmt.Println("Type: write-only")
+				}
+				if len(entry.Tags) > 0 {
+					fmt.Printf("Tags: %s\n", entry.Tags)
+				}
+				formatTime := func(t time.Time) string {
+					if relativeTimes {
+						return relativeTime(t, time.Now())
+					}
+					return t.Format("2006-01-02 15:04:05")
+				}
+				if showCreated {
+					fmt.Printf("Created: %s\n", formatTime(entry.CreatedAt))
+				}
+				if showModified {
+					fmt.Printf("Last modified: %s\n", formatTime(entry.UpdatedAt))
+				}
+				if entry.DeleteAt != nil {
+					fmt.Printf("Deleted after: %s\n", formatTime(*entry.DeleteAt))
+				}
+				if entry.ExpiresAt != nil {
+					fmt.Printf("Password expires: %s\n", formatTime(*entry.ExpiresAt))
+				}
 			}
-			if len(entry.Tags) > 0 {
-				fmt.Printf("Tags: %s\n", entry.Tags)
+
+			if view {
+				if err := viewSecret("Password", password); err != nil {
+					return errs.Internal("failed to show password: %w", err)
+				}
+			}
+
+			if copyToClipboard || copyTOTP {
+				timeout := app.Config.ClipboardTimeout
+				if cmd.Flags().Changed("clear-after") {
+					timeout = clearAfter
+				}
+
+				selection := clipboard.SelectionClipboard
+				if clipPrimary {
+					selection = clipboard.SelectionPrimary
+				}
+
+				label, value := "Password", password
+				if copyTOTP {
+					label, value = "TOTP code", code
+				}
+
+				pending, err := copyWithClear(app, label, value, timeout, selection, wait)
+				if err != nil {
+					return err
+				}
+
+				if wait && pending != nil {
+					// Clear the clipboard on Ctrl-C, as the countdown never finishes
+					app.OnClose(pending.Now)
+					<-pending.Done()
+				}
 			}
-			fmt.Printf("Created: %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
-			fmt.Printf("Last modified: %s\n", entry.UpdatedAt.Format("2006-01-02 15:04:05"))
 
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVarP(&copyToClipboard, "copy", "c", false, "Copy password to clipboard")
+	cmd.Flags().BoolVar(&clipPrimary, "clip-primary", false, "Copy password to the primary selection instead of the clipboard")
+	cmd.Flags().BoolVar(&copyTOTP, "copy-totp", false, "Copy the current TOTP code instead of the password")
+	cmd.MarkFlagsMutuallyExclusive("copy", "copy-totp")
+	cmd.Flags().IntVar(&clearAfter, "clear-after", 0, "Seconds before the copied password is cleared (overrides config)")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait in the foreground until the copied password is cleared")
 	cmd.Flags().BoolVarP(&showPassword, "show-password", "p", false, "Show password in output")
+	cmd.Flags().BoolVar(&view, "view", false, "Show password in a temporary screen that leaves no scrollback")
+	cmd.Flags().BoolVar(&mask, "mask", false, "Show the password with its middle characters masked")
+	cmd.Flags().Float64Var(&maskRatio, "mask-ratio", 0.5, "Minimum fraction of the password hidden by --mask")
+	cmd.MarkFlagsMutuallyExclusive("show-password", "view", "mask")
+	cmd.Flags().BoolVar(&raw, "raw", false, "Print only the password, for scripts; the reveal is still recorded in the access log")
+	cmd.Flags().BoolVar(&noNewline, "no-newline", false, "Omit the newline after the password printed by --raw")
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "Output format (text, json, dotenv or ini)")
 	cmd.Flags().BoolVarP(&showNotes, "show-notes", "n", false, "Show notes in output")
+	cmd.Flags().BoolVar(&showFields, "show-fields", false, "Show custom fields in output")
+	cmd.Flags().StringVar(&folder, "folder", "", "Folder of the entry, when the name matches entries in several folders")
+	cmd.Flags().BoolVar(&showCreated, "show-created", true, "Show when the entry was created")
+	cmd.Flags().BoolVar(&showModified, "show-modified", true, "Show when the entry was last modified")
+	cmd.Flags().BoolVar(&relativeTimes, "relative", false, "Show times relative to now, e.g. 3 days ago")
+
+	// --raw prints nothing but the password
+	for _, flag := range []string{"show-password", "view", "mask", "copy", "clip-primary", "copy-totp", "wait", "format", "show-notes", "show-fields"} {
+		cmd.MarkFlagsMutuallyExclusive("raw", flag)
+	}
 
 	return cmd
 }
+
+// maskedEnds is the number of characters shown at each end of a masked password
+const maskedEnds = 2
+
+// minMaskedLength is the shortest password that is only partially masked
+const minMaskedLength = 8
+
+// maskPassword replaces the middle of password with '*', showing up to
+// maskedEnds characters at each end while hiding at least ratio of it.
+// Passwords shorter than minMaskedLength are masked entirely.
+func maskPassword(password string, ratio float64) string {
+	runes := []rune(password)
+
+	shown := 0
+	if len(runes) >= minMaskedLength {
+		shown = maskedEnds
+		for shown > 0 && float64(len(runes)-2*shown) < ratio*float64(len(runes)) {
+			shown--
+		}
+	}
+
+	masked := make([]rune, len(runes))
+	for i, r := range runes {
+		if i < shown || i >= len(runes)-shown {
+			masked[i] = r
+		} else {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
+
+// entryRecord is the representation of an entry printed by get --format.
+// Secrets are only filled in when requested.
+type entryRecord struct {
+	Name      string            `json:"name"`
+	Aliases   []string          `json:"aliases,omitempty"`
+	Folder    string            `json:"folder,omitempty"`
+	Username  string            `json:"username,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Password  string            `json:"password,omitempty"`
+	Notes     string            `json:"notes,omitempty"`
+	Type      string            `json:"type"`
+	Tags      []string          `json:"tags,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Created   time.Time         `json:"created"`
+	Updated   time.Time         `json:"updated"`
+	DeleteAt  *time.Time        `json:"delete_at,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+func newEntryRecord(entry *storage.Entry, fields map[string]string) *entryRecord {
+	entryType := entry.Type
+	if entryType == "" {
+		entryType = storage.EntryTypeLogin
+	}
+
+	return &entryRecord{
+		Name:      entry.Name,
+		Aliases:   entry.Aliases,
+		Folder:    entry.Folder,
+		Username:  entry.Username,
+		URL:       entry.URL,
+		Type:      string(entryType),
+		Tags:      entry.Tags,
+		Fields:    fields,
+		Created:   entry.CreatedAt,
+		Updated:   entry.UpdatedAt,
+		DeleteAt:  entry.DeleteAt,
+		ExpiresAt: entry.ExpiresAt,
+	}
+}
+
+// pairs returns the non-empty values of the record as key/value pairs in a
+// fixed order. Custom fields follow with a "field_" prefix.
+func (r *entryRecord) pairs() [][2]string {
+	pairs := [][2]string{{"name", r.Name}}
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, [2]string{key, value})
+		}
+	}
+
+	add("aliases", strings.Join(r.Aliases, ","))
+	add("folder", r.Folder)
+	add("username", r.Username)
+	add("url", r.URL)
+	add("password", r.Password)
+	add("notes", r.Notes)
+	add("type", r.Type)
+	add("tags", strings.Join(r.Tags, ","))
+	add("created", r.Created.Format(time.RFC3339))
+	add("updated", r.Updated.Format(time.RFC3339))
+	if r.DeleteAt != nil {
+		add("delete_at", r.DeleteAt.Format(time.RFC3339))
+	}
+	if r.ExpiresAt != nil {
+		add("expires_at", r.ExpiresAt.Format(time.RFC3339))
+	}
+	for _, key := range sortedKeys(r.Fields) {
+		add("field_"+key, r.Fields[key])
+	}
+
+	return pairs
+}
+
+// writeEntryRecord writes record to w as a JSON object, dotenv KEY=value lines
+// or an INI section named after the entry.
+func writeEntryRecord(w io.Writer, record *entryRecord, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(record)
+	case "dotenv":
+		for _, pair := range record.pairs() {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", envName(pair[0]), dotenvQuote(pair[1])); err != nil {
+				return err
+			}
+		}
+	case "ini":
+		section := strings.NewReplacer("[", "", "]", "", "\n", " ").Replace(record.Name)
+		if _, err := fmt.Fprintf(w, "[%s]\n", section); err != nil {
+			return err
+		}
+		for _, pair := range record.pairs() {
+			// Unquoted semicolons start a comment in INI files
+			value := dotenvQuote(pair[1])
+			if strings.Contains(value, ";") && !strings.HasPrefix(value, `"`) {
+				value = `"` + value + `"`
+			}
+			if _, err := fmt.Fprintf(w, "%s = %s\n", pair[0], value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
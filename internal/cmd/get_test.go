@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestGetSecretsRequireMasterPassword(t *testing.T) {
+	for _, flag := range []string{"--show-password", "--mask", "--show-notes", "--show-fields"} {
+		t.Run(flag, func(t *testing.T) {
+			a := newTestApp(t)
+			a.Config.RequireMasterPassword = true
+			a.Config.AccessLog = true
+			addTestEntry(t, a, "github", "hunter2")
+
+			stubPassword(t, "wrong password")
+			if _, err := runCommand(t, a, "get", "github", flag); errs.ExitCode(err) != errs.ExitInvalidInput {
+				t.Fatalf("get %s with a wrong master password: err = %v, want invalid input", flag, err)
+			}
+
+			stubPassword(t, testMasterPassword)
+			if _, err := runCommand(t, a, "get", "github", flag); err != nil {
+				t.Fatalf("get %s: %v", flag, err)
+			}
+
+			records, err := a.Storage.ListAccessLog()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Action != storage.AccessReveal {
+				t.Fatalf("access log = %v, want a single reveal", records)
+			}
+		})
+	}
+}
+
+func TestGetMetadataIsNotLogged(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.RequireMasterPassword = true
+	a.Config.AccessLog = true
+	addTestEntry(t, a, "github", "hunter2")
+
+	stubPassword(t, "never asked for")
+	output, err := runCommand(t, a, "get", "github")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if strings.Contains(output, "hunter2") {
+		t.Errorf("metadata-only get printed the password:\n%s", output)
+	}
+
+	records, err := a.Storage.ListAccessLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("access log = %v, want nothing for a metadata-only get", records)
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"with newline", []string{"--raw"}, "hunter2\n"},
+		{"without newline", []string{"--raw", "--no-newline"}, "hunter2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			a.Config.AccessLog = true
+			addTestEntry(t, a, "github", "hunter2")
+
+			stubPassword(t, testMasterPassword)
+			output, err := runCommand(t, a, append([]string{"get", "github"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("get %v: %v", tt.args, err)
+			}
+			if output != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+
+			// --raw only keeps the output clean; the reveal is still logged
+			records, err := a.Storage.ListAccessLog()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Action != storage.AccessReveal {
+				t.Fatalf("access log = %v, want a single reveal", records)
+			}
+		})
+	}
+}
+
+func TestGetRawRejectsOtherOutput(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	if _, err := runCommand(t, a, "get", "github", "--no-newline"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("--no-newline without --raw: err = %v, want invalid input", err)
+	}
+	if _, err := runCommand(t, a, "get", "github", "--raw", "--show-notes"); err == nil {
+		t.Error("--raw with --show-notes was accepted")
+	}
+}
+
+func TestParseFieldFlags(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{[]string{"pin=1234"}, map[string]string{"pin": "1234"}, false},
+		{[]string{" pin =1234", "code=a=b"}, map[string]string{"pin": "1234", "code": "a=b"}, false},
+		{[]string{"pin="}, map[string]string{"pin": ""}, false},
+		{[]string{"pin=1", "pin=2"}, map[string]string{"pin": "2"}, false},
+		{[]string{"pin"}, nil, true},
+		{[]string{"=1234"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFieldFlags(tt.flags)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFieldFlags(%q): err = %v, want error %v", tt.flags, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("parseFieldFlags(%q) = %v, want %v", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+
+	fields := func() map[string]string {
+		t.Helper()
+		output, err := runCommand(t, a, "get", "bank", "--show-fields", "--format", "json")
+		if err != nil {
+			t.Fatalf("get --show-fields: %v", err)
+		}
+		var record entryRecord
+		if err := json.Unmarshal([]byte(output), &record); err != nil {
+			t.Fatal(err)
+		}
+		return record.Fields
+	}
+
+	if _, err := runCommand(t, a, "add", "bank", "--password", "hunter2", "--field", "pin=1234", "--field", "account=DE89"); err != nil {
+		t.Fatalf("add --field: %v", err)
+	}
+	if got, want := fields(), map[string]string{"pin": "1234", "account": "DE89"}; !maps.Equal(got, want) {
+		t.Errorf("fields after add = %v, want %v", got, want)
+	}
+
+	// Fields are stored encrypted
+	entry, err := a.Storage.GetEntry("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(entry.CustomFields), "1234") {
+		t.Error("custom fields are stored in plain text")
+	}
+
+	if _, err := runCommand(t, a, "update", "bank", "--field", "pin=4321", "--field", "account="); err != nil {
+		t.Fatalf("update --field: %v", err)
+	}
+	if got, want := fields(), map[string]string{"pin": "4321"}; !maps.Equal(got, want) {
+		t.Errorf("fields after update = %v, want %v", got, want)
+	}
+
+	// Metadata output leaves the fields out
+	output, err := runCommand(t, a, "get", "bank", "--format", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output, "4321") {
+		t.Errorf("get without --show-fields printed a custom field:\n%s", output)
+	}
+}
+
+func TestWriteEntryRecord(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := &entryRecord{
+		Name:     "my [site]",
+		Username: "alice",
+		Password: "p@ss word;1",
+		Notes:    "a;b",
+		Type:     "login",
+		Tags:     []string{"work", "dev"},
+		Fields:   map[string]string{"pin": "1234", "api key": "k"},
+		Created:  created,
+		Updated:  created,
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"dotenv", `NAME="my [site]"
+USERNAME=alice
+PASSWORD="p@ss word;1"
+NOTES=a;b
+TYPE=login
+TAGS=work,dev
+CREATED=2026-01-02T03:04:05Z
+UPDATED=2026-01-02T03:04:05Z
+FIELD_API_KEY=k
+FIELD_PIN=1234
+`},
+		{"ini", `[my site]
+name = "my [site]"
+username = alice
+password = "p@ss word;1"
+notes = "a;b"
+type = login
+tags = work,dev
+created = 2026-01-02T03:04:05Z
+updated = 2026-01-02T03:04:05Z
+field_api key = k
+field_pin = 1234
+`},
+	}
+
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := writeEntryRecord(&buf, record, tt.format); err != nil {
+			t.Fatalf("writeEntryRecord(%s): %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("writeEntryRecord(%s) =\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	var buf strings.Builder
+	if err := writeEntryRecord(&buf, record, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded entryRecord
+	if err := json.Unmarshal([]byte(buf.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != record.Name || decoded.Password != record.Password || !maps.Equal(decoded.Fields, record.Fields) {
+		t.Errorf("JSON record = %+v, want %+v", decoded, record)
+	}
+
+	if err := writeEntryRecord(&buf, record, "yaml"); err == nil {
+		t.Error("writeEntryRecord accepted an unsupported format")
+	}
+}
+
+func TestGetFormatOmitsSecretsByDefault(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	for _, format := range []string{"json", "dotenv", "ini"} {
+		output, err := runCommand(t, a, "get", "github", "--format", format)
+		if err != nil {
+			t.Fatalf("get --format %s: %v", format, err)
+		}
+		if !strings.Contains(output, "github") || strings.Contains(output, "hunter2") {
+			t.Errorf("get --format %s printed:\n%s\nwant the entry without its password", format, output)
+		}
+	}
+
+	if _, err := runCommand(t, a, "get", "github", "--format", "xml"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("get with an unknown format: err = %v, want invalid input", err)
+	}
+}
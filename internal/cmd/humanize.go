@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// relativeTime describes t relative to now in the largest whole unit, e.g.
+// "3 days ago" or "in 2 hours". Times less than a minute away are "just now".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	var text string
+	for _, unit := range units {
+		if n := int(d / unit.size); n >= 1 {
+			text = fmt.Sprintf("%d %s", n, unit.name)
+			if n > 1 {
+				text += "s"
+			}
+			break
+		}
+	}
+
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
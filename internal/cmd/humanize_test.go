@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		offset time.Duration
+		want   string
+	}{
+		{0, "just now"},
+		{-30 * time.Second, "just now"},
+		{30 * time.Second, "just now"},
+		{-time.Minute, "1 minute ago"},
+		{-90 * time.Minute, "1 hour ago"},
+		{-5 * time.Hour, "5 hours ago"},
+		{-3 * 24 * time.Hour, "3 days ago"},
+		{-45 * 24 * time.Hour, "1 month ago"},
+		{-800 * 24 * time.Hour, "2 years ago"},
+		{2 * time.Hour, "in 2 hours"},
+		{24 * time.Hour, "in 1 day"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(tt.offset), now); got != tt.want {
+			t.Errorf("relativeTime(now%+v) = %q, want %q", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestGetTimes(t *testing.T) {
+	a := newTestApp(t)
+	encrypted, err := a.EncryptPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry("github", "alice", encrypted)
+	entry.CreatedAt = time.Now().AddDate(0, 0, -3)
+	entry.UpdatedAt = time.Now().Add(-5 * time.Hour)
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{nil, []string{"Created: " + entry.CreatedAt.Format("2006-01-02 15:04:05"), "Last modified: " + entry.UpdatedAt.Format("2006-01-02 15:04:05")}, nil},
+		{[]string{"--relative"}, []string{"Created: 3 days ago", "Last modified: 5 hours ago"}, nil},
+		{[]string{"--show-created=false"}, []string{"Last modified:"}, []string{"Created:"}},
+		{[]string{"--show-modified=false", "--relative"}, []string{"Created: 3 days ago"}, []string{"Last modified:"}},
+		{[]string{"--show-created=false", "--show-modified=false"}, nil, []string{"Created:", "Last modified:"}},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"get", "github"}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("get %v: %v", tt.args, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("get %v is missing %q:\n%s", tt.args, want, output)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(output, notWant) {
+				t.Errorf("get %v printed %q:\n%s", tt.args, notWant, output)
+			}
+		}
+	}
+}
@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
@@ -19,25 +26,53 @@ func newImportCmd(app *app.App) *cobra.Command {
 		decrypt  bool
 		dryRun   bool
 		skipDups bool
+		strict   bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "import <file>",
 		Short: "Import password entries",
 		Long: `Import password entries from a JSON or CSV file.
-Supports importing encrypted or decrypted passwords.`,
+Supports importing encrypted or decrypted passwords. Gzip-compressed files
+are decompressed transparently. Blank lines and comment lines starting with #
+in CSV files are skipped.
+
+CSV columns are matched by the names in the header row, in any order, so
+exports made with 'pm export --columns' can be imported again. The header must
+include a Name column; a header naming unknown columns is rejected.
+
+Imports are limited to the max_import_entries setting and entries with fields
+larger than the max_name_length, max_password_length and max_notes_length
+settings are rejected, so a malformed file cannot fill the vault.
+
+Encrypted exports from a different vault cannot be decrypted with this vault's
+master key. Use --decrypt to be prompted for the source vault's master password
+so the entries can be re-encrypted under the current key.
+
+Entries whose name, username, password and URL match an existing entry are
+reported as unchanged and skipped, so the same file can be imported again.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			filename := args[0]
 			if _, err := os.Stat(filename); err != nil {
-				return fmt.Errorf("import file not found: %w", err)
+				return errs.NotFound("import file not found: %w", err)
+			}
+
+			if format == "" {
+				detected, err := detectImportFormat(filename)
+				if err != nil {
+					return errs.InvalidInput("failed to detect import format: %w", err)
+				}
+				format = detected
+				fmt.Printf("Detected format: %s\n", format)
 			}
 
 			var importedData *ExportData
+			var malformed int
 			var err error
 
 			// Import based on format
@@ -45,65 +80,148 @@ Supports importing encrypted or decrypted passwords.`,
 			case "json":
 				importedData, err = importJSON(filename)
 			case "csv":
-				importedData, err = importCSV(filename)
+				importedData, malformed, err = importCSV(filename, strict)
 			default:
-				return fmt.Errorf("unsupported format: %s", format)
+				return errs.InvalidInput("unsupported format: %s", format)
 			}
 
 			if err != nil {
-				return fmt.Errorf("failed to import data: %w", err)
+				return errs.Internal("failed to import data: %w", err)
+			}
+
+			if limit := app.Config.ImportLimit(); len(importedData.Entries) > limit {
+				return errs.InvalidInput("import has %d entries, more than the limit of %d (see the max_import_entries setting)", len(importedData.Entries), limit)
+			}
+
+			var sourceKey []byte
+			if importedData.Encrypted {
+				sourceKey, err = resolveSourceKey(app, importedData, decrypt)
+				if err != nil {
+					return err
+				}
 			}
 
 			// Process entries
-			var imported, skipped int
+			var skipped, unchanged int
+			var toAdd []*storage.Entry
+			seen := make(map[string]bool)
+			perFolder := storage.NameScope(app.Config.NameUniqueness) == storage.NameScopeFolder
 			for _, importEntry := range importedData.Entries {
-				// Check if entry already exists
-				existing, err := app.Storage.GetEntry(importEntry.Name)
-				if err == nil && existing != nil {
+				// Names only have to be unique within a folder under folder scope
+				key := importEntry.Name
+				if perFolder {
+					key = importEntry.Folder + "/" + importEntry.Name
+				}
+
+				// Check if entry already exists, or appeared earlier in the file
+				existing, err := findImportDuplicate(app, importEntry, perFolder)
+				if err != nil {
+					return err
+				}
+				if existing != nil && !seen[key] {
+					// Entries identical to the stored one are never duplicated
+					same, err := sameImportContent(app, existing, importEntry, importedData.Encrypted, sourceKey)
+					if err != nil {
+						return err
+					}
+					if same {
+						unchanged++
+						seen[key] = true
+						continue
+					}
+				}
+				if existing != nil || seen[key] {
 					if skipDups {
 						skipped++
 						continue
 					}
-					return fmt.Errorf("entry already exists: %s", importEntry.Name)
+					return errs.Conflict("entry already exists: %s", importEntry.Name)
 				}
 
 				// Create new entry
 				entry := &storage.Entry{
-					Name:      importEntry.Name,
-					Username:  importEntry.Username,
-					URL:       importEntry.URL,
-					Notes:     importEntry.Notes,
-					Tags:      importEntry.Tags,
-					CreatedAt: importEntry.CreatedAt,
-					UpdatedAt: importEntry.UpdatedAt,
+					Name:              importEntry.Name,
+					Aliases:           importEntry.Aliases,
+					Username:          importEntry.Username,
+					URL:               importEntry.URL,
+					Tags:              importEntry.Tags,
+					Type:              storage.EntryType(importEntry.Type),
+					Folder:            importEntry.Folder,
+					CreatedAt:         importEntry.CreatedAt,
+					UpdatedAt:         importEntry.UpdatedAt,
+					ExpiresAt:         importEntry.ExpiresAt,
+					DeleteAt:          importEntry.DeleteAt,
+					PasswordChangedAt: importEntry.PasswordChangedAt,
+				}
+
+				// Exports with stripped metadata carry no timestamps
+				if entry.CreatedAt.IsZero() {
+					entry.CreatedAt = time.Now()
+				}
+				if entry.UpdatedAt.IsZero() {
+					entry.UpdatedAt = entry.CreatedAt
 				}
 
 				// Handle password
-				if importedData.Encrypted {
+				if importedData.Encrypted && sourceKey == nil {
 					entry.Password = importEntry.Password
+				} else if importedData.Encrypted {
+					// Re-encrypt password from a foreign vault under the current key
+					plain, err := app.Encryption.Decrypt(importEntry.Password, sourceKey)
+					if err != nil {
+						return errs.Internal("failed to decrypt password for entry %s with source key: %w", entry.Name, err)
+					}
+					encryptedPass, err := app.EncryptPassword(string(plain))
+					if err != nil {
+						return errs.Internal("failed to encrypt password for entry %s: %w", entry.Name, err)
+					}
+					entry.Password = encryptedPass
 				} else {
 					// Encrypt password if it was imported in plain text
 					encryptedPass, err := app.EncryptPassword(string(importEntry.Password))
 					if err != nil {
-						return fmt.Errorf("failed to encrypt password for entry %s: %w", entry.Name, err)
+						return errs.Internal("failed to encrypt password for entry %s: %w", entry.Name, err)
 					}
 					entry.Password = encryptedPass
 				}
 
-				// Add entry unless this is a dry run
-				if !dryRun {
-					if err := app.Storage.AddEntry(entry); err != nil {
-						return fmt.Errorf("failed to add entry %s: %w", entry.Name, err)
-					}
+				if err := importNotes(app, entry, importEntry, importedData.Encrypted, sourceKey); err != nil {
+					return errs.Internal("failed to import notes for entry %s: %w", entry.Name, err)
 				}
-				imported++
+
+				// Handle custom fields the same way as the password
+				if err := importCustomFields(app, entry, importEntry, importedData.Encrypted, sourceKey); err != nil {
+					return errs.Internal("failed to import custom fields for entry %s: %w", entry.Name, err)
+				}
+
+				// Reject oversized entries by name before anything is added
+				if err := storage.ValidateEntry(entry, app.Config.EntryLimits()); err != nil {
+					return storageError(fmt.Sprintf("invalid entry %s", entry.Name), err)
+				}
+
+				toAdd = append(toAdd, entry)
+				seen[key] = true
 			}
 
+			// Add all entries at once unless this is a dry run
+			if !dryRun && len(toAdd) > 0 {
+				if err := app.Storage.AddEntries(toAdd); err != nil {
+					return storageError("failed to add entries", err)
+				}
+			}
+			imported := len(toAdd)
+
 			fmt.Printf("Import summary:\n")
 			fmt.Printf("- Imported: %d entries\n", imported)
+			if unchanged > 0 {
+				fmt.Printf("- Unchanged: %d entries identical to existing ones\n", unchanged)
+			}
 			if skipped > 0 {
 				fmt.Printf("- Skipped: %d duplicate entries\n", skipped)
 			}
+			if malformed > 0 {
+				fmt.Printf("- Warning: skipped %d malformed CSV rows\n", malformed)
+			}
 			if dryRun {
 				fmt.Println("This was a dry run - no entries were actually imported")
 			}
@@ -113,16 +231,250 @@ Supports importing encrypted or decrypted passwords.`,
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&format, "format", "f", "json", "Import format (json or csv)")
-	cmd.Flags().BoolVarP(&decrypt, "decrypt", "d", false, "Import decrypted passwords")
+	cmd.Flags().StringVarP(&format, "format", "f", "", "Import format (json or csv, detected when omitted)")
+	cmd.Flags().BoolVarP(&decrypt, "decrypt", "d", false, "Re-encrypt passwords from a vault with a different master key")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate import without making changes")
 	cmd.Flags().BoolVar(&skipDups, "skip-duplicates", false, "Skip duplicate entries instead of failing")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on malformed CSV rows instead of skipping them")
 
 	return cmd
 }
 
-func importJSON(filename string) (*ExportData, error) {
+// findImportDuplicate returns the stored entry that an imported entry
+// collides with, or nil. Under per-folder name uniqueness only an entry of
+// the same name in the same folder collides; an alias collides anywhere.
+func findImportDuplicate(app *app.App, importEntry *ExportEntry, perFolder bool) (*storage.Entry, error) {
+	candidates, err := app.Storage.FindEntries(importEntry.Name)
+	if err != nil {
+		return nil, storageError("failed to check for duplicates", err)
+	}
+
+	for _, candidate := range candidates {
+		if perFolder && candidate.Name == importEntry.Name && candidate.Folder != importEntry.Folder {
+			continue
+		}
+		return candidate, nil
+	}
+	return nil, nil
+}
+
+func importCustomFields(app *app.App, entry *storage.Entry, importEntry *ExportEntry, encrypted bool, sourceKey []byte) error {
+	if !encrypted {
+		fields, err := app.EncryptFields(importEntry.Fields)
+		entry.CustomFields = fields
+		return err
+	}
+
+	if sourceKey == nil || len(importEntry.EncryptedFields) == 0 {
+		entry.CustomFields = importEntry.EncryptedFields
+		return nil
+	}
+
+	// Re-encrypt fields from a foreign vault under the current key
+	plain, err := app.Encryption.Decrypt(importEntry.EncryptedFields, sourceKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt with source key: %w", err)
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(plain, &fields); err != nil {
+		return fmt.Errorf("failed to unmarshal custom fields: %w", err)
+	}
+
+	entry.CustomFields, err = app.EncryptFields(fields)
+	return err
+}
+
+// importNotes sets the notes of an imported entry. Encrypted notes stay
+// encrypted, and are re-encrypted under the current key if they come from
+// another vault. Plain text notes follow the encrypt_notes setting.
+func importNotes(app *app.App, entry *storage.Entry, importEntry *ExportEntry, encrypted bool, sourceKey []byte) error {
+	if !encrypted || len(importEntry.EncryptedNotes) == 0 {
+		return app.SetEntryNotes(entry, importEntry.Notes)
+	}
+
+	if sourceKey == nil {
+		entry.SecureNotes = importEntry.EncryptedNotes
+		return nil
+	}
+
+	plain, err := app.Encryption.Decrypt(importEntry.EncryptedNotes, sourceKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt with source key: %w", err)
+	}
+
+	entry.SecureNotes, err = app.EncryptPassword(string(plain))
+	return err
+}
+
+// sameImportContent reports whether imported has the same content hash as existing.
+func sameImportContent(app *app.App, existing *storage.Entry, imported *ExportEntry, encrypted bool, sourceKey []byte) (bool, error) {
+	existingPassword, err := app.DecryptPassword(existing.Password)
+	if err != nil {
+		return false, errs.Internal("failed to decrypt password for entry %s: %w", existing.Name, err)
+	}
+
+	importedPassword := string(imported.Password)
+	if encrypted && sourceKey == nil {
+		importedPassword, err = app.DecryptPassword(imported.Password)
+	} else if encrypted {
+		var plain []byte
+		plain, err = app.Encryption.Decrypt(imported.Password, sourceKey)
+		importedPassword = string(plain)
+	}
+	if err != nil {
+		return false, errs.Internal("failed to decrypt password for entry %s: %w", imported.Name, err)
+	}
+
+	return entryContentHash(existing.Name, existing.Username, existingPassword, existing.URL) ==
+		entryContentHash(imported.Name, imported.Username, importedPassword, imported.URL), nil
+}
+
+// entryContentHash hashes the name, username, password and URL of an entry.
+func entryContentHash(name, username, password, url string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.Join([]string{name, username, password, url}, "\x00")))
+}
+
+// resolveSourceKey checks that the encrypted passwords in data can be
+// decrypted by this vault. If they cannot, it returns the key of the source
+// vault derived from its master password, which is only prompted for when
+// reEncrypt is set. A nil key means the passwords can be imported as is.
+func resolveSourceKey(app *app.App, data *ExportData, reEncrypt bool) ([]byte, error) {
+	var foreign *ExportEntry
+	for _, entry := range data.Entries {
+		if _, err := app.DecryptPassword(entry.Password); err != nil {
+			foreign = entry
+			break
+		}
+	}
+
+	if foreign == nil {
+		return nil, nil
+	}
+
+	if !reEncrypt {
+		return nil, errs.InvalidInput("entry %s was encrypted with a different master key; "+
+			"re-run with --decrypt to re-encrypt it using the source vault's master password", foreign.Name)
+	}
+
+	if len(data.Salt) == 0 {
+		return nil, errs.InvalidInput("export does not record its key salt; export it again from the source vault")
+	}
+
+	fmt.Print("Enter source vault master password: ")
+	password, err := readPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	var kdf crypto.KDFParams
+	if data.KDF != nil {
+		kdf = *data.KDF
+	}
+	key, err := app.Encryption.DeriveKeyWithParams(password, data.Salt, kdf)
+	if err != nil {
+		return nil, errs.InvalidInput("export records unusable key derivation parameters: %w", err)
+	}
+	if _, err := app.Encryption.Decrypt(foreign.Password, key); err != nil {
+		return nil, errs.InvalidInput("invalid source vault master password")
+	}
+
+	return key, nil
+}
+
+// knownCSVHeaders maps the CSV headers of other password managers to their
+// names, so their exports can be recognised and rejected with a clear error.
+var knownCSVHeaders = map[string]string{
+	"folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp": "Bitwarden",
+	"url,username,password,totp,extra,name,grouping,fav":                                                 "LastPass",
+	"name,url,username,password":                "Chrome",
+	"name,url,username,password,note":           "Chrome",
+	"title,url,username,password,notes,otpauth": "1Password",
+}
+
+// gzipMagic are the first bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// importFile is an import file, transparently decompressed if it is gzipped.
+type importFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the decompressor, if any, and the underlying file.
+func (f *importFile) Close() error {
+	var err error
+	for i := len(f.closers) - 1; i >= 0; i-- {
+		if cerr := f.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// openImportFile opens filename for reading. Files with a .gz extension or
+// starting with the gzip magic bytes are decompressed while reading.
+func openImportFile(filename string) (io.ReadCloser, error) {
 	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	magic, _ := reader.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) && !strings.EqualFold(filepath.Ext(filename), ".gz") {
+		return &importFile{Reader: reader, closers: []io.Closer{file}}, nil
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+
+	return &importFile{Reader: gz, closers: []io.Closer{file, gz}}, nil
+}
+
+// detectImportFormat sniffs the beginning of filename to tell JSON from CSV.
+func detectImportFormat(filename string) (string, error) {
+	file, err := openImportFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	// Detect the format from the first line that is not blank or a comment
+	reader := bufio.NewReader(file)
+	var trimmed string
+	for first := true; ; first = false {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("empty import file")
+		}
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if !skipCSVLine(line) {
+			trimmed = strings.TrimSpace(line)
+			break
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "{"), strings.HasPrefix(trimmed, "["):
+		return "json", nil
+	case strings.Contains(trimmed, ","):
+		if manager, ok := knownCSVHeaders[strings.ToLower(trimmed)]; ok {
+			return "", fmt.Errorf("%s CSV exports are not supported; convert the file to the passio CSV layout", manager)
+		}
+		return "csv", nil
+	default:
+		return "", fmt.Errorf("unrecognized import format, use --format")
+	}
+}
+
+func importJSON(filename string) (*ExportData, error) {
+	file, err := openImportFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open import file: %w", err)
 	}
@@ -136,10 +488,15 @@ func importJSON(filename string) (*ExportData, error) {
 	return &data, nil
 }
 
-func importCSV(filename string) (*ExportData, error) {
-	file, err := os.Open(filename)
+// importCSV reads entries from a CSV export. Columns are matched by the
+// names in the header, so exports made with --columns can be imported again;
+// a header naming unknown columns or no Name column is rejected. Rows with
+// fewer fields than the header are counted and skipped, or abort the import
+// when strict is set.
+func importCSV(filename string, strict bool) (*ExportData, int, error) {
+	file, err := openImportFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open import file: %w", err)
+		return nil, 0, fmt.Errorf("failed to open import file: %w", err)
 	}
 	defer file.Close()
 
@@ -153,41 +510,111 @@ func importCSV(filename string) (*ExportData, error) {
 	// Read CSV file line by line
 	scanner := bufio.NewScanner(file)
 
-	// Skip header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("empty CSV file")
+	// Skip the header, which may carry a UTF-8 byte order mark and follow
+	// comment or blank lines
+	lineNum := 0
+	var header string
+	for header == "" {
+		if !scanner.Scan() {
+			return nil, 0, fmt.Errorf("empty CSV file")
+		}
+		lineNum++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if !skipCSVLine(line) {
+			header = line
+		}
+	}
+
+	columns, err := parseCSVHeader(header)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Process entries
+	malformed := 0
 	for scanner.Scan() {
-		line := scanner.Text()
+		lineNum++
+		// Files saved on Windows may end lines with CRLF
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if skipCSVLine(line) {
+			continue
+		}
 		fields := parseCSVLine(line)
-		if len(fields) < 8 {
-			continue // Skip invalid lines
+		if len(fields) < len(columns) {
+			if strict {
+				return nil, malformed, fmt.Errorf("malformed CSV row on line %d: expected %d fields, got %d", lineNum, len(columns), len(fields))
+			}
+			malformed++
+			continue
 		}
 
-		createdAt, _ := time.Parse(time.RFC3339, fields[6])
-		updatedAt, _ := time.Parse(time.RFC3339, fields[7])
-
-		entry := &ExportEntry{
-			Name:      fields[0],
-			Username:  fields[1],
-			Password:  []byte(fields[2]),
-			URL:       fields[3],
-			Notes:     fields[4],
-			Tags:      strings.Split(fields[5], ";"),
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
+		entry := &ExportEntry{}
+		for i, column := range columns {
+			setCSVColumn(entry, column, fields[i])
 		}
 
 		data.Entries = append(data.Entries, entry)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading CSV: %w", err)
+		return nil, malformed, fmt.Errorf("error reading CSV: %w", err)
 	}
 
-	return data, nil
+	return data, malformed, nil
+}
+
+// parseCSVHeader returns the canonical column name of each field of a CSV
+// header, matched case-insensitively against the export columns.
+func parseCSVHeader(header string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range parseCSVLine(header) {
+		column, ok := csvColumnName(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported CSV header: unknown column %q (available: %s)", name, strings.Join(csvColumnNames, ", "))
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("unsupported CSV header: column %s appears twice", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	if !seen["Name"] {
+		return nil, fmt.Errorf("unsupported CSV header: no Name column")
+	}
+
+	return columns, nil
+}
+
+// setCSVColumn sets the field of entry read from a CSV column.
+func setCSVColumn(entry *ExportEntry, column, value string) {
+	switch column {
+	case "Name":
+		entry.Name = value
+	case "Username":
+		entry.Username = value
+	case "Password":
+		entry.Password = []byte(value)
+	case "URL":
+		entry.URL = value
+	case "Notes":
+		entry.Notes = value
+	case "Tags":
+		entry.Tags = strings.Split(value, ";")
+	case "Created":
+		entry.CreatedAt, _ = time.Parse(time.RFC3339, value)
+	case "Updated":
+		entry.UpdatedAt, _ = time.Parse(time.RFC3339, value)
+	}
+}
+
+// skipCSVLine reports whether line is blank or a comment starting with #.
+func skipCSVLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
 }
 
 // parseCSVLine parses a CSV line handling quoted fields
@@ -0,0 +1,561 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// writeTestFile writes content to a file in a temporary directory and
+// returns its path.
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportCSVSkipsCommentLines(t *testing.T) {
+	path := writeTestFile(t, "export.csv", "# Exported by passio\n"+
+		"Name,Username,Password,URL,Notes,Tags,Created,Updated\n"+
+		"# a comment between rows\n"+
+		"\n"+
+		"\"#name\",alice,secret,,,,,\n"+
+		"  # an indented comment\n"+
+		"github,bob,hunter2,https://github.com,,,,\n")
+
+	data, malformed, err := importCSV(path, true)
+	if err != nil {
+		t.Fatalf("importCSV: %v", err)
+	}
+	if malformed != 0 {
+		t.Errorf("malformed = %d, want 0", malformed)
+	}
+
+	var names []string
+	for _, entry := range data.Entries {
+		names = append(names, entry.Name)
+	}
+	if len(names) != 2 || names[0] != "#name" || names[1] != "github" {
+		t.Fatalf("imported %q, want [#name github]", names)
+	}
+	if string(data.Entries[0].Password) != "secret" {
+		t.Errorf("password of #name = %q, want secret", data.Entries[0].Password)
+	}
+}
+
+func TestImportCSVSkipsCommentsBeforeHeader(t *testing.T) {
+	path := writeTestFile(t, "export.csv", "\ufeff# Exported by passio\n"+
+		"\n"+
+		"Name,Username,Password,URL,Notes,Tags,Created,Updated\n"+
+		"github,bob,hunter2,,,,,\n")
+
+	data, _, err := importCSV(path, true)
+	if err != nil {
+		t.Fatalf("importCSV: %v", err)
+	}
+	if len(data.Entries) != 1 || data.Entries[0].Name != "github" {
+		t.Fatalf("imported %d entries, want only github", len(data.Entries))
+	}
+}
+
+func TestImportCSVByteOrderMarkAndCRLF(t *testing.T) {
+	tests := map[string]string{
+		"bom":      "\ufeffName,Username,Password\ngithub,alice,hunter2\n",
+		"crlf":     "Name,Username,Password\r\ngithub,alice,hunter2\r\n",
+		"bom crlf": "\ufeffName,Username,Password\r\ngithub,alice,hunter2\r\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeTestFile(t, "import.csv", content)
+			data, malformed, err := importCSV(path, true)
+			if err != nil {
+				t.Fatalf("importCSV: %v", err)
+			}
+			if malformed != 0 || len(data.Entries) != 1 {
+				t.Fatalf("imported %d entries and %d malformed rows, want one entry", len(data.Entries), malformed)
+			}
+			entry := data.Entries[0]
+			if entry.Name != "github" || entry.Username != "alice" || string(entry.Password) != "hunter2" {
+				t.Errorf("imported %q/%q/%q, want github/alice/hunter2", entry.Name, entry.Username, entry.Password)
+			}
+		})
+	}
+}
+
+// gzipped returns content compressed with gzip.
+func gzipped(t *testing.T, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestImportGzip(t *testing.T) {
+	const csvExport = "Name,Username,Password\ngithub,alice,hunter2\n"
+	const jsonExport = `{"version": "1.0", "entries": [{"name": "gitlab", "username": "bob", "password": "aHVudGVyMw=="}]}`
+
+	tests := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{"import.csv.gz", gzipped(t, csvExport), "github"},
+		{"import.json.gz", gzipped(t, jsonExport), "gitlab"},
+		// Compression is detected from the content, not only the extension
+		{"import.csv", gzipped(t, csvExport), "github"},
+		{"import", gzipped(t, jsonExport), "gitlab"},
+	}
+
+	for _, tt := range tests {
+		a := newTestApp(t)
+		path := writeTestFile(t, tt.file, tt.content)
+		if _, err := runCommand(t, a, "import", path); err != nil {
+			t.Fatalf("import %s: %v", tt.file, err)
+		}
+		if got := vaultNames(t, a); !slices.Equal(got, []string{tt.want}) {
+			t.Errorf("importing %s added %v, want %s", tt.file, got, tt.want)
+		}
+	}
+
+	// A .gz file that is not gzipped is rejected
+	path := writeTestFile(t, "import.csv.gz", csvExport)
+	if _, err := openImportFile(path); err == nil {
+		t.Error("openImportFile accepted a .gz file that is not compressed")
+	}
+}
+
+func TestImportCSVMalformedRows(t *testing.T) {
+	const content = "Name,Username,Password\n" +
+		"github,alice,hunter2\n" +
+		"broken,bob\n" +
+		"gitlab,carol,hunter3\n" +
+		"short\n"
+
+	path := writeTestFile(t, "import.csv", content)
+	data, malformed, err := importCSV(path, false)
+	if err != nil {
+		t.Fatalf("importCSV: %v", err)
+	}
+	if malformed != 2 {
+		t.Errorf("malformed = %d, want 2", malformed)
+	}
+	if len(data.Entries) != 2 || data.Entries[0].Name != "github" || data.Entries[1].Name != "gitlab" {
+		t.Errorf("imported %d entries, want github and gitlab", len(data.Entries))
+	}
+
+	_, _, err = importCSV(path, true)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("strict importCSV: err = %v, want the first malformed line", err)
+	}
+
+	// A strict import that fails adds nothing to the vault
+	a := newTestApp(t)
+	if _, err := runCommand(t, a, "import", path, "--strict"); err == nil {
+		t.Fatal("import --strict accepted malformed rows")
+	}
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("failed strict import added %d entries", len(entries))
+	}
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"json object", `{"version": "1.0", "entries": []}`, "json", false},
+		{"json after blank lines", "\n  \n[]", "json", false},
+		{"json with bom", "\ufeff{}", "json", false},
+		{"csv", "Name,Username,Password\ngithub,alice,hunter2\n", "csv", false},
+		{"csv after comments", "# Exported by passio\nName,Password\n", "csv", false},
+		{"bitwarden csv", "folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n", "", true},
+		{"chrome csv", "name,url,username,password\n", "", true},
+		{"plain text", "hunter2\n", "", true},
+		{"empty", "", "", true},
+		{"only comments", "# nothing here\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "import", tt.content)
+			got, err := detectImportFormat(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectImportFormat: err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectImportFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportDetectsFormat(t *testing.T) {
+	a := newTestApp(t)
+	path := writeTestFile(t, "passwords.txt", "Name,Username,Password\ngithub,alice,hunter2\n")
+
+	output, err := runCommand(t, a, "import", path)
+	if err != nil {
+		t.Fatalf("import without --format: %v", err)
+	}
+	if !strings.Contains(output, "Detected format: csv") {
+		t.Errorf("import did not report the detected format:\n%s", output)
+	}
+	if _, err := a.Storage.GetEntry("github"); err != nil {
+		t.Errorf("imported entry is missing: %v", err)
+	}
+}
+
+func TestExportCSVColumnsRoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := &ExportData{
+		ExportDate: created,
+		Entries: []*ExportEntry{
+			{Name: "github", Username: "alice", Password: []byte("s3cret,pw"), URL: "https://github.com", Notes: "#work", Tags: []string{"dev", "git"}, CreatedAt: created, UpdatedAt: created},
+			{Name: "mail", Username: "bob", Password: []byte("hunter2"), URL: "https://mail.example.com"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		columns string
+	}{
+		{"all columns", strings.Join(csvColumnNames, ",")},
+		{"subset", "Name,Password"},
+		{"reordered", "URL,Password,name,Username,Tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := parseCSVColumns(tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), "export.csv")
+			if err := exportCSV(path, data, columns); err != nil {
+				t.Fatalf("exportCSV: %v", err)
+			}
+
+			imported, malformed, err := importCSV(path, true)
+			if err != nil {
+				t.Fatalf("importCSV: %v", err)
+			}
+			if malformed != 0 {
+				t.Errorf("malformed = %d, want 0", malformed)
+			}
+			if len(imported.Entries) != len(data.Entries) {
+				t.Fatalf("imported %d entries, want %d", len(imported.Entries), len(data.Entries))
+			}
+
+			for i, got := range imported.Entries {
+				want := data.Entries[i]
+				if got.Name != want.Name {
+					t.Errorf("Name = %q, want %q", got.Name, want.Name)
+				}
+				if string(got.Password) != string(want.Password) {
+					t.Errorf("%s: Password = %q, want %q", want.Name, got.Password, want.Password)
+				}
+				if slices.Contains(columns, "URL") && got.URL != want.URL {
+					t.Errorf("%s: URL = %q, want %q", want.Name, got.URL, want.URL)
+				}
+				if slices.Contains(columns, "Username") && got.Username != want.Username {
+					t.Errorf("%s: Username = %q, want %q", want.Name, got.Username, want.Username)
+				}
+				if !slices.Contains(columns, "URL") && got.URL != "" {
+					t.Errorf("%s: URL = %q without a URL column", want.Name, got.URL)
+				}
+				if slices.Contains(columns, "Notes") && got.Notes != want.Notes {
+					t.Errorf("%s: Notes = %q, want %q", want.Name, got.Notes, want.Notes)
+				}
+				if slices.Contains(columns, "Created") && !got.CreatedAt.Equal(want.CreatedAt) {
+					t.Errorf("%s: CreatedAt = %v, want %v", want.Name, got.CreatedAt, want.CreatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestImportCSVRejectsUnknownHeader(t *testing.T) {
+	tests := map[string]string{
+		"unknown column": "Name,Secret\ngithub,hunter2\n",
+		"no name":        "Username,Password\nalice,hunter2\n",
+		"duplicate":      "Name,Password,password\ngithub,a,b\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeTestFile(t, "import.csv", content)
+			if _, _, err := importCSV(path, false); err == nil {
+				t.Fatal("importCSV accepted an unsupported header")
+			}
+		})
+	}
+}
+
+func TestExportJSONRoundTripsMetadata(t *testing.T) {
+	a := newTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	entry.Aliases = []string{"gh", "hub"}
+	deleteAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	entry.DeleteAt = &deleteAt
+	if err := a.Storage.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	if _, err := runCommand(t, a, "export", "--output", path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if err := a.Storage.DeleteEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(t, a, "import", path); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	imported, err := a.Storage.GetEntry("gh")
+	if err != nil {
+		t.Fatalf("GetEntry by alias after import: %v", err)
+	}
+	if !slices.Equal(imported.Aliases, entry.Aliases) {
+		t.Errorf("Aliases = %v, want %v", imported.Aliases, entry.Aliases)
+	}
+	if imported.DeleteAt == nil || !imported.DeleteAt.Equal(deleteAt) {
+		t.Errorf("DeleteAt = %v, want %v", imported.DeleteAt, deleteAt)
+	}
+}
+
+func TestEntryContentHash(t *testing.T) {
+	base := entryContentHash("github", "alice", "hunter2", "https://github.com")
+	if entryContentHash("github", "alice", "hunter2", "https://github.com") != base {
+		t.Error("entryContentHash differs for identical content")
+	}
+
+	for _, fields := range [][4]string{
+		{"gitlab", "alice", "hunter2", "https://github.com"},
+		{"github", "bob", "hunter2", "https://github.com"},
+		{"github", "alice", "hunter3", "https://github.com"},
+		{"github", "alice", "hunter2", ""},
+		// Fields are separated, so content cannot move between them
+		{"githu", "balice", "hunter2", "https://github.com"},
+	} {
+		if entryContentHash(fields[0], fields[1], fields[2], fields[3]) == base {
+			t.Errorf("entryContentHash%q equals the hash of different content", fields)
+		}
+	}
+}
+
+func TestImportUnchangedEntries(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	// Importing the same content again is not a conflict
+	path := writeTestFile(t, "import.csv", "Name,Username,Password\ngithub,user,hunter2\ngitlab,user,hunter3\n")
+	for range 2 {
+		output, err := runCommand(t, a, "import", path)
+		if err != nil {
+			t.Fatalf("import: %v", err)
+		}
+		if !strings.Contains(output, "- Unchanged: ") {
+			t.Errorf("import did not report unchanged entries:\n%s", output)
+		}
+	}
+	if got, want := vaultNames(t, a), []string{"github", "gitlab"}; !slices.Equal(got, want) {
+		t.Errorf("vault holds %v after importing twice, want %v", got, want)
+	}
+
+	// Encrypted exports of the vault are unchanged too
+	export := filepath.Join(t.TempDir(), "export.json")
+	if _, err := runCommand(t, a, "export", "--output", export); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runCommand(t, a, "import", export)
+	if err != nil {
+		t.Fatalf("importing an export of the vault: %v", err)
+	}
+	if !strings.Contains(output, "- Imported: 0 entries") || !strings.Contains(output, "- Unchanged: 2 entries") {
+		t.Errorf("importing an export of the vault reported:\n%s", output)
+	}
+
+	// A different password under the same name still conflicts
+	changed := writeTestFile(t, "import.csv", "Name,Username,Password\ngithub,user,hunter4\n")
+	if _, err := runCommand(t, a, "import", changed); errs.ExitCode(err) != errs.ExitConflict {
+		t.Errorf("importing a changed entry: err = %v, want conflict", err)
+	}
+}
+
+func TestImportFolderScopeDuplicates(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.NameUniqueness = string(storage.NameScopeFolder)
+	if err := a.Storage.SetNameScope(storage.NameScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []string{"work", "personal"} {
+		entry := addTestEntry(t, a, "github", "hunter2")
+		entry.Folder = folder
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := writeTestFile(t, "export.json", `{"entries": [
+		{"name": "github", "folder": "work", "password": "`+base64.StdEncoding.EncodeToString([]byte("changed"))+`"},
+		{"name": "github", "folder": "oss", "password": "`+base64.StdEncoding.EncodeToString([]byte("hunter3"))+`"}
+	]}`)
+
+	if _, err := runCommand(t, a, "import", path, "--skip-duplicates"); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	entries, err := a.Storage.FindEntries("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var folders []string
+	for _, entry := range entries {
+		folders = append(folders, entry.Folder)
+	}
+	if want := []string{"oss", "personal", "work"}; !slices.Equal(folders, want) {
+		t.Fatalf("github entries in folders %v, want %v", folders, want)
+	}
+
+	// Without --skip-duplicates the entry in the same folder is a conflict
+	if _, err := runCommand(t, a, "import", path); errs.ExitCode(err) != errs.ExitConflict {
+		t.Fatalf("import of a duplicate in the same folder: err = %v, want a conflict", err)
+	}
+}
+
+// foreignExport returns a vault and an encrypted export of its github entry
+// taken before its master password was changed, so that the export is
+// encrypted with a key the vault no longer has.
+func foreignExport(t *testing.T) (*app.App, string) {
+	t.Helper()
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	if _, err := runCommand(t, a, "export", "--output", path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if err := a.Storage.DeleteEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+
+	stubPasswords(t, testMasterPassword, newMasterPassword, newMasterPassword)
+	if _, err := runCommand(t, a, "changepw"); err != nil {
+		t.Fatalf("changepw: %v", err)
+	}
+	return a, path
+}
+
+func TestImportForeignKeyExport(t *testing.T) {
+	a, path := foreignExport(t)
+
+	stubPassword(t, testMasterPassword)
+	if _, err := runCommand(t, a, "import", path, "--decrypt"); err != nil {
+		t.Fatalf("import --decrypt: %v", err)
+	}
+
+	entry, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := a.DecryptPassword(entry.Password); err != nil || password != "hunter2" {
+		t.Errorf("DecryptPassword() = %q, %v, want the password re-encrypted under the vault's key", password, err)
+	}
+}
+
+func TestImportForeignKeyExportErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		password string
+	}{
+		{"without --decrypt", nil, ""},
+		{"wrong source password", []string{"--decrypt"}, "wrong password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, path := foreignExport(t)
+
+			stubPassword(t, tt.password)
+			if _, err := runCommand(t, a, append([]string{"import", path}, tt.args...)...); errs.ExitCode(err) != errs.ExitInvalidInput {
+				t.Fatalf("import: err = %v, want invalid input", err)
+			}
+			if _, err := a.Storage.GetEntry("github"); err == nil {
+				t.Error("the entry was imported")
+			}
+		})
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  map[string]int
+		content   string
+		wantError string
+	}{
+		{"within limits", map[string]int{"max_import_entries": 2},
+			"Name,Username,Password,URL,Notes\ngithub,alice,hunter2,,\ngitlab,bob,hunter3,,\n", ""},
+		{"too many entries", map[string]int{"max_import_entries": 1},
+			"Name,Username,Password,URL,Notes\ngithub,alice,hunter2,,\ngitlab,bob,hunter3,,\n", "more than the limit of 1"},
+		{"notes too long", map[string]int{"max_notes_length": 8},
+			"Name,Username,Password,URL,Notes\ngithub,alice,hunter2,,short\ngitlab,bob,hunter3,,far too long\n", "invalid entry gitlab"},
+		{"password too long", map[string]int{"max_password_length": 4},
+			"Name,Username,Password,URL,Notes\ngithub,alice,hunter2,,\n", "invalid entry github"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			for key, value := range tt.settings {
+				if err := a.Config.SetConfigValue(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := writeTestFile(t, "import.csv", tt.content)
+
+			_, err := runCommand(t, a, "import", path)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if errs.ExitCode(err) != errs.ExitInvalidInput || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("import: err = %v, want invalid input mentioning %q", err, tt.wantError)
+			}
+			if names := vaultNames(t, a); len(names) != 0 {
+				t.Errorf("failed import added %v", names)
+			}
+		})
+	}
+}
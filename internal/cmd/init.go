@@ -3,44 +3,85 @@ package cmd
 import (
 	"crypto/rand"
 	"fmt"
-	"syscall"
+	"math"
+	"strings"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 )
 
 func newInitCmd(app *app.App) *cobra.Command {
-	var force bool
+	var (
+		force          bool
+		generateMaster bool
+		kdf            kdfOptions
+	)
 
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize Passio",
-		Long:  "Initialize Passio by creating a new password database.",
+		Long: `Initialize Passio by creating a new password database.
+
+Use --generate-master to have a strong diceware passphrase generated and used
+as the master password. It is displayed only once and you must confirm that
+you have saved it before initialization continues.
+
+The master key is derived with PBKDF2 by default, with 600000 iterations as
+recommended by OWASP. Use --kdf argon2id to use
+Argon2id instead, which is much harder to brute-force with GPUs. Its cost can
+be tuned with --kdf-iterations, --kdf-memory and --kdf-parallelism, or with
+--argon-memory in MiB and --argon-time, for example:
+
+  pm init --kdf argon2id --argon-memory 64 --argon-time 3
+
+The chosen parameters are stored in the config and used on every unlock.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsInitialized() && !force {
-				return fmt.Errorf("passio is already initialized. Use --force to reinitialize")
+				return errs.Conflict("passio is already initialized. Use --force to reinitialize")
 			}
 
-			masterPass, err := getMasterPassword()
+			kdfParams, err := kdf.params(cmd, crypto.KDFParams{Algorithm: crypto.KDFPBKDF2})
 			if err != nil {
-				return fmt.Errorf("failed to get master password: %w", err)
+				return err
+			}
+
+			var masterPass string
+			if generateMaster {
+				masterPass, err = generateMasterPassword()
+				if err != nil {
+					return errs.Internal("failed to generate master password: %w", err)
+				}
+				if !confirmMasterPasswordSaved(masterPass) {
+					fmt.Println("Initialization cancelled")
+					return nil
+				}
+			} else {
+				masterPass, err = getMasterPassword()
+				if err != nil {
+					return errs.Internal("failed to get master password: %w", err)
+				}
 			}
 
 			// Generate salt
-			salt, err := generateSalt()
+			salt, err := generateSalt(crypto.SaltLength)
 			if err != nil {
-				return fmt.Errorf("failed to generate salt: %w", err)
+				return errs.Internal("failed to generate salt: %w", err)
 			}
 
-			masterKey := app.Encryption.DeriveKey(masterPass, salt)
+			masterKey, err := app.Encryption.DeriveKeyWithParams(masterPass, salt, kdfParams)
+			if err != nil {
+				return errs.Internal("failed to derive master key: %w", err)
+			}
 
+			app.Config.SetKDFParams(kdfParams)
 			if err := app.Config.SetMasterKey(masterKey, salt); err != nil {
-				return fmt.Errorf("failed to set master key: %w", err)
+				return errs.Internal("failed to set master key: %w", err)
 			}
 
 			if err := app.Storage.Initialize(); err != nil {
-				return fmt.Errorf("failed to initialize storage: %w", err)
+				return storageError("failed to initialize storage", err)
 			}
 
 			fmt.Println("Passio initialized successfully!!")
@@ -49,37 +90,130 @@ func newInitCmd(app *app.App) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force reinitialization")
+	cmd.Flags().BoolVar(&generateMaster, "generate-master", false, "Generate a strong master passphrase")
+	kdf.addFlags(cmd, "pbkdf2")
 	return cmd
 }
 
+// kdfOptions are the flags choosing the key derivation function of a new
+// master key and its parameters.
+type kdfOptions struct {
+	algorithm   string
+	iterations  uint32
+	memory      uint32
+	parallelism uint8
+	argonMemory uint32 // MiB
+	argonTime   uint32
+}
+
+func (o *kdfOptions) addFlags(cmd *cobra.Command, defaultAlgorithm string) {
+	cmd.Flags().StringVar(&o.algorithm, "kdf", defaultAlgorithm, "Key derivation function of the master key (pbkdf2 or argon2id)")
+	cmd.Flags().Uint32Var(&o.iterations, "kdf-iterations", 0, "PBKDF2 iterations or Argon2id passes (default depends on --kdf)")
+	cmd.Flags().Uint32Var(&o.memory, "kdf-memory", 0, "Argon2id memory in KiB (default 65536)")
+	cmd.Flags().Uint8Var(&o.parallelism, "kdf-parallelism", 0, "Argon2id threads (default 4)")
+	cmd.Flags().Uint32Var(&o.argonMemory, "argon-memory", 0, "Argon2id memory in MiB (default 64)")
+	cmd.Flags().Uint32Var(&o.argonTime, "argon-time", 0, "Argon2id passes (default 3)")
+	cmd.MarkFlagsMutuallyExclusive("argon-memory", "kdf-memory")
+	cmd.MarkFlagsMutuallyExclusive("argon-time", "kdf-iterations")
+}
+
+// params returns the key derivation parameters selected by the flags. Unless
+// --kdf selects another algorithm, parameters that were not given are taken
+// from current.
+func (o *kdfOptions) params(cmd *cobra.Command, current crypto.KDFParams) (crypto.KDFParams, error) {
+	params := current.Normalized()
+	if cmd.Flags().Changed("kdf") && o.algorithm != params.Algorithm {
+		var err error
+		params, err = crypto.DefaultKDFParams(o.algorithm)
+		if err != nil {
+			return params, errs.InvalidInput("%w", err)
+		}
+	}
+
+	if cmd.Flags().Changed("kdf-iterations") {
+		params.Iterations = o.iterations
+	}
+	if cmd.Flags().Changed("kdf-memory") {
+		params.Memory = o.memory
+	}
+	if cmd.Flags().Changed("kdf-parallelism") {
+		params.Parallelism = o.parallelism
+	}
+	if cmd.Flags().Changed("argon-memory") || cmd.Flags().Changed("argon-time") {
+		if params.Algorithm != crypto.KDFArgon2id {
+			return params, errs.InvalidInput("--argon-memory and --argon-time need --kdf %s", crypto.KDFArgon2id)
+		}
+		if cmd.Flags().Changed("argon-memory") {
+			if o.argonMemory > math.MaxUint32/1024 {
+				return params, errs.InvalidInput("--argon-memory is too large: %d MiB", o.argonMemory)
+			}
+			params.Memory = o.argonMemory * 1024
+		}
+		if cmd.Flags().Changed("argon-time") {
+			params.Iterations = o.argonTime
+		}
+	}
+
+	if err := params.Validate(); err != nil {
+		return params, errs.InvalidInput("invalid key derivation parameters: %w", err)
+	}
+	return params, nil
+}
+
 func getMasterPassword() (string, error) {
-	fmt.Print("Enter master password: ")
-	masterPass, err := term.ReadPassword(int(syscall.Stdin))
+	return promptNewMasterPassword("master password")
+}
+
+// promptNewMasterPassword prompts for a new master password, described by
+// label, and its confirmation.
+func promptNewMasterPassword(label string) (string, error) {
+	fmt.Printf("Enter %s: ", label)
+	masterPass, err := readPassword()
 	if err != nil {
 		return "", err
 	}
-	fmt.Println()
 
-	fmt.Print("Confirm master password: ")
-	confirmPass, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Printf("Confirm %s: ", label)
+	confirmPass, err := readPassword()
 	if err != nil {
 		return "", err
 	}
-	fmt.Println()
 
-	if string(masterPass) != string(confirmPass) {
-		return "", fmt.Errorf("passwords do not match")
+	if masterPass != confirmPass {
+		return "", errs.InvalidInput("passwords do not match")
 	}
 
 	if len(masterPass) < 8 {
-		return "", fmt.Errorf("master password must be at least 8 characters long")
+		return "", errs.InvalidInput("master password must be at least 8 characters long")
 	}
 
-	return string(masterPass), nil
+	return masterPass, nil
+}
+
+// masterPassphraseWords is the number of diceware words in a generated master
+// password, roughly 77 bits of entropy with the EFF large wordlist.
+const masterPassphraseWords = 6
+
+func generateMasterPassword() (string, error) {
+	return generatePassphrase(masterPassphraseWords)
+}
+
+// confirmMasterPasswordSaved displays the generated master password once and
+// asks the user to confirm that they have stored it somewhere safe.
+func confirmMasterPasswordSaved(masterPass string) bool {
+	fmt.Println("Your generated master password is:")
+	fmt.Printf("\n    %s\n\n", masterPass)
+	fmt.Println("WARNING: This password will not be shown again and cannot be recovered.")
+	fmt.Println("Store it somewhere safe before continuing.")
+	fmt.Print("Type 'yes' to confirm you have saved it: ")
+
+	var response string
+	fmt.Scanln(&response)
+	return strings.ToLower(strings.TrimSpace(response)) == "yes"
 }
 
-func generateSalt() ([]byte, error) {
-	salt := make([]byte, 32)
+func generateSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
 	_, err := rand.Read(salt)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+// stubStdin makes os.Stdin read input for the rest of the test.
+func stubStdin(t *testing.T, input string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = original
+		file.Close()
+	})
+}
+
+func TestGenerateMasterPassword(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 20 {
+		password, err := generateMasterPassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A few words of the wordlist contain hyphens themselves
+		words := strings.Split(password, "-")
+		if len(words) < masterPassphraseWords {
+			t.Fatalf("master password %q has %d words, want %d", password, len(words), masterPassphraseWords)
+		}
+		for _, word := range words {
+			if word == "" {
+				t.Fatalf("master password %q has an empty word", password)
+			}
+		}
+		if seen[password] {
+			t.Fatalf("master password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
+
+func TestInitGenerateMaster(t *testing.T) {
+	tests := []struct {
+		confirm     string
+		initialized bool
+	}{
+		{"yes\n", true},
+		{"YES\n", true},
+		{"no\n", false},
+		{"\n", false},
+	}
+
+	for _, tt := range tests {
+		a := newTestApp(t)
+		previous := append([]byte(nil), a.Config.Salt...)
+		stubStdin(t, tt.confirm)
+
+		output, err := runCommand(t, a, "init", "--force", "--generate-master")
+		if err != nil {
+			t.Fatalf("init --generate-master confirming %q: %v", tt.confirm, err)
+		}
+
+		// The passphrase is the only indented line of the output
+		var password string
+		for _, line := range strings.Split(output, "\n") {
+			if strings.HasPrefix(line, "    ") {
+				password = strings.TrimSpace(line)
+			}
+		}
+		if strings.Count(password, "-") < masterPassphraseWords-1 {
+			t.Fatalf("init --generate-master printed no passphrase:\n%s", output)
+		}
+
+		reinitialized := string(a.Config.Salt) != string(previous)
+		if reinitialized != tt.initialized {
+			t.Errorf("confirming %q reinitialized the vault: %v, want %v", tt.confirm, reinitialized, tt.initialized)
+		}
+		if tt.initialized {
+			if err := a.Unlock(password); err != nil {
+				t.Errorf("unlocking with the generated master password: %v", err)
+			}
+		} else if !strings.Contains(output, "Initialization cancelled") {
+			t.Errorf("init did not report the cancellation:\n%s", output)
+		}
+	}
+}
+
+func TestKDFOptions(t *testing.T) {
+	pbkdf2 := crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.PBKDF2Iterations}
+	argon2id, err := crypto.DefaultKDFParams(crypto.KDFArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuned := crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: 2, Memory: 1024, Parallelism: 2}
+
+	tests := []struct {
+		name             string
+		defaultAlgorithm string
+		current          crypto.KDFParams
+		args             []string
+		want             crypto.KDFParams
+		wantErr          bool
+	}{
+		{"init default", "pbkdf2", crypto.KDFParams{Algorithm: crypto.KDFPBKDF2}, nil, pbkdf2, false},
+		{"init argon2id", "pbkdf2", crypto.KDFParams{Algorithm: crypto.KDFPBKDF2}, []string{"--kdf", "argon2id"}, argon2id, false},
+		{"init tuned argon2id", "pbkdf2", crypto.KDFParams{Algorithm: crypto.KDFPBKDF2},
+			[]string{"--kdf", "argon2id", "--kdf-iterations", "2", "--kdf-memory", "1024", "--kdf-parallelism", "2"}, tuned, false},
+		{"rekey keeps the current parameters", "", tuned, nil, tuned, false},
+		{"rekey to the same algorithm", "", tuned, []string{"--kdf", "argon2id"}, tuned, false},
+		{"rekey to pbkdf2", "", tuned, []string{"--kdf", "pbkdf2"}, pbkdf2, false},
+		{"rekey legacy vault", "", crypto.KDFParams{}, []string{"--kdf-iterations", "700000"},
+			crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: 700000}, false},
+		{"unknown algorithm", "pbkdf2", crypto.KDFParams{}, []string{"--kdf", "scrypt"}, crypto.KDFParams{}, true},
+		{"too few iterations", "pbkdf2", crypto.KDFParams{}, []string{"--kdf-iterations", "100"}, crypto.KDFParams{}, true},
+		{"memory with pbkdf2", "pbkdf2", crypto.KDFParams{}, []string{"--kdf-memory", "1024"}, crypto.KDFParams{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kdf kdfOptions
+			cmd := &cobra.Command{}
+			kdf.addFlags(cmd, tt.defaultAlgorithm)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := kdf.params(cmd, tt.current)
+			if tt.wantErr {
+				if errs.ExitCode(err) != errs.ExitInvalidInput {
+					t.Errorf("params(%v) = %+v, %v, want invalid input", tt.args, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("params(%v) = %+v, %v, want %+v", tt.args, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgonOptions(t *testing.T) {
+	argon2id, err := crypto.DefaultKDFParams(crypto.KDFArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuned := crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: 2, Memory: 128 * 1024, Parallelism: argon2id.Parallelism}
+
+	tests := []struct {
+		name    string
+		current crypto.KDFParams
+		args    []string
+		want    crypto.KDFParams
+		wantErr bool
+	}{
+		{"memory and time", crypto.KDFParams{}, []string{"--kdf", "argon2id", "--argon-memory", "128", "--argon-time", "2"}, tuned, false},
+		{"memory only", crypto.KDFParams{}, []string{"--kdf", "argon2id", "--argon-memory", "128"},
+			crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: argon2id.Iterations, Memory: 128 * 1024, Parallelism: argon2id.Parallelism}, false},
+		{"rekey argon2id vault", tuned, []string{"--argon-time", "4"},
+			crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: 4, Memory: 128 * 1024, Parallelism: argon2id.Parallelism}, false},
+		{"pbkdf2", crypto.KDFParams{}, []string{"--argon-memory", "128"}, crypto.KDFParams{}, true},
+		{"memory too large", crypto.KDFParams{}, []string{"--kdf", "argon2id", "--argon-memory", "4194304"}, crypto.KDFParams{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kdf kdfOptions
+			cmd := &cobra.Command{}
+			kdf.addFlags(cmd, "pbkdf2")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := kdf.params(cmd, tt.current)
+			if tt.wantErr {
+				if errs.ExitCode(err) != errs.ExitInvalidInput {
+					t.Errorf("params(%v) = %+v, %v, want invalid input", tt.args, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("params(%v) = %+v, %v, want %+v", tt.args, got, err, tt.want)
+			}
+		})
+	}
+
+	// The MiB and KiB flags set the same parameters
+	for _, args := range [][]string{
+		{"--argon-memory", "64", "--kdf-memory", "65536"},
+		{"--argon-time", "2", "--kdf-iterations", "2"},
+	} {
+		var kdf kdfOptions
+		cmd := &cobra.Command{}
+		kdf.addFlags(cmd, "argon2id")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ValidateFlagGroups(); err == nil {
+			t.Errorf("%v: both flags were accepted", args)
+		}
+	}
+}
+
+func TestRekeyToArgon2id(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	entry := addTestEntry(t, a, "github", "hunter2")
+
+	_, err := runCommand(t, a, "rekey", "--kdf", "argon2id", "--kdf-iterations", "1", "--kdf-memory", "64", "--kdf-parallelism", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Iterations: 1, Memory: 64, Parallelism: 1}
+	if got := a.Config.KDFParams(); got != want {
+		t.Errorf("KDF parameters = %+v, want %+v", got, want)
+	}
+
+	a.Lock()
+	if err := a.Unlock(testMasterPassword); err != nil {
+		t.Fatalf("unlocking the rekeyed vault: %v", err)
+	}
+	rekeyed, err := a.Storage.GetEntry(entry.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := a.DecryptPassword(rekeyed.Password); err != nil || password != "hunter2" {
+		t.Errorf("decrypted %q, %v after rekeying, want hunter2", password, err)
+	}
+}
@@ -9,32 +9,69 @@ import (
 	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 func newListCmd(app *app.App) *cobra.Command {
 	var (
-		filter   string
-		sortBy   string
-		showAll  bool
-		showTags bool
+		filter    string
+		sortBy    string
+		showAll   bool
+		showTags  bool
+		format    string
+		groupBy   string
+		olderThan string
+		newerThan string
+		tagsAll   []string
+		tagsAny   []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all password entries",
 		Long: `List all password entries in a tabular format.
-Entries can be filtered and sorted based on various criteria.`,
+Entries can be filtered and sorted based on various criteria.
+
+Use --format wide to also show the days since each password was last changed,
+its tags and whether it has expired.
+
+Use --group-by tag or --group-by folder to list entries under a header per
+group. Entries with several tags are listed under each of them.
+
+Use --older-than and --newer-than to only list entries last modified more or
+less than a duration ago, e.g. --older-than 90d. Durations accept d (days),
+w (weeks), m (30-day months) and y (365-day years).
+
+Use --tags-all work,email to only list entries with every one of the tags, or
+--tags-any work,email to list entries with at least one of them. Tags must
+match in full, ignoring case.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("passio is locked. Please unlock first")
+				return errLocked
+			}
+
+			if format != "compact" && format != "wide" {
+				return errs.InvalidInput("unsupported format: %s", format)
+			}
+			wide := format == "wide"
+
+			if groupBy != "" && groupBy != "tag" && groupBy != "folder" {
+				return errs.InvalidInput("unsupported grouping: %s", groupBy)
+			}
+
+			ages, err := parseAgeFilter(olderThan, newerThan)
+			if err != nil {
+				return err
 			}
 
 			entries, err := app.Storage.ListEntries()
 			if err != nil {
-				return fmt.Errorf("failed to list entries: %w", err)
+				return storageError("failed to list entries", err)
 			}
+			entries = ages.apply(entries, time.Now())
+			entries = filterByTags(entries, tagsAll, tagsAny)
 
 			if filter != "" {
 				filtered := make([]*storage.Entry, 0)
@@ -54,49 +91,82 @@ Entries can be filtered and sorted based on various criteria.`,
 
 			sortEntries(entries, sortBy)
 
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			anyExpired := false
+			printTable := func(entries []*storage.Entry) {
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
-			headers := []string{"Name", "Username", "URL", "Created", "Last Modified"}
-			if showTags {
-				headers = append(headers, "Tags")
-			}
-			fmt.Fprintln(w, strings.Join(headers, "\t"))
-			fmt.Fprintln(w, strings.Repeat("-", 80))
+				headers := []string{"Name", "Username", "URL", "Created", "Last Modified"}
+				if wide {
+					headers = append(headers, "Age (days)", "Tags", "Expired")
+				} else if showTags {
+					headers = append(headers, "Tags")
+				}
+				fmt.Fprintln(w, strings.Join(headers, "\t"))
+				fmt.Fprintln(w, strings.Repeat("-", 80))
 
-			for _, entry := range entries {
+				for _, entry := range entries {
 
-				// Format dates
-				created := entry.CreatedAt.Format("2006-01-02")
-				modified := entry.UpdatedAt.Format("2006-01-02")
+					// Format dates
+					created := entry.CreatedAt.Format("2006-01-02")
+					modified := entry.UpdatedAt.Format("2006-01-02")
 
-				// Check password age
-				passwordAge := time.Since(entry.UpdatedAt).Hours() / 24
-				ageIndicator := " "
-				if passwordAge > float64(app.Config.PasswordExpiration) {
-					ageIndicator = "!" // Indicate old password
-				}
+					// Check password age
+					passwordAge := app.AgeDays(entry)
+					expired := app.IsExpired(entry)
+					ageIndicator := " "
+					if expired && !wide {
+						ageIndicator = "!" // Indicate expired password
+						anyExpired = true
+					}
 
-				// Format row
-				row := []string{
-					ageIndicator + entry.Name,
-					entry.Username,
-					entry.URL,
-					created,
-					modified,
-				}
+					// Format row
+					row := []string{
+						ageIndicator + entry.Name,
+						entry.Username,
+						entry.URL,
+						created,
+						modified,
+					}
 
-				if showTags {
-					row = append(row, strings.Join(entry.Tags, ", "))
+					if wide {
+						expiredFlag := "no"
+						if expired {
+							expiredFlag = "yes"
+						}
+						row = append(row, fmt.Sprintf("%.0f", passwordAge), strings.Join(entry.Tags, ", "), expiredFlag)
+					} else if showTags {
+						row = append(row, strings.Join(entry.Tags, ", "))
+					}
+
+					fmt.Fprintln(w, strings.Join(row, "\t"))
 				}
 
-				fmt.Fprintln(w, strings.Join(row, "\t"))
+				w.Flush()
 			}
 
-			w.Flush()
+			if groupBy == "" {
+				printTable(entries)
+			} else {
+				groups := groupEntries(entries, groupBy)
+
+				names := make([]string, 0, len(groups))
+				for name := range groups {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				for i, name := range names {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("%s (%d)\n", name, len(groups[name]))
+					printTable(groups[name])
+				}
+			}
 
 			fmt.Printf("\nTotal entries: %d\n", len(entries))
-			if app.Config.PasswordExpiration > 0 {
-				fmt.Println("! indicates password older than configured expiration period")
+			if anyExpired {
+				fmt.Println("! indicates an expired password")
 			}
 
 			return nil
@@ -108,10 +178,74 @@ Entries can be filtered and sorted based on various criteria.`,
 	cmd.Flags().StringVarP(&sortBy, "sort", "s", "name", "Sort entries by: name, username, created, modified")
 	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Show all entry details")
 	cmd.Flags().BoolVarP(&showTags, "tags", "t", false, "Show entry tags")
+	cmd.Flags().StringVar(&format, "format", "compact", "Table format (compact or wide)")
+	cmd.Flags().StringVar(&groupBy, "group-by", "", "Group entries by tag or folder")
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Only list entries last modified longer ago than this, e.g. 90d")
+	cmd.Flags().StringVar(&newerThan, "newer-than", "", "Only list entries last modified more recently than this, e.g. 2w")
+	cmd.Flags().StringSliceVar(&tagsAll, "tags-all", nil, "Only list entries with all of these tags")
+	cmd.Flags().StringSliceVar(&tagsAny, "tags-any", nil, "Only list entries with at least one of these tags")
 
 	return cmd
 }
 
+// groupEntries groups entries by folder or by each of their tags.
+func groupEntries(entries []*storage.Entry, groupBy string) map[string][]*storage.Entry {
+	groups := make(map[string][]*storage.Entry)
+
+	for _, entry := range entries {
+		switch groupBy {
+		case "folder":
+			folder := entry.Folder
+			if folder == "" {
+				folder = "(no folder)"
+			}
+			groups[folder] = append(groups[folder], entry)
+		case "tag":
+			if len(entry.Tags) == 0 {
+				groups["(untagged)"] = append(groups["(untagged)"], entry)
+			}
+			for _, tag := range entry.Tags {
+				groups[tag] = append(groups[tag], entry)
+			}
+		}
+	}
+
+	return groups
+}
+
+// filterByTags returns the entries that have every tag in all and, if any is
+// not empty, at least one tag in any. Tags are compared ignoring case.
+func filterByTags(entries []*storage.Entry, all, any []string) []*storage.Entry {
+	if len(all) == 0 && len(any) == 0 {
+		return entries
+	}
+
+	filtered := make([]*storage.Entry, 0)
+	for _, entry := range entries {
+		matchesAll := true
+		for _, tag := range all {
+			if !hasTag(entry.Tags, strings.ToLower(strings.TrimSpace(tag))) {
+				matchesAll = false
+				break
+			}
+		}
+
+		matchesAny := len(any) == 0
+		for _, tag := range any {
+			if hasTag(entry.Tags, strings.ToLower(strings.TrimSpace(tag))) {
+				matchesAny = true
+				break
+			}
+		}
+
+		if matchesAll && matchesAny {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
 func containsTag(tags []string, search string) bool {
 	for _, tag := range tags {
 		if strings.Contains(strings.ToLower(tag), search) {
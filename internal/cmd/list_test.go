@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// columnSeparator separates the columns of a table printed by tabwriter
+var columnSeparator = regexp.MustCompile(`\s{2,}`)
+
+// tableRows returns the cells of each row of the tables in a list output,
+// keyed by the first cell.
+func tableRows(output string) map[string][]string {
+	rows := make(map[string][]string)
+	inTable := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "----"):
+			inTable = true
+		case strings.TrimSpace(line) == "":
+			inTable = false
+		case inTable:
+			cells := columnSeparator.Split(strings.TrimSpace(line), -1)
+			rows[cells[0]] = cells
+		}
+	}
+	return rows
+}
+
+// addListEntry adds an entry whose password was changed days ago.
+func addListEntry(t *testing.T, a *app.App, name, folder string, days int, tags ...string) {
+	t.Helper()
+	encrypted, err := a.EncryptPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry(name, "alice", encrypted)
+	entry.URL = "https://" + name + ".example.com"
+	entry.Folder = folder
+	entry.Tags = tags
+	entry.PasswordChangedAt = time.Now().AddDate(0, 0, -days)
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListWide(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 90
+	addListEntry(t, a, "fresh", "", 10, "work", "email")
+	addListEntry(t, a, "stale", "", 100, "home")
+
+	output, err := runCommand(t, a, "list", "--format", "wide")
+	if err != nil {
+		t.Fatalf("list --format wide: %v", err)
+	}
+	if !strings.HasPrefix(output, "Name  ") || !strings.Contains(output, "Age (days)") {
+		t.Errorf("list --format wide has no age column:\n%s", output)
+	}
+
+	rows := tableRows(output)
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"fresh", []string{"10", "work, email", "no"}},
+		{"stale", []string{"100", "home", "yes"}},
+	}
+	for _, tt := range tests {
+		row := rows[tt.name]
+		if len(row) != 8 || !slices.Equal(row[5:], tt.want) {
+			t.Errorf("row of %s = %q, want age, tags and expiry %q", tt.name, row, tt.want)
+		}
+	}
+
+	// The wide format spells out expiry instead of marking names
+	if strings.Contains(output, "!stale") || strings.Contains(output, "indicates an expired password") {
+		t.Errorf("list --format wide marks expired names:\n%s", output)
+	}
+
+	output, err = runCommand(t, a, "list")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if _, ok := tableRows(output)["!stale"]; !ok || !strings.Contains(output, "! indicates an expired password") {
+		t.Errorf("list does not mark the expired entry:\n%s", output)
+	}
+
+	if _, err := runCommand(t, a, "list", "--format", "tall"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("list with an unknown format: err = %v, want invalid input", err)
+	}
+}
+
+// listGroups returns the group headers of a list --group-by output and the
+// names listed under each.
+func listGroups(output string) map[string][]string {
+	groups := make(map[string][]string)
+	var group string
+	for _, block := range strings.Split(output, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		if len(lines) < 3 || !strings.HasPrefix(lines[2], "----") {
+			continue
+		}
+		group = lines[0]
+		groups[group] = []string{}
+		for _, row := range lines[3:] {
+			groups[group] = append(groups[group], strings.Fields(row)[0])
+		}
+	}
+	return groups
+}
+
+func TestListGroupBy(t *testing.T) {
+	a := newTestApp(t)
+	addListEntry(t, a, "github", "work", 0, "dev", "work")
+	addListEntry(t, a, "gitlab", "work", 0, "dev")
+	addListEntry(t, a, "mail", "", 0)
+
+	tests := []struct {
+		groupBy string
+		want    map[string][]string
+	}{
+		{"folder", map[string][]string{
+			"(no folder) (1)": {"mail"},
+			"work (2)":        {"github", "gitlab"},
+		}},
+		{"tag", map[string][]string{
+			"(untagged) (1)": {"mail"},
+			"dev (2)":        {"github", "gitlab"},
+			"work (1)":       {"github"},
+		}},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, "list", "--group-by", tt.groupBy)
+		if err != nil {
+			t.Fatalf("list --group-by %s: %v", tt.groupBy, err)
+		}
+		got := listGroups(output)
+		if len(got) != len(tt.want) {
+			t.Errorf("list --group-by %s printed groups %v, want %v", tt.groupBy, got, tt.want)
+		}
+		for group, names := range tt.want {
+			if !slices.Equal(got[group], names) {
+				t.Errorf("list --group-by %s lists %v under %q, want %v", tt.groupBy, got[group], group, names)
+			}
+		}
+		if !strings.Contains(output, "Total entries: 3") {
+			t.Errorf("list --group-by %s does not count each entry once:\n%s", tt.groupBy, output)
+		}
+	}
+
+	if _, err := runCommand(t, a, "list", "--group-by", "username"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("list with an unknown grouping: err = %v, want invalid input", err)
+	}
+}
+
+func TestListAgeFilters(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	for name, days := range map[string]int{"new": 1, "month": 30, "old": 200} {
+		encrypted, err := a.EncryptPassword("hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := storage.NewEntry(name, "alice", encrypted)
+		entry.URL = "https://example.com"
+		entry.CreatedAt = time.Now().AddDate(0, 0, -days)
+		entry.UpdatedAt = entry.CreatedAt
+		if err := a.Storage.AddEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--older-than", "90d"}, []string{"old"}},
+		{[]string{"--newer-than", "2w"}, []string{"new"}},
+		{[]string{"--older-than", "1w", "--newer-than", "6m"}, []string{"month"}},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"list"}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("list %v: %v", tt.args, err)
+		}
+		var got []string
+		for name := range tableRows(output) {
+			got = append(got, name)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("list %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	for _, command := range []string{"list", "audit"} {
+		if _, err := runCommand(t, a, command, "--older-than", "ninety"); errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("%s with an invalid --older-than: err = %v, want invalid input", command, err)
+		}
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	entries := []*storage.Entry{
+		{Name: "github", Tags: []string{"work", "Code"}},
+		{Name: "gitlab", Tags: []string{"code"}},
+		{Name: "mail", Tags: []string{"work", "email"}},
+		{Name: "bank"},
+	}
+
+	tests := []struct {
+		name string
+		all  []string
+		any  []string
+		want []string
+	}{
+		{"no filters", nil, nil, []string{"github", "gitlab", "mail", "bank"}},
+		{"all of one tag", []string{"work"}, nil, []string{"github", "mail"}},
+		{"all of two tags", []string{"work", "code"}, nil, []string{"github"}},
+		{"any of two tags", nil, []string{"email", "code"}, []string{"github", "gitlab", "mail"}},
+		{"ignoring case and spaces", []string{" WORK "}, []string{"CODE"}, []string{"github"}},
+		{"whole tags only", []string{"wor"}, nil, nil},
+		{"unknown tag", nil, []string{"travel"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range filterByTags(entries, tt.all, tt.any) {
+				got = append(got, entry.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterByTags(%q, %q) = %v, want %v", tt.all, tt.any, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListAndSearchTagFilters(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	addListEntry(t, a, "github", "", 1, "work", "code")
+	addListEntry(t, a, "gitlab", "", 1, "code")
+	addListEntry(t, a, "gitea", "", 1, "work")
+
+	tests := []struct {
+		flags []string
+		want  []string
+	}{
+		{[]string{"--tags-all", "work,code"}, []string{"github"}},
+		{[]string{"--tags-any", "work", "--tags-any", "travel"}, []string{"gitea", "github"}},
+		{[]string{"--tags-all", "code", "--tags-any", "work"}, []string{"github"}},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"list"}, tt.flags...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed := slices.Sorted(maps.Keys(tableRows(output)))
+		if !slices.Equal(listed, tt.want) {
+			t.Errorf("list %v = %v, want %v", tt.flags, listed, tt.want)
+		}
+
+		found := searchResults(t, a, append([]string{"git", "--sort", "name"}, tt.flags...)...)
+		if !slices.Equal(found, tt.want) {
+			t.Errorf("search git %v = %v, want %v", tt.flags, found, tt.want)
+		}
+	}
+}
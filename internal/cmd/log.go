@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newLogCmd(app *app.App) *cobra.Command {
+	var clear bool
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the entry access log",
+		Long: `Show the access log of sensitive operations on entries.
+Each record holds the time, the entry name and the action (update, delete
+or reveal). Showing, viewing or copying a password is recorded as a reveal;
+getting an entry's other details is not. Secret values are never recorded.
+
+Logging is disabled by default. Enable it with 'pm config set access_log true'.
+Use --clear to remove all records and 'pm log export' to dump them as JSON.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if clear {
+				if err := app.Storage.ClearAccessLog(); err != nil {
+					return storageError("failed to clear access log", err)
+				}
+
+				fmt.Println("Access log cleared")
+				return nil
+			}
+
+			records, err := app.Storage.ListAccessLog()
+			if err != nil {
+				return storageError("failed to list access log", err)
+			}
+
+			if len(records) == 0 {
+				fmt.Println("No access records found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Time\tEntry\tAction")
+			for _, record := range records {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", record.Timestamp.Format("2006-01-02 15:04:05"), record.EntryName, record.Action)
+			}
+			w.Flush()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&clear, "clear", false, "Remove all access records")
+
+	cmd.AddCommand(newLogExportCmd(app))
+
+	return cmd
+}
+
+type AccessLogExport struct {
+	ExportDate time.Time               `json:"export_date"`
+	Since      *time.Time              `json:"since,omitempty"`
+	Records    []*storage.AccessRecord `json:"records"`
+}
+
+func newLogExportCmd(app *app.App) *cobra.Command {
+	var (
+		outputFile string
+		since      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the access log as JSON",
+		Long: `Export the access log as JSON for ingestion by other tools.
+Records only hold the time, entry name and action; no secrets are exported.
+
+Use --since to only export records at or after a point in time, given as a
+date (2006-01-02), an RFC 3339 timestamp or a duration ago (e.g. 24h).
+Writes to standard output unless --output is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			export := &AccessLogExport{ExportDate: time.Now()}
+
+			if since != "" {
+				t, err := parseSince(since, export.ExportDate)
+				if err != nil {
+					return err
+				}
+				export.Since = &t
+			}
+
+			records, err := app.Storage.ListAccessLog()
+			if err != nil {
+				return storageError("failed to list access log", err)
+			}
+
+			export.Records = make([]*storage.AccessRecord, 0, len(records))
+			for _, record := range records {
+				if export.Since != nil && record.Timestamp.Before(*export.Since) {
+					continue
+				}
+				export.Records = append(export.Records, record)
+			}
+
+			data, err := json.MarshalIndent(export, "", "  ")
+			if err != nil {
+				return errs.Internal("failed to encode access log: %w", err)
+			}
+			data = append(data, '\n')
+
+			if outputFile == "" {
+				_, err := os.Stdout.Write(data)
+				return err
+			}
+
+			if err := writeFileRestricted(outputFile, data); err != nil {
+				return errs.Internal("failed to write access log export: %w", err)
+			}
+
+			fmt.Printf("Successfully exported %d access records to %s\n", len(export.Records), outputFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path")
+	cmd.Flags().StringVar(&since, "since", "", "Only export records since a date, timestamp or duration ago")
+
+	return cmd
+}
+
+// parseSince parses a date, an RFC 3339 timestamp or a duration before now.
+// Go durations take precedence, so 30m is 30 minutes rather than months.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := parseDate(value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if d, err := parseAge(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, errs.InvalidInput("invalid --since value: %s", value)
+}
+
+// parseDate parses an RFC 3339 timestamp or a date in local time.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// ageUnits are the calendar units accepted by parseAge, in days
+var ageUnits = map[byte]int{'d': 1, 'w': 7, 'm': 30, 'y': 365}
+
+// parseAge parses a non-negative duration such as 90d, 2w, 6m or 1y, where a
+// month is 30 days and a year 365 days, or any Go duration such as 12h.
+func parseAge(value string) (time.Duration, error) {
+	if len(value) > 1 {
+		if days, ok := ageUnits[value[len(value)-1]]; ok {
+			if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+				return time.Duration(n*days) * 24 * time.Hour, nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 90d, 2w, 6m, 1y or 12h", value)
+	}
+	return d, nil
+}
+
+// ageFilter selects entries by how long ago they were last modified. Zero
+// durations are not applied.
+type ageFilter struct {
+	olderThan time.Duration
+	newerThan time.Duration
+}
+
+// parseAgeFilter parses the --older-than and --newer-than flag values.
+func parseAgeFilter(olderThan, newerThan string) (ageFilter, error) {
+	var filter ageFilter
+	var err error
+
+	if olderThan != "" {
+		if filter.olderThan, err = parseAge(olderThan); err != nil {
+			return filter, errs.InvalidInput("invalid --older-than: %w", err)
+		}
+	}
+	if newerThan != "" {
+		if filter.newerThan, err = parseAge(newerThan); err != nil {
+			return filter, errs.InvalidInput("invalid --newer-than: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// apply returns the entries last modified within the filter's bounds.
+func (f ageFilter) apply(entries []*storage.Entry, now time.Time) []*storage.Entry {
+	if f.olderThan == 0 && f.newerThan == 0 {
+		return entries
+	}
+
+	filtered := make([]*storage.Entry, 0, len(entries))
+	for _, entry := range entries {
+		age := now.Sub(entry.UpdatedAt)
+		if f.olderThan > 0 && age <= f.olderThan {
+			continue
+		}
+		if f.newerThan > 0 && age >= f.newerThan {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// logAccess records an operation on an entry in the access log, if enabled.
+func logAccess(app *app.App, entryName string, action storage.AccessAction) error {
+	if err := app.LogAccess(entryName, action); err != nil {
+		return storageError("failed to record access", err)
+	}
+	return nil
+}
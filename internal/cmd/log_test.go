@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// accessLog returns the entry name and action of each access record of a.
+func accessLog(t *testing.T, a *app.App) []string {
+	t.Helper()
+	records, err := a.Storage.ListAccessLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged := make([]string, 0, len(records))
+	for _, record := range records {
+		logged = append(logged, record.EntryName+" "+string(record.Action))
+	}
+	return logged
+}
+
+func TestAccessLogRecordsOperations(t *testing.T) {
+	tests := []struct {
+		enabled bool
+		want    []string
+	}{
+		{true, []string{"github reveal", "github update", "gitlab reveal", "gitlab delete"}},
+		{false, []string{}},
+	}
+
+	for _, tt := range tests {
+		a := newTestApp(t)
+		a.Config.AccessLog = tt.enabled
+		stubPassword(t, testMasterPassword)
+		addTestEntry(t, a, "github", "hunter2")
+		addTestEntry(t, a, "gitlab", "hunter3")
+
+		for _, args := range [][]string{
+			{"get", "github"},
+			{"get", "github", "--show-password"},
+			{"update", "github", "--url", "https://github.com"},
+			{"get", "gitlab", "--copy"},
+			{"list"},
+			{"delete", "gitlab", "--force"},
+		} {
+			if _, err := runCommand(t, a, args...); err != nil {
+				t.Fatalf("%s: %v", strings.Join(args, " "), err)
+			}
+		}
+
+		if got := accessLog(t, a); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("access log with logging enabled %v = %q, want %q", tt.enabled, got, tt.want)
+		}
+	}
+}
+
+func TestLogCommand(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.AccessLog = true
+	if err := a.Storage.LogAccess("github", storage.AccessReveal); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runCommand(t, a, "log")
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "Time") || !strings.Contains(lines[1], "github") || !strings.HasSuffix(lines[1], "reveal") {
+		t.Errorf("log printed:\n%s\nwant a header and the github reveal", output)
+	}
+
+	if _, err := runCommand(t, a, "log", "--clear"); err != nil {
+		t.Fatalf("log --clear: %v", err)
+	}
+	if output, err = runCommand(t, a, "log"); err != nil || !strings.Contains(output, "No access records found") {
+		t.Errorf("log after clearing printed %q, %v, want no records", output, err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-05-01T08:00:00Z", time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC), false},
+		{"2026-05-01", time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local), false},
+		{"24h", now.Add(-24 * time.Hour), false},
+		{"30m", now.Add(-30 * time.Minute), false},
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"2w", now.AddDate(0, 0, -14), false},
+		{"-1h", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSince(%q): err = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLogExport(t *testing.T) {
+	a := newTestApp(t)
+	for _, name := range []string{"github", "gitlab"} {
+		if err := a.Storage.LogAccess(name, storage.AccessReveal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, 2},
+		{[]string{"--since", "1h"}, 2},
+		{[]string{"--since", "2999-01-01"}, 0},
+	}
+
+	for _, tt := range tests {
+		output, err := runCommand(t, a, append([]string{"log", "export"}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("log export %v: %v", tt.args, err)
+		}
+		var export AccessLogExport
+		if err := json.Unmarshal([]byte(output), &export); err != nil {
+			t.Fatalf("log export %v printed invalid JSON: %v", tt.args, err)
+		}
+		if len(export.Records) != tt.want {
+			t.Errorf("log export %v exported %d records, want %d", tt.args, len(export.Records), tt.want)
+		}
+		if (export.Since != nil) != (len(tt.args) > 0) {
+			t.Errorf("log export %v has since %v", tt.args, export.Since)
+		}
+	}
+
+	// Exports written to a file are readable only by the current user
+	path := filepath.Join(t.TempDir(), "log.json")
+	if _, err := runCommand(t, a, "log", "export", "--output", path); err != nil {
+		t.Fatalf("log export --output: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("log export wrote a file with mode %o, want 600", perm)
+	}
+
+	if _, err := runCommand(t, a, "log", "export", "--since", "soon"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("log export with an invalid --since: err = %v, want invalid input", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * day, false},
+		{"2w", 14 * day, false},
+		{"6m", 180 * day, false},
+		{"1y", 365 * day, false},
+		{"0d", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"-5d", 0, true},
+		{"-1h", 0, true},
+		{"1.5d", 0, true},
+		{"90", 0, true},
+		{"ninety days", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q): err = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAgeFilter(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	var entries []*storage.Entry
+	for _, days := range []int{1, 30, 100, 400} {
+		entries = append(entries, &storage.Entry{Name: strconv.Itoa(days), UpdatedAt: now.AddDate(0, 0, -days)})
+	}
+
+	tests := []struct {
+		olderThan, newerThan string
+		want                 []string
+	}{
+		{"", "", []string{"1", "30", "100", "400"}},
+		{"90d", "", []string{"100", "400"}},
+		{"", "2w", []string{"1"}},
+		{"2w", "1y", []string{"30", "100"}},
+		// The bounds are exclusive
+		{"30d", "", []string{"100", "400"}},
+		{"", "30d", []string{"1"}},
+		{"1y", "2w", []string{}},
+	}
+
+	for _, tt := range tests {
+		filter, err := parseAgeFilter(tt.olderThan, tt.newerThan)
+		if err != nil {
+			t.Fatalf("parseAgeFilter(%q, %q): %v", tt.olderThan, tt.newerThan, err)
+		}
+		got := []string{}
+		for _, entry := range filter.apply(entries, now) {
+			got = append(got, entry.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("older than %q and newer than %q = %v, want %v", tt.olderThan, tt.newerThan, got, tt.want)
+		}
+	}
+
+	for _, flags := range [][2]string{{"soon", ""}, {"", "-3d"}} {
+		if _, err := parseAgeFilter(flags[0], flags[1]); errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("parseAgeFilter(%q, %q): err = %v, want invalid input", flags[0], flags[1], err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/otp"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// totpField is the custom field holding an entry's TOTP secret, as base32 or
+// an otpauth:// URI
+const totpField = "totp"
+
+func newLoginCmd(app *app.App) *cobra.Command {
+	var (
+		delay  time.Duration
+		folder string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "login <name>",
+		Short: "Copy the password and then the TOTP code of an entry",
+		Long: `Copy the password of an entry to the clipboard and, after --delay, replace it
+with the entry's current TOTP code, so both steps of a two-factor login can be
+pasted in turn. The TOTP secret is read from the entry's "totp" custom field,
+e.g. added with 'pm update <name> --field totp=<secret>'.
+
+As with 'pm get', a name or alias matching several entries is resolved by
+asking on a terminal, or with --folder.
+
+The command stays in the foreground until the clipboard has been cleared.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if delay < 0 {
+				return errs.InvalidInput("--delay must be non-negative")
+			}
+
+			entry, err := resolveEntry(app, args[0], folder)
+			if err != nil {
+				return err
+			}
+
+			if entry.IsWriteOnly() {
+				return errs.InvalidInput("entry %s is write-only and cannot be revealed. Use 'pm verify %s' to check a value", entry.Name, entry.Name)
+			}
+
+			totp, err := entryTOTP(app, entry)
+			if err != nil {
+				return err
+			}
+
+			if err := verifyMasterPassword(app); err != nil {
+				return err
+			}
+			if err := logAccess(app, entry.Name, storage.AccessReveal); err != nil {
+				return err
+			}
+
+			password, err := app.DecryptPassword(entry.Password)
+			if err != nil {
+				return errs.Internal("failed to decrypt password: %w", err)
+			}
+
+			// Clear the password on Ctrl-C before the TOTP code replaces it
+			app.OnClose(func() {
+				clipboard.ClearIfHolds(app.Clipboard, password)
+			})
+
+			if _, err := copyWithClear(app, "Password", password, 0, clipboard.SelectionClipboard, false); err != nil {
+				return err
+			}
+
+			fmt.Printf("TOTP code will be copied in %s\n", delay)
+			time.Sleep(delay)
+
+			pending, err := copyWithClear(app, "TOTP code", totp.Code(time.Now()), app.Config.ClipboardTimeout, clipboard.SelectionClipboard, true)
+			if err != nil {
+				return err
+			}
+
+			if pending != nil {
+				app.OnClose(pending.Now)
+				<-pending.Done()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&delay, "delay", 5*time.Second, "Time between copying the password and the TOTP code")
+	cmd.Flags().StringVar(&folder, "folder", "", "Folder of the entry, when the name matches entries in several folders")
+
+	return cmd
+}
+
+// entryTOTP returns the TOTP generator of an entry from its totp custom field.
+func entryTOTP(app *app.App, entry *storage.Entry) (*otp.TOTP, error) {
+	fields, err := app.DecryptFields(entry.CustomFields)
+	if err != nil {
+		return nil, errs.Internal("failed to decrypt custom fields: %w", err)
+	}
+
+	var secret string
+	for key, value := range fields {
+		if strings.EqualFold(key, totpField) {
+			secret = value
+			break
+		}
+	}
+	if secret == "" {
+		return nil, errs.InvalidInput("entry %s has no TOTP secret. Add it with 'pm update %s --field %s=<secret>'", entry.Name, entry.Name, totpField)
+	}
+
+	totp, err := otp.Parse(secret)
+	if err != nil {
+		return nil, errs.InvalidInput("entry %s: %w", entry.Name, err)
+	}
+	return totp, nil
+}
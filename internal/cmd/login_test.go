@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/otp"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestLoginResolvesEntriesByFolder(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.RequireMasterPassword = false
+	a.Config.ClipboardTimeout = 0
+	if err := a.Storage.SetNameScope(storage.NameScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the work entry has a TOTP secret, so login fails on the other
+	for folder, fields := range map[string]map[string]string{
+		"work":     {totpField: "JBSWY3DPEHPK3PXP"},
+		"personal": nil,
+	} {
+		entry := addTestEntry(t, a, "github", "hunter2")
+		entry.Folder = folder
+		var err error
+		if entry.CustomFields, err = a.EncryptFields(fields); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runCommand(t, a, "login", "github", "--delay", "0"); errs.ExitCode(err) != errs.ExitConflict {
+		t.Fatalf("login with an ambiguous name: err = %v, want a conflict", err)
+	}
+	if _, err := runCommand(t, a, "login", "github", "--folder", "work", "--delay", "0"); err != nil {
+		t.Fatalf("login --folder work: %v", err)
+	}
+}
+
+// addTOTPEntry adds an entry with the given password and TOTP secret.
+func addTOTPEntry(t *testing.T, a *app.App, name, password, secret string) {
+	t.Helper()
+	entry := addTestEntry(t, a, name, password)
+	fields, err := a.EncryptFields(map[string]string{totpField: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.CustomFields = fields
+	if err := a.Storage.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertTOTPCode fails the test if code is not the code of secret at any
+// time between before and now.
+func assertTOTPCode(t *testing.T, secret, code string, before time.Time) {
+	t.Helper()
+	totp, err := otp.Parse(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != totp.Code(before) && code != totp.Code(time.Now()) {
+		t.Errorf("copied %q, want the TOTP code %s", code, totp.Code(time.Now()))
+	}
+}
+
+func TestGetCopyTOTP(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTOTPEntry(t, a, "github", "hunter2", secret)
+	addTestEntry(t, a, "gitlab", "hunter3")
+
+	before := time.Now()
+	output, err := runCommand(t, a, "get", "github", "--copy-totp", "--clear-after", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "TOTP code copied to clipboard") {
+		t.Errorf("get --copy-totp printed %q", output)
+	}
+	code, _ := a.Clipboard.ReadAll()
+	assertTOTPCode(t, secret, code, before)
+
+	if _, err := runCommand(t, a, "get", "gitlab", "--copy-totp"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("get --copy-totp without a TOTP secret: err = %v, want invalid input", err)
+	}
+	if _, err := runCommand(t, a, "get", "github", "--copy-totp", "--copy"); err == nil {
+		t.Error("get --copy-totp --copy was accepted")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	const secret = "otpauth://totp/GitHub:me?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+	a := newTestApp(t)
+	a.Config.ClipboardTimeout = 0
+	stubPassword(t, testMasterPassword)
+	addTOTPEntry(t, a, "github", "hunter2", secret)
+	addTestEntry(t, a, "gitlab", "hunter3")
+
+	before := time.Now()
+	output, err := runCommand(t, a, "login", "github", "--delay", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Password copied to clipboard", "TOTP code copied to clipboard"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("login printed %q, want %q", output, want)
+		}
+	}
+	code, _ := a.Clipboard.ReadAll()
+	assertTOTPCode(t, secret, code, before)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"login", "gitlab", "--delay", "0"}, errs.ExitInvalidInput},
+		{[]string{"login", "github", "--delay", "-1s"}, errs.ExitInvalidInput},
+		{[]string{"login", "missing", "--delay", "0"}, errs.ExitNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := runCommand(t, a, tt.args...); errs.ExitCode(err) != tt.want {
+			t.Errorf("%v: err = %v, want exit code %d", tt.args, err, tt.want)
+		}
+	}
+
+	// Nothing was copied by the failed logins
+	if got, _ := a.Clipboard.ReadAll(); got != code {
+		t.Errorf("clipboard holds %q after failed logins, want %q", got, code)
+	}
+}
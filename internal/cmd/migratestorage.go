@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateStorageCmd(app *app.App) *cobra.Command {
+	var (
+		storageType string
+		dsn         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate-storage",
+		Short: "Move all entries to a new storage backend",
+		Long: `Copy all entries from the current storage into a new one and switch the
+configuration to it. The previous storage is left in place.
+
+Supported storage types:
+  - sqlite: --dsn is the path of the new database file, which must not exist`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if storage.StorageType(storageType) != storage.SQLite {
+				return errs.InvalidInput("unsupported storage type: %s", storageType)
+			}
+
+			if dsn == "" {
+				return errs.InvalidInput("--dsn is required")
+			}
+
+			path, err := filepath.Abs(dsn)
+			if err != nil {
+				return errs.InvalidInput("invalid dsn: %s", dsn)
+			}
+
+			migrated, err := app.MigrateStorage(storageType, path)
+			if err != nil {
+				return storageError("storage migration failed", err)
+			}
+
+			fmt.Printf("Successfully migrated %d entries to %s storage at %s\n", migrated, storageType, path)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&storageType, "to", "sqlite", "Target storage type")
+	cmd.Flags().StringVar(&dsn, "dsn", "", "Location of the target storage")
+
+	return cmd
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestMigrateStorageCommand(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+	work := addTestEntry(t, a, "gitlab", "hunter3")
+	work.Folder = "work"
+	if err := a.Storage.UpdateEntry(work); err != nil {
+		t.Fatal(err)
+	}
+	previous := a.Config.DBPath
+
+	dsn := filepath.Join(t.TempDir(), "migrated.db")
+	if _, err := runCommand(t, a, "migrate-storage", "--dsn", dsn); err != nil {
+		t.Fatalf("migrate-storage: %v", err)
+	}
+
+	if got, want := vaultNames(t, a), []string{"github", "gitlab"}; !slices.Equal(got, want) {
+		t.Errorf("migrated vault holds %v, want %v", got, want)
+	}
+	entry, err := a.Storage.GetEntry("gitlab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Folder != "work" {
+		t.Errorf("migrated gitlab is in folder %q, want work", entry.Folder)
+	}
+	if password, err := a.DecryptPassword(entry.Password); err != nil || password != "hunter3" {
+		t.Errorf("migrated password = %q, %v, want hunter3", password, err)
+	}
+
+	// The config points at the new database and the previous one is kept
+	if a.Config.DBPath != dsn {
+		t.Errorf("DBPath = %s, want %s", a.Config.DBPath, dsn)
+	}
+	if _, err := os.Stat(previous); err != nil {
+		t.Errorf("previous database was removed: %v", err)
+	}
+	old, err := storage.NewSQLiteStorage(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	if entries, err := old.ListEntries(); err != nil || len(entries) != 2 {
+		t.Errorf("previous database holds %d entries, %v, want 2", len(entries), err)
+	}
+
+	if _, err := runCommand(t, a, "migrate-storage", "--dsn", dsn); err == nil {
+		t.Error("migrate-storage into an existing database succeeded")
+	}
+}
+
+func TestMigrateStorageCommandRejectsInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"migrate-storage"},
+		{"migrate-storage", "--to", "postgres", "--dsn", "db"},
+	} {
+		a := newTestApp(t)
+		if _, err := runCommand(t, a, args...); errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("%v: err = %v, want invalid input", args, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newPruneCmd(app *app.App) *cobra.Command {
+	var (
+		dryRun       bool
+		force        bool
+		criteria     []string
+		placeholders []string
+		expiredTTL   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove empty or placeholder entries",
+		Long: `Find entries matching all of the given emptiness criteria and delete them.
+Available criteria:
+  - username: the username is empty
+  - url: the URL is empty
+  - notes: the notes are empty
+  - password: the password is one of the placeholder values
+
+Use --expired-ttl instead to delete the entries whose TTL, set with
+'pm add --ttl', has passed.
+
+Use --dry-run to only list the matching entries.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			for _, c := range criteria {
+				switch c {
+				case "username", "url", "notes", "password":
+				default:
+					return errs.InvalidInput("unknown prune criterion: %s", c)
+				}
+			}
+
+			entries, err := app.Storage.ListEntries()
+			if err != nil {
+				return storageError("failed to list entries", err)
+			}
+
+			var targets []*storage.Entry
+			now := time.Now()
+			for _, entry := range entries {
+				matched := expiredTTL && entry.IsPastTTL(now)
+				if !expiredTTL {
+					matched, err = matchesPruneCriteria(app, entry, criteria, placeholders)
+					if err != nil {
+						return err
+					}
+				}
+				if matched {
+					targets = append(targets, entry)
+				}
+			}
+
+			if len(targets) == 0 {
+				fmt.Println("No entries to prune")
+				return nil
+			}
+
+			if expiredTTL {
+				fmt.Println("Entries past their TTL:")
+			} else {
+				fmt.Printf("Entries matching %s:\n", strings.Join(criteria, ", "))
+			}
+			for _, entry := range targets {
+				fmt.Printf("- %s\n", entry.Name)
+			}
+
+			if dryRun {
+				fmt.Printf("\n%d entries would be deleted. This was a dry run\n", len(targets))
+				return nil
+			}
+
+			// Confirm deletion unless force flag is set
+			if !force {
+				fmt.Printf("Are you sure you want to delete %d entries? [y/N]: ", len(targets))
+				var response string
+				fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("Prune cancelled")
+					return nil
+				}
+			}
+
+			for _, entry := range targets {
+				if err := app.Storage.DeleteEntry(entry.Name); err != nil {
+					return storageError(fmt.Sprintf("failed to delete entry %s", entry.Name), err)
+				}
+				if err := logAccess(app, entry.Name, storage.AccessDelete); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Successfully pruned %d entries\n", len(targets))
+			return nil
+		},
+	}
+
+	// Add flags
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List matching entries without deleting them")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+	cmd.Flags().StringSliceVar(&criteria, "criteria", []string{"username", "url"}, "Emptiness criteria an entry must all match")
+	cmd.Flags().BoolVar(&expiredTTL, "expired-ttl", false, "Prune entries whose TTL has passed instead of empty ones")
+	cmd.MarkFlagsMutuallyExclusive("expired-ttl", "criteria")
+	cmd.Flags().StringSliceVar(&placeholders, "placeholders", []string{"password", "changeme", "placeholder", "todo", "xxx"}, "Passwords considered placeholders")
+
+	return cmd
+}
+
+func matchesPruneCriteria(app *app.App, entry *storage.Entry, criteria, placeholders []string) (bool, error) {
+	for _, criterion := range criteria {
+		switch criterion {
+		case "username":
+			if strings.TrimSpace(entry.Username) != "" {
+				return false, nil
+			}
+		case "url":
+			if strings.TrimSpace(entry.URL) != "" {
+				return false, nil
+			}
+		case "notes":
+			if strings.TrimSpace(entry.Notes) != "" || len(entry.SecureNotes) > 0 {
+				return false, nil
+			}
+		case "password":
+			password, err := app.DecryptPassword(entry.Password)
+			if err != nil {
+				return false, errs.Internal("failed to decrypt password for entry %s: %w", entry.Name, err)
+			}
+			if !isPlaceholder(password, placeholders) {
+				return false, nil
+			}
+		}
+	}
+
+	return len(criteria) > 0, nil
+}
+
+func isPlaceholder(password string, placeholders []string) bool {
+	password = strings.TrimSpace(password)
+	if password == "" {
+		return true
+	}
+
+	for _, p := range placeholders {
+		if strings.EqualFold(password, p) {
+			return true
+		}
+	}
+	return false
+}
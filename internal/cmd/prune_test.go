@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+)
+
+func TestIsPlaceholder(t *testing.T) {
+	placeholders := []string{"changeme", "todo"}
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"", true},
+		{"   ", true},
+		{"changeme", true},
+		{"ChangeMe", true},
+		{" todo ", true},
+		{"changeme1", false},
+		{"hunter2", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPlaceholder(tt.password, placeholders); got != tt.want {
+			t.Errorf("isPlaceholder(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+// newPruneTestApp returns an app whose vault holds entries with and without
+// a username, URL and real password.
+func newPruneTestApp(t *testing.T) *app.App {
+	t.Helper()
+	a := newTestApp(t)
+	for _, e := range []struct{ name, username, url, password string }{
+		{"empty", "", "", "hunter2"},
+		{"placeholder", "", "", "changeme"},
+		{"no-username", "", "https://example.com", "hunter3"},
+		{"complete", "alice", "https://github.com", "hunter4"},
+	} {
+		entry := addTestEntry(t, a, e.name, e.password)
+		entry.Username = e.username
+		entry.URL = e.url
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"complete", "no-username"}},
+		{[]string{"--criteria", "username"}, []string{"complete"}},
+		{[]string{"--criteria", "username,url,password"}, []string{"complete", "empty", "no-username"}},
+		{[]string{"--criteria", "password", "--placeholders", "hunter3"}, []string{"complete", "empty", "placeholder"}},
+		{[]string{"--dry-run"}, []string{"complete", "empty", "no-username", "placeholder"}},
+	}
+
+	for _, tt := range tests {
+		a := newPruneTestApp(t)
+		if _, err := runCommand(t, a, append([]string{"prune", "--force"}, tt.args...)...); err != nil {
+			t.Fatalf("prune %v: %v", tt.args, err)
+		}
+		if got := vaultNames(t, a); !slices.Equal(got, tt.want) {
+			t.Errorf("after prune %v the vault holds %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	a := newPruneTestApp(t)
+	if _, err := runCommand(t, a, "prune", "--criteria", "password,tags"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("prune with an unknown criterion: err = %v, want invalid input", err)
+	}
+}
+
+func TestPruneExpiredTTL(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "permanent", "hunter2")
+	for name, deleteAt := range map[string]time.Time{
+		"expired": time.Now().Add(-time.Hour),
+		"pending": time.Now().Add(time.Hour),
+	} {
+		entry := addTestEntry(t, a, name, "hunter2")
+		entry.DeleteAt = &deleteAt
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runCommand(t, a, "prune", "--expired-ttl", "--force"); err != nil {
+		t.Fatalf("prune --expired-ttl: %v", err)
+	}
+	if got, want := vaultNames(t, a), []string{"pending", "permanent"}; !slices.Equal(got, want) {
+		t.Errorf("after prune --expired-ttl the vault holds %v, want %v", got, want)
+	}
+}
+
+// vaultNames returns the sorted names of the entries in the vault of a.
+func vaultNames(t *testing.T, a *app.App) []string {
+	t.Helper()
+	entries, err := a.Storage.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newReindexCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the password database indexes",
+		Long: `Rebuild the indexes derived from the stored entries, such as the entry name
+index, and refresh the statistics SQLite uses to plan queries.
+Useful after bulk imports or when searches return stale results.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if err := app.Storage.Reindex(); err != nil {
+				return storageError("reindex failed", err)
+			}
+
+			fmt.Println("Successfully rebuilt indexes")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func newRekeyCmd(app *app.App) *cobra.Command {
+	var (
+		saltLength int
+		kdf        kdfOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Re-encrypt the vault with current crypto parameters",
+		Long: `Re-derive the master key from the same master password using a fresh salt
+and the current key derivation parameters, then re-encrypt every entry under it.
+All entries are re-encrypted in a single transaction and rolled back on failure.
+
+Use --salt-length to change the length of the salt in bytes.
+
+Use --kdf to switch the key derivation function, e.g. --kdf argon2id to move a
+vault created with PBKDF2 to Argon2id, and --kdf-iterations, --kdf-memory and
+--kdf-parallelism to change its cost.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if saltLength < crypto.MinSaltLength {
+				return errs.InvalidInput("salt length must be at least %d bytes", crypto.MinSaltLength)
+			}
+
+			kdfParams, err := kdf.params(cmd, app.Config.KDFParams())
+			if err != nil {
+				return err
+			}
+
+			fmt.Print("Enter master password: ")
+			password, err := readPassword()
+			if err != nil {
+				return errs.Internal("failed to read password: %w", err)
+			}
+
+			salt, err := generateSalt(saltLength)
+			if err != nil {
+				return errs.Internal("failed to generate salt: %w", err)
+			}
+
+			if err := app.Rekey(password, password, salt, kdfParams); err != nil {
+				return errs.Internal("rekey failed: %w", err)
+			}
+
+			fmt.Println("Successfully re-encrypted vault")
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&saltLength, "salt-length", crypto.SaltLength, "Length of the new salt in bytes")
+	kdf.addFlags(cmd, "")
+
+	return cmd
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// selectEntry picks one of several entries matching name. It is a variable so
+// the prompt can be replaced.
+var selectEntry = promptEntrySelection
+
+// resolveEntry returns the entry with the given name or alias. A name that
+// matches several entries, in different folders or through aliases, is
+// narrowed down to those in folder if it is set. If that still leaves several,
+// selectEntry picks one.
+func resolveEntry(app *app.App, name, folder string) (*storage.Entry, error) {
+	if folder == "" {
+		entry, err := app.Storage.GetEntry(name)
+		if err == nil {
+			return entry, nil
+		}
+		if !errors.Is(err, storage.ErrEntryAmbiguous) {
+			return nil, storageError("failed to get entry", err)
+		}
+	}
+
+	candidates, err := app.Storage.FindEntries(name)
+	if err != nil {
+		return nil, storageError("failed to get entry", err)
+	}
+
+	if folder != "" {
+		inFolder := make([]*storage.Entry, 0, len(candidates))
+		for _, entry := range candidates {
+			if entry.Folder == folder {
+				inFolder = append(inFolder, entry)
+			}
+		}
+		candidates = inFolder
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, storageError("failed to get entry", storage.ErrEntryNotFound)
+	case 1:
+		return candidates[0], nil
+	}
+
+	return selectEntry(name, candidates)
+}
+
+// candidateList lists entries one per line, numbered from 1, with their
+// folder and username.
+func candidateList(entries []*storage.Entry) string {
+	var b strings.Builder
+	for i, entry := range entries {
+		label := entry.Name
+		if entry.Folder != "" {
+			label = entry.Folder + "/" + entry.Name
+		}
+		if entry.Username != "" {
+			label += " (" + entry.Username + ")"
+		}
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, label)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// promptEntrySelection prints the numbered candidates on stderr and reads the
+// number of the chosen one from stdin. Without a terminal to ask on, the
+// candidates are listed in the error instead.
+func promptEntrySelection(name string, candidates []*storage.Entry) (*storage.Entry, error) {
+	if !isTerminal(os.Stdin) {
+		return nil, errs.Conflict("%s matches %d entries, use --folder to pick one:\n%s", name, len(candidates), candidateList(candidates))
+	}
+
+	fmt.Fprintln(os.Stderr, "Several entries match:")
+	fmt.Fprintln(os.Stderr, candidateList(candidates))
+	fmt.Fprintf(os.Stderr, "Select an entry [1-%d]: ", len(candidates))
+
+	var response string
+	fmt.Scanln(&response)
+	n, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || n < 1 || n > len(candidates) {
+		return nil, errs.InvalidInput("invalid selection: %q", response)
+	}
+	return candidates[n-1], nil
+}
@@ -6,26 +6,46 @@ import (
 	"strings"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/spf13/cobra"
 )
 
 func newRestoreCmd(app *app.App) *cobra.Command {
-	var force bool
+	var (
+		force  bool
+		target string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "restore <backup-file>",
 		Short: "Restore from a backup file",
 		Long: `Restore the password database from a backup file.
-This will replace the current database with the backup.`,
+This will replace the current database with the backup.
+
+Use --to to restore into a new database file instead, leaving the current
+database untouched, for example to compare vaults.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			backupFile := args[0]
 			if _, err := os.Stat(backupFile); err != nil {
-				return fmt.Errorf("backup file not found: %w", err)
+				return errs.NotFound("backup file not found: %w", err)
+			}
+
+			if target != "" {
+				restored, err := app.Storage.RestoreTo(backupFile, target)
+				if err != nil {
+					return storageError("restore failed", err)
+				}
+				if err := restored.Close(); err != nil {
+					return storageError("failed to close restored database", err)
+				}
+
+				fmt.Printf("Successfully restored backup to %s\n", target)
+				return nil
 			}
 
 			// Confirm restore unless force flag is set
@@ -42,7 +62,7 @@ This will replace the current database with the backup.`,
 
 			// Perform restore
 			if err := app.Storage.Restore(backupFile); err != nil {
-				return fmt.Errorf("restore failed: %w", err)
+				return storageError("restore failed", err)
 			}
 
 			fmt.Println("Successfully restored from backup")
@@ -52,6 +72,7 @@ This will replace the current database with the backup.`,
 
 	// Add flags
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&target, "to", "", "Restore into a new database file at this path")
 
 	return cmd
 }
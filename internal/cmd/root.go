@@ -1,18 +1,27 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/filelock"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
 func NewRootCmd(app *app.App) *cobra.Command {
 	var (
-		configFile string
-		debug      bool
+		configFile  string
+		debug       bool
+		noColor     bool
+		lockTimeout time.Duration
+		lock        *filelock.Lock
 	)
 
 	cmd := &cobra.Command{
@@ -30,45 +39,140 @@ Features:
 - Tags and search functionality`,
 
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if cmd.Name() == "init" {
+			// A lock still held after a failed command is reused
+			if isMutating(cmd) && lock == nil {
+				var err error
+				lock, err = acquireProcessLock(app, lockTimeout)
+				if err != nil {
+					return err
+				}
+			}
+
+			if cmd.Name() == "init" || cmd.Name() == "status" {
 				return nil
 			}
 
 			if !app.IsInitialized() {
-				return fmt.Errorf("passio is not initialized. Run 'pm init' first")
+				return errs.InvalidInput("passio is not initialized. Run 'pm init' first")
 			}
 
 			return nil
 		},
+
+		// Let other processes in as soon as the command has finished. Cobra
+		// skips this when the command fails, and the lock is then released
+		// when the app is closed.
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if lock != nil {
+				lock.Release()
+				lock = nil
+			}
+		},
 	}
 
 	cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is $HOME/.passio/config.json)")
 	cmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output (also disabled by the NO_COLOR environment variable)")
+	cmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 10*time.Second, "how long to wait for another passio process to finish changing the vault")
 
 	cmd.AddCommand(
 		newInitCmd(app),
 		newAddCmd(app),
 		newGetCmd(app),
+		newLoginCmd(app),
 		newListCmd(app),
 		newUpdateCmd(app),
 		newDeleteCmd(app),
 		newSearchCmd(app),
-		newGenerateCmd(),
+		newGenerateCmd(app),
 		newAuditCmd(app),
 		newLockCmd(app),
 		newUnlockCmd(app),
 		newExportCmd(app),
 		newStatsCmd(app),
 		newImportCmd(app),
+		newDiffCmd(app),
 		newConfigCmd(app),
 		newBackupCmd(app),
 		newRestoreCmd(app),
+		newVerifyBackupCmd(app),
+		newRekeyCmd(app),
+		newChangePasswordCmd(app),
+		newSecurityCmd(app),
+		newVerifyCmd(app),
+		newEnvCmd(app),
+		newDotenvCmd(app),
+		newPruneCmd(app),
+		newCompactCmd(app),
+		newReindexCmd(app),
+		newEncryptNotesCmd(app),
+		newMigrateStorageCmd(app),
+		newClipClearCmd(app),
+		newLogCmd(app),
+		newStatusCmd(app),
 		newVersionCmd(),
 	)
 
 	return cmd
 }
 
+// mutatingCommands are the commands that change the vault or config. Only one
+// process at a time may run them.
+var mutatingCommands = map[string]bool{
+	"pm init":                 true,
+	"pm add":                  true,
+	"pm update":               true,
+	"pm delete":               true,
+	"pm import":               true,
+	"pm prune":                true,
+	"pm compact":              true,
+	"pm reindex":              true,
+	"pm encrypt-notes":        true,
+	"pm rekey":                true,
+	"pm changepw":             true,
+	"pm security upgrade-kdf": true,
+	"pm restore":              true,
+	"pm migrate-storage":      true,
+	"pm config set":           true,
+	"pm config edit":          true,
+	// Unlocking replaces a master key stored by older versions in the config
+	"pm unlock": true,
+}
+
+// mutatingFlags are the flags that make an otherwise read-only command change
+// the vault, by command path.
+var mutatingFlags = map[string]string{
+	"pm log": "clear",
+}
+
+// isMutating reports whether cmd, with the flags it was given, changes the
+// vault or config.
+func isMutating(cmd *cobra.Command) bool {
+	if flag, ok := mutatingFlags[cmd.CommandPath()]; ok && cmd.Flags().Changed(flag) {
+		return true
+	}
+	return mutatingCommands[cmd.CommandPath()]
+}
+
+// acquireProcessLock takes the lock file next to the config, waiting up to
+// timeout for another process to release it. The lock is released when the
+// app is closed, unless released earlier.
+func acquireProcessLock(app *app.App, timeout time.Duration) (*filelock.Lock, error) {
+	path := filepath.Join(filepath.Dir(app.Config.ConfigPath), "passio.lock")
+	lock, err := filelock.Acquire(path, timeout)
+	if errors.Is(err, filelock.ErrLocked) {
+		return nil, errs.Conflict("another passio process is running; try again once it has finished")
+	}
+	if err != nil {
+		return nil, errs.Internal("failed to acquire lock: %w", err)
+	}
+
+	app.OnClose(func() {
+		lock.Release()
+	})
+	return lock, nil
+}
+
 func newLockCmd(app *app.App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "lock",
@@ -85,15 +189,33 @@ func newUnlockCmd(app *app.App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "unlock",
 		Short: "Unlock passio",
+		Long: `Unlock passio with the master password.
+With the use_keychain setting, the master key is kept in the OS keychain
+(macOS Keychain, Secret Service or Windows Credential Manager) and later
+unlocks read it from there instead of prompting. Where no keychain is
+available, the master password is prompted for as usual.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.Config.UseKeychain {
+				if err := app.UnlockWithKeyStore(); err == nil {
+					fmt.Println("Password manager unlocked with the key from the OS keychain")
+					return nil
+				}
+			}
+
 			fmt.Print("Enter master password: ")
 			password, err := readPassword()
 			if err != nil {
-				return fmt.Errorf("failed to read password: %w", err)
+				return errs.Internal("failed to read password: %w", err)
 			}
 
 			if err := app.Unlock(password); err != nil {
-				return fmt.Errorf("failed to unlock: %w", err)
+				return errs.InvalidInput("failed to unlock: %w", err)
+			}
+
+			if app.Config.UseKeychain {
+				if err := app.RememberKey(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v; the master password will be prompted for next time\n", err)
+				}
 			}
 
 			fmt.Println("Password manager unlocked")
@@ -102,21 +224,47 @@ func newUnlockCmd(app *app.App) *cobra.Command {
 	}
 }
 
+// version is the passio release, printed by 'pm version' and recorded in exports
+const version = "1.0.0"
+
 func newVersionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Passio version 1.0.0")
+			fmt.Printf("Passio version %s\n", version)
 		},
 	}
 }
 
-func readPassword() (string, error) {
+// verifyMasterPassword asks for the master password again before secrets are
+// revealed or exported, when the require_master_pass setting is enabled.
+func verifyMasterPassword(app *app.App) error {
+	if !app.Config.RequireMasterPassword {
+		return nil
+	}
+
+	// Prompt on stderr so that it never ends up in piped output
+	fmt.Fprint(os.Stderr, "Re-enter master password: ")
+	password, err := readPassword()
+	if err != nil {
+		return errs.Internal("failed to read password: %w", err)
+	}
+
+	if !app.Config.ValidateMasterPassword(app, password) {
+		return errs.InvalidInput("invalid master password")
+	}
+
+	return nil
+}
+
+// readPassword reads a password from the terminal without echoing it. It is
+// a variable so that tests can supply the password.
+var readPassword = func() (string, error) {
 	password, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
 		return "", err
 	}
-	fmt.Println() // Print a newline after the password input
+	fmt.Fprintln(os.Stderr) // Print a newline after the password input
 	return string(password), nil
 }
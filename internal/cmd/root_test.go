@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/clipboard"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/filelock"
+	"github.com/jayakrishnanMurali/passio/internal/keystore"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// testMasterPassword is the master password of vaults made by newTestApp
+const testMasterPassword = "correct horse battery staple"
+
+// newTestApp returns an unlocked app with an empty vault in a temporary home
+// directory. Its key is derived with the cheapest accepted parameters.
+func newTestApp(t *testing.T) *app.App {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	a, err := app.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	a.Clipboard = clipboard.NewMemoryClipboard()
+	a.KeyStore = keystore.NewMemoryKeyStore()
+
+	salt := make([]byte, crypto.SaltLength)
+	params := crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.KDFIterations}
+	key, err := a.Encryption.DeriveKeyWithParams(testMasterPassword, salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Config.SetKDFParams(params)
+	if err := a.Config.SetMasterKey(key, salt); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Storage.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Unlock(testMasterPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	// Clear copies in-process instead of in a detached pm process
+	original := startClearer
+	startClearer = func(request clearRequest) error {
+		var input bytes.Buffer
+		if err := json.NewEncoder(&input).Encode(request); err != nil {
+			return err
+		}
+		go runClearer(a, &input)
+		return nil
+	}
+	t.Cleanup(func() { startClearer = original })
+
+	return a
+}
+
+// addTestEntry adds an entry with the given password to the vault of a.
+func addTestEntry(t *testing.T, a *app.App, name, password string) *storage.Entry {
+	t.Helper()
+	encrypted, err := a.EncryptPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry(name, "user", encrypted)
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+// runCommand runs the pm command line args against a and returns what it
+// printed to stdout.
+func runCommand(t *testing.T, a *app.App, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	root := NewRootCmd(a)
+	root.SetArgs(args)
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+	err = root.Execute()
+
+	w.Close()
+	return <-output, err
+}
+
+// stubPassword makes readPassword return password for the rest of the test.
+func stubPassword(t *testing.T, password string) {
+	t.Helper()
+	original := readPassword
+	readPassword = func() (string, error) { return password, nil }
+	t.Cleanup(func() { readPassword = original })
+}
+
+// stubPasswords makes readPassword return passwords in turn for the rest of
+// the test.
+func stubPasswords(t *testing.T, passwords ...string) {
+	t.Helper()
+	original := readPassword
+	readPassword = func() (string, error) {
+		if len(passwords) == 0 {
+			t.Fatal("prompted for more passwords than expected")
+		}
+		password := passwords[0]
+		passwords = passwords[1:]
+		return password, nil
+	}
+	t.Cleanup(func() { readPassword = original })
+}
+
+func TestMutatingCommandsTakeProcessLock(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+
+	lock, err := filelock.Acquire(filepath.Join(filepath.Dir(a.Config.ConfigPath), "passio.lock"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	for _, args := range [][]string{
+		{"log", "--clear"},
+		{"unlock"},
+		{"add", "github"},
+	} {
+		_, err := runCommand(t, a, append(args, "--lock-timeout", "0")...)
+		if errs.ExitCode(err) != errs.ExitConflict {
+			t.Errorf("pm %s while another process holds the lock: err = %v, want conflict", strings.Join(args, " "), err)
+		}
+	}
+
+	// Reading the log does not need the lock
+	if _, err := runCommand(t, a, "log", "--lock-timeout", "0"); err != nil {
+		t.Errorf("pm log while another process holds the lock: %v", err)
+	}
+}
+
+func TestProcessLockReleasedAfterCommand(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	if _, err := runCommand(t, a, "update", "github", "--touch"); err != nil {
+		t.Fatalf("update --touch: %v", err)
+	}
+
+	lock, err := filelock.Acquire(filepath.Join(filepath.Dir(a.Config.ConfigPath), "passio.lock"), 0)
+	if err != nil {
+		t.Fatalf("lock still held after the command finished: %v", err)
+	}
+	lock.Release()
+}
+
+func TestUnlockWithKeychain(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.UseKeychain = true
+	a.Lock()
+
+	// The first unlock prompts and saves the key
+	stubPassword(t, testMasterPassword)
+	output, err := runCommand(t, a, "unlock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Password manager unlocked") || strings.Contains(output, "keychain") {
+		t.Errorf("unlock printed %q", output)
+	}
+	if _, err := a.KeyStore.Retrieve(a.Config.ConfigPath); err != nil {
+		t.Fatalf("unlock did not save the key: %v", err)
+	}
+
+	// Later unlocks use the saved key without prompting
+	a.Lock()
+	stubPasswords(t)
+	output, err = runCommand(t, a, "unlock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "unlocked with the key from the OS keychain") || a.IsLocked() {
+		t.Errorf("unlock printed %q, locked = %v", output, a.IsLocked())
+	}
+
+	// Turning the setting off removes the saved key
+	stubPassword(t, testMasterPassword)
+	if _, err := runCommand(t, a, "config", "set", "use_keychain", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.KeyStore.Retrieve(a.Config.ConfigPath); !errors.Is(err, keystore.ErrNotFound) {
+		t.Errorf("key still saved after turning use_keychain off: %v", err)
+	}
+}
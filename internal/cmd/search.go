@@ -3,55 +3,134 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"unicode/utf8"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
 	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 func newSearchCmd(app *app.App) *cobra.Command {
 	var (
-		showTags bool
-		byTag    bool
+		showTags  bool
+		byTag     bool
+		matchAll  bool
+		matchAny  bool
+		highlight bool
+		sortBy    string
+		reverse   bool
+		tagsAll   []string
+		tagsAny   []string
+		fields    []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "search <query>",
 		Short: "Search for password entries",
 		Long: `Search for password entries by name, username, URL, or tags.
-Use --by-tag to search only in tags.`,
-		Args: cobra.ExactArgs(1),
+Use --by-tag to search only in tags.
+
+Multiple whitespace-separated terms can be combined with --and (every term must
+match) or --or (any term may match). Terms are matched against name, username,
+URL and notes. Notes encrypted with 'pm encrypt-notes' are not searched.
+
+When passio is built with the sqlite_fts5 tag, plain searches use a full-text
+index and match entries containing every word of the query as a word prefix.
+Otherwise the query is matched as a substring.
+
+Results are ranked by relevance: exact name matches first, then names starting
+with the query, then names containing it and finally matches in other fields.
+Use --sort name, username, created or modified to order them by that field
+instead, and --reverse to reverse the order.
+
+Use --field to only search some fields: name, username, url, notes or tags.
+It can be repeated or given a comma-separated list, e.g. --field name,username.
+
+Use --tags-all and --tags-any to only keep results with all or at least one of
+a comma-separated list of tags, as with 'pm list'.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
+			}
+
+			switch sortBy {
+			case "relevance", "name", "username", "created", "modified":
+			default:
+				return errs.InvalidInput("unsupported sort order: %s (use relevance, name, username, created or modified)", sortBy)
 			}
 
-			query := args[0]
+			searchFields := make([]storage.SearchField, 0, len(fields))
+			for _, name := range fields {
+				field, err := storage.ParseSearchField(strings.ToLower(strings.TrimSpace(name)))
+				if err != nil {
+					return errs.InvalidInput("%v", err)
+				}
+				searchFields = append(searchFields, field)
+			}
+
+			query := strings.Join(args, " ")
 			var entries []*storage.Entry
 			var err error
 
-			if byTag {
+			switch {
+			case byTag:
 				entries, err = app.Storage.GetEntriesByTag(query)
-			} else {
-				entries, err = app.Storage.SearchEntries(query)
+			case matchAll || matchAny:
+				entries, err = app.Storage.ListEntries()
+				if err == nil {
+					entries = filterByTerms(entries, strings.Fields(query), matchAll, searchFields)
+				}
+			default:
+				entries, err = app.Storage.SearchEntries(query, searchFields...)
 			}
 
 			if err != nil {
-				return fmt.Errorf("search failed: %w", err)
+				return storageError("search failed", err)
 			}
+			entries = filterByTags(entries, tagsAll, tagsAny)
 
 			if len(entries) == 0 {
 				fmt.Println("No matching entries found")
 				return nil
 			}
 
+			// Ranking is stable, so names break ties between equal ranks
+			if sortBy == "relevance" {
+				sortByName(entries)
+				if !byTag {
+					terms := []string{query}
+					if matchAll || matchAny {
+						terms = strings.Fields(query)
+					}
+					rankByRelevance(entries, terms)
+				}
+			} else {
+				sortEntries(entries, sortBy)
+			}
+			if reverse {
+				slices.Reverse(entries)
+			}
+
+			// Highlighting is only applied when writing to a terminal
+			h := highlighter{enabled: highlight && colorEnabled(cmd, os.Stdout)}
+			if !byTag {
+				h.terms = strings.Fields(query)
+				if !matchAll && !matchAny {
+					h.terms = []string{query}
+				}
+			}
+
 			// Create tabwriter for formatted output
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 			// Print header
-			headers := []string{"Name", "Username", "URL", "Last Modified"}
+			headers := []string{h.pad("Name"), h.pad("Username"), h.pad("URL"), "Last Modified"}
 			if showTags {
 				headers = append(headers, "Tags")
 			}
@@ -61,9 +140,9 @@ Use --by-tag to search only in tags.`,
 			// Print entries
 			for _, entry := range entries {
 				row := []string{
-					entry.Name,
-					entry.Username,
-					entry.URL,
+					h.apply(entry.Name),
+					h.apply(entry.Username),
+					h.apply(entry.URL),
 					entry.UpdatedAt.Format("2006-01-02 15:04:05"),
 				}
 
@@ -83,6 +162,171 @@ Use --by-tag to search only in tags.`,
 	// Add flags
 	cmd.Flags().BoolVarP(&showTags, "show-tags", "t", false, "Show tags in results")
 	cmd.Flags().BoolVarP(&byTag, "by-tag", "b", false, "Search only in tags")
+	cmd.Flags().BoolVar(&matchAll, "and", false, "Match entries containing all terms")
+	cmd.Flags().BoolVar(&matchAny, "or", false, "Match entries containing any term")
+	cmd.Flags().BoolVar(&highlight, "highlight", false, "Highlight matched terms when writing to a terminal (see --no-color)")
+	cmd.Flags().StringVar(&sortBy, "sort", "relevance", "Sort order of the results (relevance, name, username, created or modified)")
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Reverse the sort order")
+	cmd.Flags().StringSliceVar(&tagsAll, "tags-all", nil, "Only show results with all of these tags")
+	cmd.Flags().StringSliceVar(&tagsAny, "tags-any", nil, "Only show results with at least one of these tags")
+	cmd.Flags().StringSliceVar(&fields, "field", nil, "Only search these fields: name, username, url, notes or tags (repeatable)")
+	cmd.MarkFlagsMutuallyExclusive("and", "or", "by-tag")
+	cmd.MarkFlagsMutuallyExclusive("field", "by-tag")
 
 	return cmd
 }
+
+// filterByTerms returns the entries whose given fields, or
+// storage.DefaultSearchFields if none are given, contain all of the terms
+// (matchAll) or at least one of them.
+func filterByTerms(entries []*storage.Entry, terms []string, matchAll bool, fields []storage.SearchField) []*storage.Entry {
+	filtered := make([]*storage.Entry, 0)
+	if len(fields) == 0 {
+		fields = storage.DefaultSearchFields
+	}
+
+	for _, entry := range entries {
+		matched := 0
+		for _, term := range terms {
+			if entryContains(entry, strings.ToLower(term), fields) {
+				matched++
+			}
+		}
+
+		if (matchAll && matched == len(terms)) || (!matchAll && matched > 0) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// Relevance ranks of a search match, from most to least relevant
+const (
+	rankExactName = iota
+	rankNamePrefix
+	rankNameContains
+	rankOtherField
+)
+
+// rankByRelevance stably sorts entries by the best rank of any of the terms.
+func rankByRelevance(entries []*storage.Entry, terms []string) {
+	ranks := make(map[*storage.Entry]int, len(entries))
+	for _, entry := range entries {
+		ranks[entry] = rankOtherField
+		for _, term := range terms {
+			ranks[entry] = min(ranks[entry], matchRank(entry.Name, term))
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return ranks[entries[i]] < ranks[entries[j]]
+	})
+}
+
+// matchRank returns how relevant a match of term is for an entry with the given name.
+func matchRank(name, term string) int {
+	name, term = strings.ToLower(name), strings.ToLower(term)
+	switch {
+	case name == term:
+		return rankExactName
+	case strings.HasPrefix(name, term):
+		return rankNamePrefix
+	case strings.Contains(name, term):
+		return rankNameContains
+	default:
+		return rankOtherField
+	}
+}
+
+// entryContains reports whether any of the given fields of entry contain the
+// lower case term.
+func entryContains(entry *storage.Entry, term string, fields []storage.SearchField) bool {
+	for _, field := range fields {
+		var value string
+		switch field {
+		case storage.SearchName:
+			value = entry.Name
+		case storage.SearchUsername:
+			value = entry.Username
+		case storage.SearchURL:
+			value = entry.URL
+		case storage.SearchNotes:
+			value = entry.Notes
+		case storage.SearchTags:
+			value = strings.Join(entry.Tags, " ")
+		}
+		if strings.Contains(strings.ToLower(value), term) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	highlightStart = "\033[1m"
+	highlightEnd   = "\033[0m"
+	// highlightPad has the same length as a highlight so that tabwriter,
+	// which counts escape sequences as text, keeps columns aligned
+	highlightPad = "\033[0m\033[0m"
+)
+
+// highlighter wraps the first match of any of its terms in a cell with
+// terminal escape sequences.
+type highlighter struct {
+	terms   []string
+	enabled bool
+}
+
+func (h highlighter) apply(s string) string {
+	if !h.enabled {
+		return s
+	}
+
+	for _, term := range h.terms {
+		if term == "" {
+			continue
+		}
+		if i, end := indexFold(s, term); i >= 0 {
+			return s[:i] + highlightStart + s[i:end] + highlightEnd + s[end:]
+		}
+	}
+
+	return h.pad(s)
+}
+
+// indexFold returns the byte offsets in s of the first match of substr under
+// Unicode case folding, or -1, -1. It compares rune by rune, as lower casing
+// s first can change its length and shift the offsets.
+func indexFold(s, substr string) (start, end int) {
+	for i := range s {
+		if n, ok := hasPrefixFold(s[i:], substr); ok {
+			return i, i + n
+		}
+	}
+	return -1, -1
+}
+
+// hasPrefixFold reports whether s starts with prefix under Unicode case
+// folding, and the length in bytes of the matching part of s.
+func hasPrefixFold(s, prefix string) (int, bool) {
+	n := 0
+	for _, want := range prefix {
+		if n >= len(s) {
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !strings.EqualFold(string(r), string(want)) {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+func (h highlighter) pad(s string) string {
+	if !h.enabled {
+		return s
+	}
+	return s + highlightPad
+}
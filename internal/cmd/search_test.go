@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestHighlighterApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		s     string
+		want  string
+	}{
+		{"ascii", []string{"hub"}, "GitHub", "Git" + highlightStart + "Hub" + highlightEnd},
+		{"first term that matches", []string{"lab", "git"}, "github", highlightStart + "git" + highlightEnd + "hub"},
+		// İ lower cases to two runes, so offsets into the lower cased string
+		// would be off by one byte per İ
+		{"length changing case", []string{"abc"}, "İİİabc", "İİİ" + highlightStart + "abc" + highlightEnd},
+		{"non-ascii term", []string{"ÜBER"}, "das über", "das " + highlightStart + "über" + highlightEnd},
+		{"no match", []string{"xyz"}, "İİİabc", "İİİabc" + highlightPad},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := highlighter{terms: tt.terms, enabled: true}
+			if got := h.apply(tt.s); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+// searchResults runs pm search with args and returns the names of the
+// entries found, in the order printed.
+func searchResults(t *testing.T, a *app.App, args ...string) []string {
+	t.Helper()
+	output, err := runCommand(t, a, append([]string{"search"}, args...)...)
+	if err != nil {
+		t.Fatalf("search %v: %v", args, err)
+	}
+
+	var names []string
+	lines := strings.Split(output, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "----") {
+			continue
+		}
+		for _, row := range lines[i+1:] {
+			if row == "" {
+				break
+			}
+			names = append(names, strings.Fields(row)[0])
+		}
+		break
+	}
+	return names
+}
+
+// addSearchEntry adds an entry with the given username and tags to a.
+func addSearchEntry(t *testing.T, a *app.App, name, username string, tags ...string) *storage.Entry {
+	t.Helper()
+	entry := addTestEntry(t, a, name, "hunter2")
+	entry.Username = username
+	entry.Tags = tags
+	if err := a.Storage.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestSearchMultipleTerms(t *testing.T) {
+	a := newTestApp(t)
+	addSearchEntry(t, a, "github", "alice")
+	addSearchEntry(t, a, "gitlab", "bob")
+	addSearchEntry(t, a, "mail", "alice")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--and", "git", "alice"}, []string{"github"}},
+		{[]string{"--and", "git", "carol"}, nil},
+		{[]string{"--or", "gitlab", "mail"}, []string{"gitlab", "mail"}},
+		{[]string{"--or", "alice", "nobody"}, []string{"github", "mail"}},
+		{[]string{"--and", "--field", "name", "git", "alice"}, nil},
+	}
+
+	for _, tt := range tests {
+		got := searchResults(t, a, append(tt.args, "--sort", "name")...)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRank(t *testing.T) {
+	tests := []struct {
+		name, term string
+		want       int
+	}{
+		{"github", "github", rankExactName},
+		{"GitHub", "github", rankExactName},
+		{"github", "Git", rankNamePrefix},
+		{"my-github", "git", rankNameContains},
+		{"gitlab", "hub", rankOtherField},
+	}
+
+	for _, tt := range tests {
+		if got := matchRank(tt.name, tt.term); got != tt.want {
+			t.Errorf("matchRank(%q, %q) = %d, want %d", tt.name, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanksByRelevance(t *testing.T) {
+	a := newTestApp(t)
+	addSearchEntry(t, a, "a-git", "alice")
+	addSearchEntry(t, a, "bob", "git")
+	addSearchEntry(t, a, "git", "alice")
+	addSearchEntry(t, a, "gitlab", "alice")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"git"}, []string{"git", "gitlab", "a-git", "bob"}},
+		{[]string{"git", "--sort", "name"}, []string{"a-git", "bob", "git", "gitlab"}},
+		{[]string{"--or", "bob", "gitlab"}, []string{"bob", "gitlab"}},
+	}
+
+	for _, tt := range tests {
+		if got := searchResults(t, a, tt.args...); !slices.Equal(got, tt.want) {
+			t.Errorf("search %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+// addSortEntry adds an entry created and last modified the given number of
+// days ago to a.
+func addSortEntry(t *testing.T, a *app.App, name, username string, created, modified int) {
+	t.Helper()
+	encrypted, err := a.EncryptPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry(name, username, encrypted)
+	entry.CreatedAt = time.Now().AddDate(0, 0, -created)
+	entry.UpdatedAt = time.Now().AddDate(0, 0, -modified)
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchSortOrders(t *testing.T) {
+	a := newTestApp(t)
+	addSortEntry(t, a, "git", "carol", 10, 1)
+	addSortEntry(t, a, "github", "alice", 30, 20)
+	addSortEntry(t, a, "gitlab", "Bob", 20, 5)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--sort", "name"}, []string{"git", "github", "gitlab"}},
+		{[]string{"--sort", "username"}, []string{"github", "gitlab", "git"}},
+		{[]string{"--sort", "created"}, []string{"github", "gitlab", "git"}},
+		{[]string{"--sort", "modified"}, []string{"github", "gitlab", "git"}},
+		{[]string{"--sort", "created", "--reverse"}, []string{"git", "gitlab", "github"}},
+		{[]string{"--sort", "username", "--reverse"}, []string{"git", "gitlab", "github"}},
+		{[]string{"--reverse"}, []string{"gitlab", "github", "git"}},
+	}
+
+	for _, tt := range tests {
+		args := append([]string{"git"}, tt.args...)
+		if got := searchResults(t, a, args...); !slices.Equal(got, tt.want) {
+			t.Errorf("search %v = %v, want %v", args, got, tt.want)
+		}
+	}
+
+	_, err := runCommand(t, a, "search", "git", "--sort", "size")
+	if errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("search --sort size: got %v, want invalid input", err)
+	}
+}
+
+func TestSearchField(t *testing.T) {
+	a := newTestApp(t)
+	addSearchEntry(t, a, "github", "alice", "work")
+	gitlab := addSearchEntry(t, a, "gitlab", "bob")
+	gitlab.Notes = "alice's work account"
+	if err := a.Storage.UpdateEntry(gitlab); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"alice"}, []string{"github", "gitlab"}},
+		{[]string{"alice", "--field", "username"}, []string{"github"}},
+		{[]string{"alice", "--field", "notes"}, []string{"gitlab"}},
+		{[]string{"alice", "--field", "name"}, nil},
+		{[]string{"work", "--field", "tags"}, []string{"github"}},
+		{[]string{"work", "--field", "tags", "--field", "notes"}, []string{"github", "gitlab"}},
+		{[]string{"work", "--field", "Tags, notes"}, []string{"github", "gitlab"}},
+		{[]string{"--and", "alice", "work", "--field", "notes"}, []string{"gitlab"}},
+		{[]string{"--or", "bob", "work", "--field", "username,tags"}, []string{"github", "gitlab"}},
+	}
+
+	for _, tt := range tests {
+		args := append(tt.args, "--sort", "name")
+		if got := searchResults(t, a, args...); !slices.Equal(got, tt.want) {
+			t.Errorf("search %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	_, err := runCommand(t, a, "search", "alice", "--field", "password")
+	if errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("search --field password: got %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+func newSecurityCmd(app *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "security",
+		Short: "Manage the vault's cryptographic settings",
+	}
+
+	cmd.AddCommand(newUpgradeKDFCmd(app))
+
+	return cmd
+}
+
+func newUpgradeKDFCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "upgrade-kdf",
+		Short: "Raise the cost of the master key derivation to current recommendations",
+		Long: `Re-derive the master key with the vault's key derivation function at the
+currently recommended cost, e.g. 600000 PBKDF2 iterations for vaults created
+with 4096, and re-encrypt every entry under it. The master password stays the
+same and is verified first.
+
+Use 'pm rekey --kdf argon2id' to switch to Argon2id instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			current := app.Config.KDFParams().Normalized()
+			upgraded := current.Upgraded()
+			if upgraded == current {
+				fmt.Printf("Key derivation already meets current recommendations (%s, %d iterations)\n", current.Name(), current.Iterations)
+				return nil
+			}
+
+			fmt.Print("Enter master password: ")
+			password, err := readPassword()
+			if err != nil {
+				return errs.Internal("failed to read password: %w", err)
+			}
+
+			if !app.Config.ValidateMasterPassword(app, password) {
+				return errs.InvalidInput("invalid master password")
+			}
+
+			salt, err := generateSalt(crypto.SaltLength)
+			if err != nil {
+				return errs.Internal("failed to generate salt: %w", err)
+			}
+
+			if err := app.Rekey(password, password, salt, upgraded); err != nil {
+				return errs.Internal("upgrade failed: %w", err)
+			}
+
+			fmt.Printf("Upgraded key derivation to %s with %d iterations\n", upgraded.Name(), upgraded.Iterations)
+			return nil
+		},
+	}
+}
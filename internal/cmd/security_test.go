@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/crypto"
+)
+
+func TestUpgradeKDF(t *testing.T) {
+	a := newTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	oldSalt := a.Config.Salt
+	stubPassword(t, testMasterPassword)
+
+	output, err := runCommand(t, a, "security", "upgrade-kdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "600000 iterations") {
+		t.Errorf("upgrade-kdf printed %q", output)
+	}
+
+	want := crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.PBKDF2Iterations}
+	if got := a.Config.KDFParams(); got != want {
+		t.Errorf("KDF parameters = %+v, want %+v", got, want)
+	}
+	if bytes.Equal(a.Config.Salt, oldSalt) {
+		t.Error("upgrade-kdf kept the old salt")
+	}
+
+	// The entry is re-encrypted under the key derived at the new cost
+	key, err := a.Encryption.DeriveKeyWithParams(testMasterPassword, a.Config.Salt, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := a.Encryption.Decrypt(updated.Password, key); err != nil || string(plain) != "hunter2" {
+		t.Errorf("entry decrypts to %q, %v with the upgraded key", plain, err)
+	}
+	oldKey, err := a.Encryption.DeriveKeyWithParams(testMasterPassword, oldSalt, crypto.KDFParams{Algorithm: crypto.KDFPBKDF2, Iterations: crypto.KDFIterations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Encryption.Decrypt(updated.Password, oldKey); err == nil {
+		t.Error("entry still decrypts with the old key")
+	}
+	if bytes.Equal(updated.Password, entry.Password) {
+		t.Error("entry was not re-encrypted")
+	}
+
+	a.Lock()
+	if err := a.Unlock(testMasterPassword); err != nil {
+		t.Fatalf("the master password does not unlock after the upgrade: %v", err)
+	}
+
+	// A second run has nothing to do
+	output, err = runCommand(t, a, "security", "upgrade-kdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "already meets") {
+		t.Errorf("second upgrade-kdf printed %q", output)
+	}
+}
+
+func TestUpgradeKDFRejectsWrongPassword(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, "wrong")
+
+	if _, err := runCommand(t, a, "security", "upgrade-kdf"); err == nil {
+		t.Fatal("upgrade-kdf succeeded with the wrong master password")
+	}
+	if a.Config.KDFIterations != crypto.KDFIterations {
+		t.Errorf("iterations = %d after a failed upgrade", a.Config.KDFIterations)
+	}
+}
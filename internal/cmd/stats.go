@@ -1,15 +1,29 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"time"
+	"os"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
+type StatsReport struct {
+	*storage.StorageStats
+	ReusedPasswords int  `json:"reused_passwords"`
+	NeverRotated    int  `json:"never_rotated"` // Entries still holding the password they were created with
+	SecurityScore   *int `json:"security_score,omitempty"`
+}
+
 func newStatsCmd(app *app.App) *cobra.Command {
-	var detailed bool
+	var (
+		detailed      bool
+		securityScore bool
+		jsonOutput    bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "stats",
@@ -17,15 +31,83 @@ func newStatsCmd(app *app.App) *cobra.Command {
 		Long: `Display statistics about stored passwords including:
 - Total number of entries
 - Password age information
-- Security statistics`,
+- Security statistics
+
+Expired passwords are those changed longer ago than password_expiration, or
+past the expiry date set on the entry with 'pm update --expires-at'.
+Never rotated entries, counted separately, still have the password they were
+created with, whatever their age. Updates that leave the password alone, such
+as 'pm update --touch', do not count as a rotation.
+
+The security score (0-100) is weighted as follows:
+- 40% proportion of strong passwords
+- 30% proportion of unexpired passwords
+- 30% proportion of unique (not reused) passwords`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			stats, err := app.Storage.GetStats()
 			if err != nil {
-				return fmt.Errorf("failed to get statistics: %w", err)
+				return storageError("failed to get statistics", err)
+			}
+
+			report := &StatsReport{StorageStats: stats}
+
+			if stats.TotalEntries > 0 && (detailed || securityScore || jsonOutput) {
+				// Get and analyze all entries for detailed stats
+				entries, err := app.Storage.ListEntries()
+				if err != nil {
+					return storageError("failed to list entries", err)
+				}
+
+				reusedPasswords := make(map[string][]string)
+
+				for _, entry := range entries {
+					// Check expired passwords
+					if app.IsExpired(entry) {
+						stats.ExpiredPasswords++
+					}
+
+					// Other updates, e.g. --touch, leave the original password in place
+					if !entry.PasswordChangedAt.After(entry.CreatedAt) {
+						report.NeverRotated++
+					}
+
+					// Decrypt and check password strength
+					password, err := app.DecryptPassword(entry.Password)
+					if err != nil {
+						return errs.Internal("failed to decrypt password: %w", err)
+					}
+
+					if weak, _ := app.IsWeakPassword(password); weak {
+						stats.WeakPasswords++
+					}
+
+					// Track password reuse
+					reusedPasswords[password] = append(reusedPasswords[password], entry.Name)
+				}
+
+				// Count passwords shared by more than one entry, and the entries using them
+				var reusedEntries int
+				for _, names := range reusedPasswords {
+					if len(names) > 1 {
+						report.ReusedPasswords++
+						reusedEntries += len(names)
+					}
+				}
+
+				if securityScore || jsonOutput {
+					score := app.SecurityScore(stats.TotalEntries, stats.WeakPasswords, stats.ExpiredPasswords, reusedEntries)
+					report.SecurityScore = &score
+				}
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
 			}
 
 			// Print basic stats
@@ -39,55 +121,16 @@ func newStatsCmd(app *app.App) *cobra.Command {
 				fmt.Printf("Average password age: %.1f days\n", stats.AveragePassAge)
 
 				if detailed {
-					// Get and analyze all entries for detailed stats
-					entries, err := app.Storage.ListEntries()
-					if err != nil {
-						return fmt.Errorf("failed to list entries: %w", err)
-					}
-
-					var (
-						expiredCount    int
-						weakCount       int
-						reusedPasswords = make(map[string][]string)
-					)
-
-					for _, entry := range entries {
-						// Check expired passwords
-						age := time.Since(entry.UpdatedAt).Hours() / 24
-						if age > float64(app.Config.PasswordExpiration) {
-							expiredCount++
-						}
-
-						// Decrypt and check password strength
-						password, err := app.DecryptPassword(entry.Password)
-						if err != nil {
-							return fmt.Errorf("failed to decrypt password: %w", err)
-						}
-
-						health := app.CheckPasswordHealth(password)
-						if !health["length"] || !health["uppercase"] ||
-							!health["lowercase"] || !health["numbers"] ||
-							!health["specialChars"] || !health["notCommon"] {
-							weakCount++
-						}
-
-						// Track password reuse
-						reusedPasswords[password] = append(reusedPasswords[password], entry.Name)
-					}
-
 					fmt.Println("\nDetailed Statistics")
 					fmt.Println("-------------------")
-					fmt.Printf("Expired passwords: %d\n", expiredCount)
-					fmt.Printf("Weak passwords: %d\n", weakCount)
-
-					// Report password reuse
-					var reusedCount int
-					for _, entries := range reusedPasswords {
-						if len(entries) > 1 {
-							reusedCount++
-						}
-					}
-					fmt.Printf("Reused passwords: %d\n", reusedCount)
+					fmt.Printf("Expired passwords: %d\n", stats.ExpiredPasswords)
+					fmt.Printf("Never rotated: %d\n", report.NeverRotated)
+					fmt.Printf("Weak passwords: %d\n", stats.WeakPasswords)
+					fmt.Printf("Reused passwords: %d\n", report.ReusedPasswords)
+				}
+
+				if securityScore {
+					fmt.Printf("\nSecurity score: %d/100\n", *report.SecurityScore)
 				}
 			}
 
@@ -97,6 +140,8 @@ func newStatsCmd(app *app.App) *cobra.Command {
 
 	// Add flags
 	cmd.Flags().BoolVarP(&detailed, "detailed", "d", false, "Show detailed statistics")
+	cmd.Flags().BoolVar(&securityScore, "security-score", false, "Show composite security score (0-100)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output statistics as JSON")
 
 	return cmd
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStatsNeverRotated(t *testing.T) {
+	a := newTestApp(t)
+
+	// Entries created a while ago, so that later updates are distinguishable
+	for _, name := range []string{"untouched", "touched", "rotated"} {
+		entry := addTestEntry(t, a, name, "initial-"+name)
+		entry.CreatedAt = entry.CreatedAt.Add(-time.Hour)
+		entry.UpdatedAt = entry.CreatedAt
+		entry.PasswordChangedAt = entry.CreatedAt
+		if err := a.Storage.DeleteEntry(name); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Storage.AddEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runCommand(t, a, "update", "touched", "--touch"); err != nil {
+		t.Fatalf("update --touch: %v", err)
+	}
+	if _, err := runCommand(t, a, "update", "rotated", "--password", "a-new-password"); err != nil {
+		t.Fatalf("update --password: %v", err)
+	}
+
+	output, err := runCommand(t, a, "stats", "--json")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+
+	var report StatsReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("decoding stats: %v\n%s", err, output)
+	}
+	if report.NeverRotated != 2 {
+		t.Errorf("never_rotated = %d, want 2 (untouched and touched)", report.NeverRotated)
+	}
+}
+
+func TestStatsSecurityScore(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 0
+	addTestEntry(t, a, "github", "Xk9#mP2$vL7@qR4!")
+	addTestEntry(t, a, "gitlab", "Xk9#mP2$vL7@qR4!")
+	addTestEntry(t, a, "mail", "password")
+
+	output, err := runCommand(t, a, "stats", "--json")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+
+	var report StatsReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("decoding stats: %v\n%s", err, output)
+	}
+	if report.WeakPasswords != 1 || report.ReusedPasswords != 1 {
+		t.Fatalf("weak = %d, reused = %d, want 1 and 1", report.WeakPasswords, report.ReusedPasswords)
+	}
+	// 2 of 3 strong, none expired, 2 of 3 entries reusing a password
+	if want := a.SecurityScore(3, 1, 0, 2); report.SecurityScore == nil || *report.SecurityScore != want {
+		t.Errorf("security_score = %v, want %d", report.SecurityScore, want)
+	}
+}
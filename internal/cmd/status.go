@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCmd(app *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:     "status",
+		Aliases: []string{"whoami"},
+		Short:   "Show the state of passio",
+		Long: `Show which config and database are in use, whether passio is initialized
+and unlocked, how many entries it holds and when the session locks.
+Works without unlocking and never shows secrets.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			initialized := app.IsInitialized()
+			locked := app.IsLocked()
+
+			fmt.Printf("Config: %s\n", app.Config.ConfigPath)
+			fmt.Printf("Database: %s (%s)\n", app.Config.DBPath, app.Config.StorageType)
+			fmt.Printf("Initialized: %s\n", yesNo(initialized))
+			fmt.Printf("Locked: %s\n", yesNo(locked))
+
+			if initialized {
+				stats, err := app.Storage.GetStats()
+				if err != nil {
+					return storageError("failed to count entries", err)
+				}
+				fmt.Printf("Entries: %d\n", stats.TotalEntries)
+			}
+
+			if expiry, ok := app.SessionExpiry(); ok {
+				fmt.Printf("Session locks at: %s\n", expiry.Format("2006-01-02 15:04:05"))
+			}
+
+			return nil
+		},
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// statusFields returns the fields printed by pm status, keyed by label.
+func statusFields(t *testing.T, output string) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		label, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			t.Fatalf("unexpected status line %q", line)
+		}
+		fields[label] = value
+	}
+	return fields
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		lock        bool
+		autoLock    int
+		wantLocked  string
+		wantSession bool
+	}{
+		{"unlocked", false, 300, "no", true},
+		{"unlocked without auto-lock", false, 0, "no", false},
+		{"locked", true, 300, "yes", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			addTestEntry(t, a, "github", "hunter2")
+			addTestEntry(t, a, "gitlab", "hunter3")
+			a.Config.AutoLockTimeout = tt.autoLock
+			if tt.lock {
+				a.Lock()
+			}
+
+			output, err := runCommand(t, a, "status")
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			fields := statusFields(t, output)
+
+			want := map[string]string{
+				"Config":      a.Config.ConfigPath,
+				"Database":    a.Config.DBPath + " (sqlite)",
+				"Initialized": "yes",
+				"Locked":      tt.wantLocked,
+				"Entries":     "2",
+			}
+			for label, value := range want {
+				if fields[label] != value {
+					t.Errorf("status %s = %q, want %q", label, fields[label], value)
+				}
+			}
+			if _, ok := fields["Session locks at"]; ok != tt.wantSession {
+				t.Errorf("status shows the session expiry: %v, want %v", ok, tt.wantSession)
+			}
+			if strings.Contains(output, "hunter") {
+				t.Errorf("status printed a password:\n%s", output)
+			}
+		})
+	}
+}
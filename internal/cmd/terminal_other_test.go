@@ -0,0 +1,13 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+// openTerminal returns nil, as pseudo-terminals are only opened on Linux.
+func openTerminal(t *testing.T) *os.File {
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -18,17 +20,31 @@ func newUpdateCmd(app *app.App) *cobra.Command {
 		generate bool
 		length   int
 		special  bool
+		folder   string
+		touch    bool
+		expires  string
+		fields   []string
+		aliases  []string
+		genType  string
+		words    int
 	)
 
 	cmd := &cobra.Command{
 		Use:   "update <name>",
 		Short: "Update an existing password entry",
 		Long: `Update an existing password entry in the password manager.
-Only specified fields will be updated. Use --generate to create a new password.`,
+Only specified fields will be updated. Use --generate to create a new password,
+a random password or diceware passphrase as chosen by the password_generator
+setting or --generator.
+Use --touch to mark the entry as reviewed by refreshing its modification time.
+It does not reset the password's age, which counts from the last password
+change, so an expired password stays expired until it is changed.
+Use --expires-at to expire the password at a date of its own instead of after
+password_expiration days, or --expires-at "" to go back to the setting.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if app.IsLocked() {
-				return fmt.Errorf("password manager is locked. Please unlock first")
+				return errLocked
 			}
 
 			name := args[0]
@@ -36,7 +52,20 @@ Only specified fields will be updated. Use --generate to create a new password.`
 			// Get existing entry
 			entry, err := app.Storage.GetEntry(name)
 			if err != nil {
-				return fmt.Errorf("failed to get entry: %w", err)
+				return storageError("failed to get entry", err)
+			}
+
+			if touch {
+				// Storing the entry unchanged refreshes its modification time
+				if err := app.Storage.UpdateEntry(entry); err != nil {
+					return storageError("failed to update entry", err)
+				}
+				if err := logAccess(app, entry.Name, storage.AccessUpdate); err != nil {
+					return err
+				}
+
+				fmt.Printf("Marked entry as reviewed: %s\n", name)
+				return nil
 			}
 
 			// Update fields if provided
@@ -48,9 +77,9 @@ Only specified fields will be updated. Use --generate to create a new password.`
 				var newPassword string
 				if generate {
 					var err error
-					newPassword, err = generatePassword(length, special)
+					newPassword, err = generateEntryPassword(app, genType, length, special, words)
 					if err != nil {
-						return fmt.Errorf("failed to generate password: %w", err)
+						return err
 					}
 					fmt.Printf("Generated new password: %s\n", newPassword)
 				} else {
@@ -60,7 +89,7 @@ Only specified fields will be updated. Use --generate to create a new password.`
 				// Encrypt the new password
 				encryptedPass, err := app.EncryptPassword(newPassword)
 				if err != nil {
-					return fmt.Errorf("failed to encrypt password: %w", err)
+					return errs.Internal("failed to encrypt password: %w", err)
 				}
 				entry.Password = encryptedPass
 			}
@@ -70,7 +99,54 @@ Only specified fields will be updated. Use --generate to create a new password.`
 			}
 
 			if notes != "" {
-				entry.Notes = notes
+				if err := app.SetEntryNotes(entry, notes); err != nil {
+					return errs.Internal("failed to store notes: %w", err)
+				}
+			}
+
+			if cmd.Flags().Changed("folder") {
+				entry.Folder = folder
+			}
+
+			if cmd.Flags().Changed("alias") {
+				entry.Aliases = parseAliasFlags(aliases)
+			}
+
+			if cmd.Flags().Changed("expires-at") {
+				entry.ExpiresAt = nil
+				if expires != "" {
+					expiresAt, err := parseDate(expires)
+					if err != nil {
+						return errs.InvalidInput("invalid --expires-at value: %s", expires)
+					}
+					entry.ExpiresAt = &expiresAt
+				}
+			}
+
+			if len(fields) > 0 {
+				changes, err := parseFieldFlags(fields)
+				if err != nil {
+					return err
+				}
+
+				customFields, err := app.DecryptFields(entry.CustomFields)
+				if err != nil {
+					return errs.Internal("failed to decrypt custom fields: %w", err)
+				}
+
+				// An empty value removes the field
+				for key, value := range changes {
+					if value == "" {
+						delete(customFields, key)
+					} else {
+						customFields[key] = value
+					}
+				}
+
+				entry.CustomFields, err = app.EncryptFields(customFields)
+				if err != nil {
+					return errs.Internal("failed to encrypt custom fields: %w", err)
+				}
 			}
 
 			if tags != "" {
@@ -83,7 +159,10 @@ Only specified fields will be updated. Use --generate to create a new password.`
 
 			// Update entry in storage
 			if err := app.Storage.UpdateEntry(entry); err != nil {
-				return fmt.Errorf("failed to update entry: %w", err)
+				return storageError("failed to update entry", err)
+			}
+			if err := logAccess(app, entry.Name, storage.AccessUpdate); err != nil {
+				return err
 			}
 
 			fmt.Printf("Successfully updated entry: %s\n", name)
@@ -97,9 +176,20 @@ Only specified fields will be updated. Use --generate to create a new password.`
 	cmd.Flags().StringVar(&url, "url", "", "New URL")
 	cmd.Flags().StringVar(&notes, "notes", "", "New notes")
 	cmd.Flags().StringVar(&tags, "tags", "", "New comma-separated list of tags")
+	cmd.Flags().StringArrayVar(&fields, "field", nil, "Set custom field as key=value, or remove it with key= (repeatable)")
+	cmd.Flags().StringVar(&folder, "folder", "", "New folder (empty to remove from folder)")
+	cmd.Flags().StringArrayVar(&aliases, "alias", nil, "Replace the entry's aliases (repeatable, empty to remove all)")
 	cmd.Flags().BoolVarP(&generate, "generate", "g", false, "Generate a new password")
+	cmd.Flags().BoolVar(&touch, "touch", false, "Refresh the modification time without changing the entry or the password's age")
+	cmd.Flags().StringVar(&expires, "expires-at", "", "Date the password expires, overriding password_expiration (empty to remove)")
 	cmd.Flags().IntVarP(&length, "length", "l", 16, "Length of generated password")
 	cmd.Flags().BoolVarP(&special, "special", "s", true, "Include special characters in generated password")
+	cmd.Flags().StringVar(&genType, "generator", "", "Generate a random password or passphrase (default is the password_generator setting)")
+	cmd.Flags().IntVar(&words, "words", 0, "Number of words in a generated passphrase (default is the passphrase_words setting)")
+
+	for _, flag := range []string{"username", "password", "url", "notes", "tags", "folder", "field", "alias", "expires-at", "generate"} {
+		cmd.MarkFlagsMutuallyExclusive("touch", flag)
+	}
 
 	return cmd
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestUpdateExpiresAt(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	if _, err := runCommand(t, a, "update", "github", "--expires-at", "2030-01-02"); err != nil {
+		t.Fatalf("update --expires-at: %v", err)
+	}
+	entry, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local)
+	if entry.ExpiresAt == nil || !entry.ExpiresAt.Equal(want) {
+		t.Fatalf("ExpiresAt = %v, want %v", entry.ExpiresAt, want)
+	}
+
+	if _, err := runCommand(t, a, "update", "github", "--expires-at", ""); err != nil {
+		t.Fatalf("update --expires-at \"\": %v", err)
+	}
+	if entry, err = a.Storage.GetEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+	if entry.ExpiresAt != nil {
+		t.Fatalf("ExpiresAt = %v after clearing it, want nil", entry.ExpiresAt)
+	}
+
+	// Last, as a failed command keeps the process lock until the app closes
+	if _, err := runCommand(t, a, "update", "github", "--expires-at", "next week"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Fatalf("update --expires-at with an invalid date: err = %v, want invalid input", err)
+	}
+}
+
+func TestUpdateTouchKeepsPasswordAge(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.PasswordExpiration = 90
+
+	encrypted, err := a.EncryptPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := storage.NewEntry("github", "user", encrypted)
+	entry.CreatedAt = time.Now().AddDate(0, 0, -100)
+	entry.UpdatedAt = entry.CreatedAt
+	if err := a.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCommand(t, a, "update", "github", "--touch"); err != nil {
+		t.Fatalf("update --touch: %v", err)
+	}
+	if entry, err = a.Storage.GetEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(entry.UpdatedAt) > time.Minute {
+		t.Errorf("UpdatedAt = %v, want it refreshed", entry.UpdatedAt)
+	}
+	if !a.IsExpired(entry) {
+		t.Errorf("touched entry is no longer expired (age %.0f days)", a.AgeDays(entry))
+	}
+}
+
+func TestUpdateTouch(t *testing.T) {
+	a := newTestApp(t)
+	entry := addTestEntry(t, a, "github", "hunter2")
+	entry.URL = "https://github.com"
+	entry.UpdatedAt = time.Now().Add(-time.Hour)
+	if err := a.Storage.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	before, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCommand(t, a, "update", "github", "--touch"); err != nil {
+		t.Fatalf("update --touch: %v", err)
+	}
+	after, err := a.Storage.GetEntry("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want later than %v", after.UpdatedAt, before.UpdatedAt)
+	}
+	if after.Username != before.Username || after.URL != before.URL || !bytes.Equal(after.Password, before.Password) {
+		t.Errorf("update --touch changed the entry: %+v, want %+v", after, before)
+	}
+
+	if _, err := runCommand(t, a, "update", "github", "--touch", "--username", "bob"); err == nil {
+		t.Error("update --touch --username was accepted")
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jayakrishnanMurali/passio/internal/app"
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd(app *app.App) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:     "verify [name]",
+		Aliases: []string{"test-password"},
+		Short:   "Verify a value against a stored secret, or the whole vault",
+		Long: `Verify a value against the secret stored for an entry without revealing it,
+e.g. to check that you still remember a password. The value is compared in
+constant time and the stored secret is never printed.
+This is the only way to check the secret of a write-only entry.
+
+Without a name, every entry of the vault is checked instead: its password,
+custom fields and encrypted notes must decrypt and pass authentication with
+the master key. Entries that fail, e.g. because they were corrupted or
+tampered with, are listed by name without printing any secret, and the
+command exits non-zero so it can be used in scripts and health checks.
+Use --json for a machine-readable report.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.IsLocked() {
+				return errLocked
+			}
+
+			if len(args) == 0 {
+				return verifyVault(app, jsonOutput)
+			}
+			if jsonOutput {
+				return errs.InvalidInput("--json is only supported when verifying the whole vault")
+			}
+
+			name := args[0]
+
+			entry, err := app.Storage.GetEntry(name)
+			if err != nil {
+				return storageError("failed to get entry", err)
+			}
+
+			fmt.Print("Enter value to verify: ")
+			value, err := readPassword()
+			if err != nil {
+				return errs.Internal("failed to read value: %w", err)
+			}
+
+			password, err := app.DecryptPassword(entry.Password)
+			if err != nil {
+				return errs.Internal("failed to decrypt password: %w", err)
+			}
+
+			if subtle.ConstantTimeCompare([]byte(value), []byte(password)) != 1 {
+				return errs.InvalidInput("value does not match the secret stored for %s", name)
+			}
+
+			fmt.Printf("Value matches the secret stored for %s\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the vault check as JSON")
+
+	return cmd
+}
+
+// vaultReport is the result of checking every entry of the vault.
+type vaultReport struct {
+	Total   int            `json:"total"`
+	Healthy []string       `json:"healthy"`
+	Failing []failingEntry `json:"failing"`
+}
+
+// failingEntry is an entry whose secrets could not be decrypted.
+type failingEntry struct {
+	Name   string `json:"name"`
+	Folder string `json:"folder,omitempty"`
+	Field  string `json:"field"`
+	Error  string `json:"error"`
+}
+
+// verifyVault decrypts the secrets of every entry and reports the entries
+// that fail, returning an error if there are any.
+func verifyVault(app *app.App, jsonOutput bool) error {
+	entries, err := app.Storage.ListEntries()
+	if err != nil {
+		return storageError("failed to list entries", err)
+	}
+
+	report := vaultReport{Total: len(entries), Healthy: []string{}, Failing: []failingEntry{}}
+	for _, entry := range entries {
+		if field, err := verifyEntrySecrets(app, entry); err != nil {
+			report.Failing = append(report.Failing, failingEntry{
+				Name:   entry.Name,
+				Folder: entry.Folder,
+				Field:  field,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		report.Healthy = append(report.Healthy, entry.Name)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return errs.Internal("failed to encode report: %w", err)
+		}
+	} else {
+		for _, failing := range report.Failing {
+			name := failing.Name
+			if failing.Folder != "" {
+				name = failing.Folder + "/" + name
+			}
+			fmt.Printf("FAILED  %s (%s): %s\n", name, failing.Field, failing.Error)
+		}
+		fmt.Printf("Verified %d entries: %d healthy, %d failing\n", report.Total, len(report.Healthy), len(report.Failing))
+	}
+
+	if len(report.Failing) > 0 {
+		return errs.Internal("%d of %d entries failed verification", len(report.Failing), report.Total)
+	}
+	return nil
+}
+
+// verifyEntrySecrets decrypts the password, custom fields and encrypted notes
+// of entry, returning the first field that fails.
+func verifyEntrySecrets(app *app.App, entry *storage.Entry) (string, error) {
+	if _, err := app.DecryptPassword(entry.Password); err != nil {
+		return "password", err
+	}
+	if _, err := app.DecryptFields(entry.CustomFields); err != nil {
+		return "custom fields", err
+	}
+	if _, err := app.EntryNotes(entry); err != nil {
+		return "notes", err
+	}
+	return "", nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+func TestWriteOnlyEntries(t *testing.T) {
+	a := newTestApp(t)
+	stubPassword(t, testMasterPassword)
+	addTestEntry(t, a, "github", "hunter2")
+	if _, err := runCommand(t, a, "add", "api-key", "--password", "s3cret-value", "--write-only"); err != nil {
+		t.Fatalf("add --write-only: %v", err)
+	}
+
+	// Every way of revealing the secret is refused
+	for _, flag := range []string{"--show-password", "--copy", "--view", "--raw", "--mask"} {
+		output, err := runCommand(t, a, "get", "api-key", flag)
+		if errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("get %s of a write-only entry: err = %v, want invalid input", flag, err)
+		}
+		if strings.Contains(output, "s3cret-value") {
+			t.Errorf("get %s printed the write-only secret", flag)
+		}
+	}
+
+	// Decrypted exports leave the entry out
+	output, err := runCommand(t, a, "export", "--decrypt", "--yes", "--stdout")
+	if err != nil {
+		t.Fatalf("export --decrypt: %v", err)
+	}
+	var exported ExportData
+	if err := json.Unmarshal([]byte(output), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Entries) != 1 || exported.Entries[0].Name != "github" {
+		t.Errorf("decrypted export = %v, want only github", exported.Entries)
+	}
+
+	// The secret can still be verified
+	stubPassword(t, "s3cret-value")
+	output, err = runCommand(t, a, "verify", "api-key")
+	if err != nil {
+		t.Fatalf("verify with the stored value: %v", err)
+	}
+	if strings.Contains(output, "s3cret-value") {
+		t.Errorf("verify printed the write-only secret")
+	}
+
+	stubPassword(t, "wrong-value")
+	if _, err := runCommand(t, a, "verify", "api-key"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("verify with a wrong value: err = %v, want invalid input", err)
+	}
+}
+
+func TestTestPasswordAlias(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	for _, command := range []string{"verify", "test-password"} {
+		stubPassword(t, "hunter2")
+		output, err := runCommand(t, a, command, "github")
+		if err != nil {
+			t.Fatalf("%s with the stored password: %v", command, err)
+		}
+		if !strings.Contains(output, "Value matches the secret stored for github") {
+			t.Errorf("%s printed:\n%s", command, output)
+		}
+
+		stubPassword(t, "hunter3")
+		if _, err := runCommand(t, a, command, "github"); errs.ExitCode(err) != errs.ExitInvalidInput {
+			t.Errorf("%s with a wrong password: err = %v, want invalid input", command, err)
+		}
+	}
+}
+
+// corrupt returns a copy of ciphertext that fails authentication.
+func corrupt(ciphertext []byte) []byte {
+	corrupted := slices.Clone(ciphertext)
+	corrupted[len(corrupted)-1] ^= 0xff
+	return corrupted
+}
+
+func TestVerifyVault(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	output, err := runCommand(t, a, "verify")
+	if err != nil {
+		t.Fatalf("verify of a healthy vault: %v", err)
+	}
+	if !strings.Contains(output, "Verified 1 entries: 1 healthy, 0 failing") {
+		t.Errorf("verify printed:\n%s", output)
+	}
+
+	bank := addTestEntry(t, a, "bank", "hunter2")
+	bank.Password = corrupt(bank.Password)
+	mail := addTestEntry(t, a, "mail", "hunter2")
+	mail.Folder = "personal"
+	mail.SecureNotes = corrupt(mail.Password)
+	api := addTestEntry(t, a, "api", "hunter2")
+	api.CustomFields = corrupt(api.Password)
+	for _, entry := range []*storage.Entry{bank, mail, api} {
+		if err := a.Storage.UpdateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err = runCommand(t, a, "verify")
+	if errs.ExitCode(err) != errs.ExitInternal {
+		t.Errorf("verify of a corrupted vault: err = %v, want internal error", err)
+	}
+	for _, want := range []string{
+		"FAILED  bank (password)",
+		"FAILED  personal/mail (notes)",
+		"FAILED  api (custom fields)",
+		"Verified 4 entries: 1 healthy, 3 failing",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("verify output is missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "hunter2") {
+		t.Errorf("verify printed a secret:\n%s", output)
+	}
+
+	output, err = runCommand(t, a, "verify", "--json")
+	if errs.ExitCode(err) != errs.ExitInternal {
+		t.Errorf("verify --json of a corrupted vault: err = %v, want internal error", err)
+	}
+	var report vaultReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("parsing the report: %v\n%s", err, output)
+	}
+	var failing []string
+	for _, entry := range report.Failing {
+		failing = append(failing, entry.Name+":"+entry.Field)
+	}
+	slices.Sort(failing)
+	if report.Total != 4 || !slices.Equal(report.Healthy, []string{"github"}) ||
+		!slices.Equal(failing, []string{"api:custom fields", "bank:password", "mail:notes"}) {
+		t.Errorf("report = %+v", report)
+	}
+
+	if _, err := runCommand(t, a, "verify", "github", "--json"); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("verify <name> --json: err = %v, want invalid input", err)
+	}
+}
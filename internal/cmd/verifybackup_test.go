@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jayakrishnanMurali/passio/internal/errs"
+	"github.com/jayakrishnanMurali/passio/internal/storage"
+)
+
+// backupTestVault backs up s to a file in a temporary directory
+// and returns its path.
+func backupTestVault(t *testing.T, s storage.Storage) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := s.Backup(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyBackup(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+	addTestEntry(t, a, "gitlab", "swordfish")
+	path := backupTestVault(t, a.Storage)
+
+	output, err := runCommand(t, a, "verify-backup", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Integrity check: ok", "Entries: 2", "2 sampled entries ok", "Backup is usable"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("verify-backup output %q lacks %q", output, want)
+		}
+	}
+
+	// Compressed backups are decompressed first
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := path + ".gz"
+	file, err := os.Create(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write(data)
+	gz.Close()
+	file.Close()
+
+	if output, err := runCommand(t, a, "verify-backup", compressed); err != nil || !strings.Contains(output, "Backup is usable") {
+		t.Errorf("verify-backup of a compressed backup = %q, %v", output, err)
+	}
+}
+
+func TestVerifyBackupRejectsUnusableBackups(t *testing.T) {
+	a := newTestApp(t)
+	addTestEntry(t, a, "github", "hunter2")
+
+	junk := filepath.Join(t.TempDir(), "junk.db")
+	if err := os.WriteFile(junk, []byte(strings.Repeat("junk", 1024)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(t, a, "verify-backup", junk); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("verify-backup of a junk file: %v, want an invalid input error", err)
+	}
+
+	if _, err := runCommand(t, a, "verify-backup", filepath.Join(t.TempDir(), "missing.db")); errs.ExitCode(err) != errs.ExitNotFound {
+		t.Errorf("verify-backup of a missing file: %v, want a not found error", err)
+	}
+
+	// A backup of another vault does not decrypt with this master key
+	other := newTestApp(t)
+	entry := storage.NewEntry("bank", "user", []byte("not encrypted with this key"))
+	if err := other.Storage.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(t, a, "verify-backup", backupTestVault(t, other.Storage)); errs.ExitCode(err) != errs.ExitInvalidInput {
+		t.Errorf("verify-backup of another vault's backup: %v, want an invalid input error", err)
+	}
+}
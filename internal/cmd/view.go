@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	enterAltScreen = "\033[?1049h\033[H\033[2J"
+	exitAltScreen  = "\033[?1049l"
+)
+
+// canUseAltScreen reports whether a secret can be shown in the terminal's
+// alternate screen buffer, which needs both stdin and stdout on a terminal.
+func canUseAltScreen(stdin, stdout *os.File) bool {
+	return isTerminal(stdin) && isTerminal(stdout)
+}
+
+// viewSecret shows secret without leaving it in the scrollback. On a
+// terminal it is shown in the alternate screen buffer until a key is
+// pressed. Otherwise it is only printed after the user confirms.
+func viewSecret(label, secret string) error {
+	if !canUseAltScreen(os.Stdin, os.Stdout) {
+		fmt.Fprintf(os.Stderr, "WARNING: output is not a terminal, the %s will be printed in plain text.\n", strings.ToLower(label))
+		fmt.Fprint(os.Stderr, "Print it anyway? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			fmt.Fprintln(os.Stderr, "Not printed")
+			return nil
+		}
+
+		fmt.Printf("%s: %s\n", label, secret)
+		return nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set terminal mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	// Raw mode disables output processing, so lines end with \r\n
+	fmt.Print(enterAltScreen)
+	fmt.Printf("%s: %s\r\n\r\nPress any key to continue...", label, secret)
+	defer fmt.Print(exitAltScreen)
+
+	key := make([]byte, 1)
+	if _, err := os.Stdin.Read(key); err != nil {
+		return fmt.Errorf("failed to read key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetViewWithoutTerminal(t *testing.T) {
+	tests := []struct {
+		response string
+		printed  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+	}
+
+	for _, tt := range tests {
+		a := newTestApp(t)
+		a.Config.AccessLog = true
+		stubPassword(t, testMasterPassword)
+		addTestEntry(t, a, "github", "hunter2")
+
+		stubStdin(t, tt.response)
+		if canUseAltScreen(os.Stdin, os.Stdout) {
+			t.Fatal("canUseAltScreen accepted a regular file as stdin")
+		}
+
+		output, err := runCommand(t, a, "get", "github", "--view")
+		if err != nil {
+			t.Fatalf("get --view answering %q: %v", tt.response, err)
+		}
+		if printed := strings.Contains(output, "Password: hunter2"); printed != tt.printed {
+			t.Errorf("get --view answering %q printed the password: %v, want %v", tt.response, printed, tt.printed)
+		}
+		if strings.Contains(output, enterAltScreen) {
+			t.Errorf("get --view switched screens without a terminal")
+		}
+
+		// Viewing counts as a reveal even if the user declined to print
+		if got := accessLog(t, a); len(got) != 1 || got[0] != "github reveal" {
+			t.Errorf("access log = %q, want a single github reveal", got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"crypto/sha256"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ChaChaEncryption encrypts with ChaCha20-Poly1305, which is faster than
+// AES-GCM on CPUs without AES instructions. Keys are derived as with
+// AESEncryption.
+type ChaChaEncryption struct{}
+
+func NewChaChaEncryption() *ChaChaEncryption {
+	return &ChaChaEncryption{}
+}
+
+// Encrypt encrypts data with ChaCha20-Poly1305. The result is the
+// CipherChaCha20Poly1305 version byte, the nonce and the sealed data.
+func (e *ChaChaEncryption) Encrypt(data []byte, key []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, CipherChaCha20Poly1305, data)
+}
+
+// Decrypt decrypts data encrypted by any Encryption.
+func (e *ChaChaEncryption) Decrypt(data []byte, key []byte) ([]byte, error) {
+	return decrypt(data, key)
+}
+
+func (e *ChaChaEncryption) DeriveKey(password string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(password), salt, KDFIterations, KeyLength, sha256.New)
+}
+
+func (e *ChaChaEncryption) DeriveKeyWithParams(password string, salt []byte, params KDFParams) ([]byte, error) {
+	return params.deriveKey(password, salt)
+}
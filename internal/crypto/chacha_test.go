@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChaChaRoundTrip(t *testing.T) {
+	e := NewChaChaEncryption()
+	key := testKey(t)
+
+	encrypted, err := e.Encrypt([]byte("hunter2"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted[0] != CipherChaCha20Poly1305 {
+		t.Errorf("version byte = %d, want %d", encrypted[0], CipherChaCha20Poly1305)
+	}
+
+	decrypted, err := e.Decrypt(encrypted, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != "hunter2" {
+		t.Errorf("Decrypt(Encrypt(hunter2)) = %q", decrypted)
+	}
+}
+
+func TestChaChaDetectsTampering(t *testing.T) {
+	e := NewChaChaEncryption()
+	key := testKey(t)
+	encrypted, err := e.Encrypt([]byte("hunter2"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range encrypted {
+		tampered := append([]byte(nil), encrypted...)
+		tampered[i] ^= 0x01
+		if _, err := e.Decrypt(tampered, key); err == nil {
+			t.Errorf("decrypted with byte %d flipped", i)
+		}
+	}
+	for n := 0; n < len(encrypted); n++ {
+		if _, err := e.Decrypt(encrypted[:n], key); err == nil {
+			t.Errorf("decrypted the first %d bytes", n)
+		}
+	}
+}
+
+// Each Encryption decrypts what the other encrypted, as the version byte
+// selects the cipher, but a ciphertext relabelled as the other cipher fails.
+func TestCiphersAreNotInterchangeable(t *testing.T) {
+	key := testKey(t)
+	aes, chacha := NewAESEncryption(), NewChaChaEncryption()
+
+	tests := []struct {
+		name      string
+		encrypt   Encryption
+		decrypt   Encryption
+		relabelAs byte
+	}{
+		{"aes as chacha", aes, chacha, CipherChaCha20Poly1305},
+		{"chacha as aes", chacha, aes, CipherAESGCM},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encrypted, err := test.encrypt.Encrypt([]byte("hunter2"), key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decrypted, err := test.decrypt.Decrypt(encrypted, key)
+			if err != nil || string(decrypted) != "hunter2" {
+				t.Fatalf("Decrypt() = %q, %v", decrypted, err)
+			}
+
+			relabelled := append([]byte(nil), encrypted...)
+			relabelled[0] = test.relabelAs
+			aead, err := newAEAD(test.relabelAs, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := openAEAD(aead, relabelled[1:], relabelled[:1]); err == nil {
+				t.Error("opened the ciphertext with the other cipher")
+			}
+			if _, err := test.decrypt.Decrypt(relabelled, key); err == nil {
+				t.Error("decrypted the ciphertext relabelled as the other cipher")
+			}
+		})
+	}
+}
+
+func TestNewEncryption(t *testing.T) {
+	key := testKey(t)
+
+	tests := map[string]byte{
+		"":               CipherAESGCM,
+		AESGCM:           CipherAESGCM,
+		ChaCha20Poly1305: CipherChaCha20Poly1305,
+	}
+	for name, version := range tests {
+		e, err := NewEncryption(name)
+		if err != nil {
+			t.Fatalf("NewEncryption(%q): %v", name, err)
+		}
+		encrypted, err := e.Encrypt([]byte("hunter2"), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted[0] != version {
+			t.Errorf("NewEncryption(%q) encrypts with version %d, want %d", name, encrypted[0], version)
+		}
+	}
+
+	if _, err := NewEncryption("rot13"); err == nil {
+		t.Error("NewEncryption accepted an unknown cipher")
+	}
+}
+
+func TestCiphersDeriveTheSameKeys(t *testing.T) {
+	salt := bytes.Repeat([]byte{7}, SaltLength)
+	params := KDFParams{Algorithm: KDFPBKDF2, Iterations: KDFIterations}
+
+	aesKey, err := NewAESEncryption().DeriveKeyWithParams("hunter2", salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chachaKey, err := NewChaChaEncryption().DeriveKeyWithParams("hunter2", salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(aesKey, chachaKey) {
+		t.Error("switching cipher changes the master key")
+	}
+}
@@ -5,14 +5,63 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
+	"fmt"
 
+	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/pbkdf2"
 )
 
+// Key derivation parameters of master keys derived before the choice of KDF,
+// which DeriveKey still uses
+const (
+	KDFAlgorithm  = "pbkdf2-sha256"
+	KDFIterations = 4096
+	KeyLength     = 32
+	// SaltLength is the length in bytes of newly generated salts
+	SaltLength = 32
+	// MinSaltLength is the shortest salt accepted for a master key
+	MinSaltLength = 16
+)
+
+// Cipher versions, stored as the first byte of every ciphertext so that the
+// cipher can change without breaking existing data. The version byte is
+// authenticated along with the ciphertext. Ciphertexts written before
+// versioning start directly with the nonce.
+const (
+	// CipherAESGCM is AES-256-GCM with a random 96-bit nonce
+	CipherAESGCM byte = 1
+	// CipherChaCha20Poly1305 is ChaCha20-Poly1305 with a random 96-bit nonce
+	CipherChaCha20Poly1305 byte = 2
+)
+
+// Names of the ciphers new data can be encrypted with
+const (
+	AESGCM           = "aes-gcm"
+	ChaCha20Poly1305 = "chacha20-poly1305"
+)
+
+// NewEncryption returns the Encryption encrypting with the named cipher. An
+// empty name selects AESGCM. Every Encryption decrypts data encrypted by any
+// of them.
+func NewEncryption(name string) (Encryption, error) {
+	switch name {
+	case "", AESGCM:
+		return NewAESEncryption(), nil
+	case ChaCha20Poly1305:
+		return NewChaChaEncryption(), nil
+	default:
+		return nil, fmt.Errorf("unsupported cipher %q (use %s or %s)", name, AESGCM, ChaCha20Poly1305)
+	}
+}
+
 type Encryption interface {
 	Encrypt(data []byte, key []byte) ([]byte, error)
 	Decrypt(data []byte, key []byte) ([]byte, error)
 	DeriveKey(password string, salt []byte) []byte
+	// DeriveKeyWithParams derives a key with the given key derivation
+	// function and parameters
+	DeriveKeyWithParams(password string, salt []byte, params KDFParams) ([]byte, error)
 }
 
 type AESEncryption struct{}
@@ -21,44 +70,87 @@ func NewAESEncryption() *AESEncryption {
 	return &AESEncryption{}
 }
 
+// Encrypt encrypts data with AES-256-GCM. The result is the CipherAESGCM
+// version byte, the nonce and the sealed data.
 func (e *AESEncryption) Encrypt(data []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
+	gcm, err := newAESGCM(key)
 	if err != nil {
 		return nil, err
 	}
+	return seal(gcm, CipherAESGCM, data)
+}
+
+// Decrypt decrypts data encrypted by any Encryption.
+func (e *AESEncryption) Decrypt(data []byte, key []byte) ([]byte, error) {
+	return decrypt(data, key)
+}
 
-	gcm, err := cipher.NewGCM(block)
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
+	return cipher.NewGCM(block)
+}
 
-	nonce := make([]byte, gcm.NonceSize())
+// newAEAD returns the cipher of the given version keyed with key.
+func newAEAD(version byte, key []byte) (cipher.AEAD, error) {
+	switch version {
+	case CipherAESGCM:
+		return newAESGCM(key)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unknown cipher version %d", version)
+	}
+}
+
+// seal encrypts data with aead under a random nonce and prefixes it with the
+// version byte and the nonce.
+func seal(aead cipher.AEAD, version byte, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, err
 	}
 
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	header := append([]byte{version}, nonce...)
+	return aead.Seal(header, nonce, data, header[:1]), nil
 }
 
-func (e *AESEncryption) Decrypt(data []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
+// decrypt decrypts data sealed by seal, dispatching on its version byte.
+// Data without a known version byte, or that only fails to authenticate with
+// it, is decrypted as AES-GCM data written before versioning, as its first
+// nonce byte may happen to equal a version.
+func decrypt(data, key []byte) ([]byte, error) {
+	if len(data) > 0 {
+		if aead, err := newAEAD(data[0], key); err == nil {
+			if plain, err := openAEAD(aead, data[1:], data[:1]); err == nil {
+				return plain, nil
+			}
+		}
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newAESGCM(key)
 	if err != nil {
 		return nil, err
 	}
+	return openAEAD(gcm, data, nil)
+}
 
-	if len(data) < gcm.NonceSize() {
-		return nil, err
+// openAEAD opens data made of a nonce followed by the sealed text.
+func openAEAD(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
 	}
 
-	nonce, cipherText := data[:gcm.NonceSize()], data[gcm.NonceSize():]
-	return gcm.Open(nil, nonce, cipherText, nil)
+	nonce, cipherText := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, cipherText, additionalData)
 }
 
 func (e *AESEncryption) DeriveKey(password string, salt []byte) []byte {
-	return pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New)
+	return pbkdf2.Key([]byte(password), salt, KDFIterations, KeyLength, sha256.New)
+}
+
+func (e *AESEncryption) DeriveKeyWithParams(password string, salt []byte, params KDFParams) ([]byte, error) {
+	return params.deriveKey(password, salt)
 }
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"testing"
+)
+
+// testKey returns a random key of KeyLength bytes.
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeyLength)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// legacyEncrypt encrypts data with AES-GCM as before ciphertexts were
+// versioned: the nonce followed by the sealed data.
+func legacyEncrypt(t *testing.T, data, key, nonce []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gcm.Seal(append([]byte(nil), nonce...), nonce, data, nil)
+}
+
+func TestAESRoundTrip(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+
+	for _, plain := range [][]byte{[]byte("hunter2"), {}, bytes.Repeat([]byte{0xff}, 4096)} {
+		encrypted, err := e.Encrypt(plain, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted[0] != CipherAESGCM {
+			t.Errorf("version byte = %d, want %d", encrypted[0], CipherAESGCM)
+		}
+
+		decrypted, err := e.Decrypt(encrypted, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, plain) {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q", plain, decrypted)
+		}
+	}
+}
+
+func TestEncryptUsesFreshNonces(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+
+	first, _ := e.Encrypt([]byte("hunter2"), key)
+	second, _ := e.Encrypt([]byte("hunter2"), key)
+	if bytes.Equal(first, second) {
+		t.Error("encrypting the same data twice gave the same ciphertext")
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	e := NewAESEncryption()
+	encrypted, err := e.Encrypt([]byte("hunter2"), testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Decrypt(encrypted, testKey(t)); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
+
+func TestDecryptLegacyFormat(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+
+	// The first nonce byte may equal a cipher version
+	for _, first := range []byte{0, CipherAESGCM, CipherChaCha20Poly1305, 0xff} {
+		nonce := make([]byte, 12)
+		rand.Read(nonce)
+		nonce[0] = first
+
+		decrypted, err := e.Decrypt(legacyEncrypt(t, []byte("hunter2"), key, nonce), key)
+		if err != nil {
+			t.Fatalf("nonce starting with %d: %v", first, err)
+		}
+		if string(decrypted) != "hunter2" {
+			t.Errorf("nonce starting with %d: decrypted %q", first, decrypted)
+		}
+	}
+}
+
+func TestFlippedVersionByteFailsAuthentication(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+	encrypted, err := e.Encrypt([]byte("hunter2"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []byte{0, CipherChaCha20Poly1305, 0x7f} {
+		tampered := append([]byte(nil), encrypted...)
+		tampered[0] = version
+		if _, err := e.Decrypt(tampered, key); err == nil {
+			t.Errorf("decrypted with the version byte changed to %d", version)
+		}
+	}
+}
+
+func TestDecryptTamperedCiphertext(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+	encrypted, err := e.Encrypt([]byte("hunter2"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(encrypted); i++ {
+		tampered := append([]byte(nil), encrypted...)
+		tampered[i] ^= 0x01
+		if _, err := e.Decrypt(tampered, key); err == nil {
+			t.Errorf("decrypted with byte %d flipped", i)
+		}
+	}
+}
+
+func TestDecryptTruncatedInput(t *testing.T) {
+	e := NewAESEncryption()
+	key := testKey(t)
+	encrypted, err := e.Encrypt([]byte("hunter2"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < len(encrypted); n++ {
+		if _, err := e.Decrypt(encrypted[:n], key); err == nil {
+			t.Errorf("decrypted the first %d bytes", n)
+		}
+	}
+	if _, err := e.Decrypt(nil, key); err == nil {
+		t.Error("decrypted nil")
+	}
+}
+
+func TestEncryptRejectsBadKey(t *testing.T) {
+	if _, err := NewAESEncryption().Encrypt([]byte("hunter2"), make([]byte, 7)); err == nil {
+		t.Error("encrypted with a 7-byte key")
+	}
+}
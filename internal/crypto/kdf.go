@@ -0,0 +1,149 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Key derivation functions the master key can be derived with
+const (
+	KDFPBKDF2   = "pbkdf2"
+	KDFArgon2id = "argon2id"
+)
+
+// PBKDF2Iterations is the iteration count of new PBKDF2 keys, as recommended
+// by OWASP for PBKDF2-HMAC-SHA256
+const PBKDF2Iterations = 600000
+
+// Default Argon2id parameters, the second recommended option of RFC 9106
+const (
+	Argon2idIterations  = 3
+	Argon2idMemory      = 64 * 1024 // KiB
+	Argon2idParallelism = 4
+)
+
+// minPBKDF2Iterations is the fewest PBKDF2 iterations accepted. It is the
+// iteration count of vaults created before the choice of KDF, which must
+// still unlock.
+const minPBKDF2Iterations = KDFIterations
+
+// KDFParams selects the key derivation function of the master key and its
+// cost. The zero value is PBKDF2 with KDFIterations iterations, which vaults
+// created before the choice of KDF use.
+type KDFParams struct {
+	Algorithm   string `json:"algorithm,omitempty"`   // KDFPBKDF2 or KDFArgon2id
+	Iterations  uint32 `json:"iterations,omitempty"`  // PBKDF2 iterations or Argon2id passes
+	Memory      uint32 `json:"memory,omitempty"`      // Argon2id memory in KiB
+	Parallelism uint8  `json:"parallelism,omitempty"` // Argon2id threads
+}
+
+// DefaultKDFParams returns the recommended parameters of algorithm for new keys.
+func DefaultKDFParams(algorithm string) (KDFParams, error) {
+	switch algorithm {
+	case "", KDFPBKDF2:
+		return KDFParams{Algorithm: KDFPBKDF2, Iterations: PBKDF2Iterations}, nil
+	case KDFArgon2id:
+		return KDFParams{
+			Algorithm:   KDFArgon2id,
+			Iterations:  Argon2idIterations,
+			Memory:      Argon2idMemory,
+			Parallelism: Argon2idParallelism,
+		}, nil
+	default:
+		return KDFParams{}, fmt.Errorf("unsupported key derivation function %q (use %s or %s)", algorithm, KDFPBKDF2, KDFArgon2id)
+	}
+}
+
+// Normalized returns p with the algorithm and any unset parameters filled in
+// with their defaults. Without an algorithm, p describes a key derived before
+// the choice of KDF, with KDFIterations PBKDF2 iterations unless set.
+func (p KDFParams) Normalized() KDFParams {
+	if p.Algorithm == "" {
+		p.Algorithm = KDFPBKDF2
+		if p.Iterations == 0 {
+			p.Iterations = KDFIterations
+		}
+	}
+
+	defaults, err := DefaultKDFParams(p.Algorithm)
+	if err != nil {
+		return p
+	}
+
+	if p.Iterations == 0 {
+		p.Iterations = defaults.Iterations
+	}
+	if p.Memory == 0 {
+		p.Memory = defaults.Memory
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = defaults.Parallelism
+	}
+	p.Algorithm = defaults.Algorithm
+	return p
+}
+
+// Upgraded returns p with any cost parameter below the recommended defaults of
+// its algorithm raised to them.
+func (p KDFParams) Upgraded() KDFParams {
+	p = p.Normalized()
+	defaults, err := DefaultKDFParams(p.Algorithm)
+	if err != nil {
+		return p
+	}
+
+	p.Iterations = max(p.Iterations, defaults.Iterations)
+	p.Memory = max(p.Memory, defaults.Memory)
+	p.Parallelism = max(p.Parallelism, defaults.Parallelism)
+	return p
+}
+
+// Name returns the descriptive name of the algorithm, e.g. "pbkdf2-sha256".
+func (p KDFParams) Name() string {
+	if p.Normalized().Algorithm == KDFPBKDF2 {
+		return KDFAlgorithm
+	}
+	return p.Algorithm
+}
+
+// Validate checks that the algorithm is supported and its parameters are
+// within bounds.
+func (p KDFParams) Validate() error {
+	if _, err := DefaultKDFParams(p.Algorithm); err != nil {
+		return err
+	}
+
+	p = p.Normalized()
+	switch p.Algorithm {
+	case KDFPBKDF2:
+		if p.Memory != 0 || p.Parallelism != 0 {
+			return fmt.Errorf("memory and parallelism only apply to %s", KDFArgon2id)
+		}
+		if p.Iterations < minPBKDF2Iterations {
+			return fmt.Errorf("%s needs at least %d iterations", KDFPBKDF2, minPBKDF2Iterations)
+		}
+	case KDFArgon2id:
+		if p.Memory < 8*uint32(p.Parallelism) {
+			return fmt.Errorf("%s memory must be at least 8 KiB per thread", KDFArgon2id)
+		}
+	}
+	return nil
+}
+
+// deriveKey derives a KeyLength key from password and salt with p.
+func (p KDFParams) deriveKey(password string, salt []byte) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	p = p.Normalized()
+	switch p.Algorithm {
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, KeyLength), nil
+	default:
+		return pbkdf2.Key([]byte(password), salt, int(p.Iterations), KeyLength, sha256.New), nil
+	}
+}
@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func TestZeroKDFParamsAreLegacyPBKDF2(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, SaltLength)
+
+	if got := (KDFParams{}).Normalized(); got != (KDFParams{Algorithm: KDFPBKDF2, Iterations: KDFIterations}) {
+		t.Errorf("KDFParams{}.Normalized() = %+v", got)
+	}
+
+	key, err := NewAESEncryption().DeriveKeyWithParams("hunter2", salt, KDFParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := pbkdf2.Key([]byte("hunter2"), salt, 4096, KeyLength, sha256.New)
+	if !bytes.Equal(key, legacy) {
+		t.Error("the zero parameters do not derive the key of vaults created with 4096 iterations")
+	}
+	if !bytes.Equal(NewAESEncryption().DeriveKey("hunter2", salt), legacy) {
+		t.Error("DeriveKey no longer derives the legacy key")
+	}
+}
+
+func TestUpgraded(t *testing.T) {
+	tests := []struct {
+		params KDFParams
+		want   KDFParams
+	}{
+		{KDFParams{}, KDFParams{Algorithm: KDFPBKDF2, Iterations: PBKDF2Iterations}},
+		{KDFParams{Algorithm: KDFPBKDF2, Iterations: 1000000}, KDFParams{Algorithm: KDFPBKDF2, Iterations: 1000000}},
+		{
+			KDFParams{Algorithm: KDFArgon2id, Iterations: 1, Memory: 1024, Parallelism: 8},
+			KDFParams{Algorithm: KDFArgon2id, Iterations: Argon2idIterations, Memory: Argon2idMemory, Parallelism: 8},
+		},
+	}
+	for _, test := range tests {
+		if got := test.params.Upgraded(); got != test.want {
+			t.Errorf("%+v.Upgraded() = %+v, want %+v", test.params, got, test.want)
+		}
+	}
+}
+
+func TestValidateKDFParams(t *testing.T) {
+	valid := []KDFParams{
+		{},
+		{Algorithm: KDFPBKDF2, Iterations: KDFIterations},
+		{Algorithm: KDFPBKDF2, Iterations: PBKDF2Iterations},
+		{Algorithm: KDFArgon2id},
+	}
+	for _, params := range valid {
+		if err := params.Validate(); err != nil {
+			t.Errorf("%+v.Validate() = %v", params, err)
+		}
+	}
+
+	invalid := []KDFParams{
+		{Algorithm: "scrypt"},
+		{Algorithm: KDFPBKDF2, Iterations: KDFIterations - 1},
+		{Algorithm: KDFPBKDF2, Memory: 1024},
+		{Algorithm: KDFArgon2id, Memory: 8, Parallelism: 4},
+	}
+	for _, params := range invalid {
+		if err := params.Validate(); err == nil {
+			t.Errorf("%+v.Validate() accepted invalid parameters", params)
+		}
+		if _, err := params.deriveKey("hunter2", []byte("salt")); err == nil {
+			t.Errorf("derived a key with invalid parameters %+v", params)
+		}
+	}
+}
+
+func TestIterationsChangeTheKey(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, SaltLength)
+	e := NewAESEncryption()
+
+	legacy, err := e.DeriveKeyWithParams("hunter2", salt, KDFParams{Algorithm: KDFPBKDF2, Iterations: KDFIterations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, err := e.DeriveKeyWithParams("hunter2", salt, KDFParams{Algorithm: KDFPBKDF2, Iterations: KDFIterations + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(legacy, more) {
+		t.Error("the iteration count does not affect the key")
+	}
+}
+
+func TestDefaultKDFParams(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      KDFParams
+		wantErr   bool
+	}{
+		{"", KDFParams{Algorithm: KDFPBKDF2, Iterations: PBKDF2Iterations}, false},
+		{KDFPBKDF2, KDFParams{Algorithm: KDFPBKDF2, Iterations: PBKDF2Iterations}, false},
+		{KDFArgon2id, KDFParams{Algorithm: KDFArgon2id, Iterations: Argon2idIterations, Memory: Argon2idMemory, Parallelism: Argon2idParallelism}, false},
+		{"scrypt", KDFParams{}, true},
+	}
+	for _, test := range tests {
+		got, err := DefaultKDFParams(test.algorithm)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("DefaultKDFParams(%q) = %+v, %v, want %+v", test.algorithm, got, err, test.want)
+		}
+		if err == nil && got.Normalized() != got {
+			t.Errorf("the defaults of %q are not normalized: %+v", test.algorithm, got.Normalized())
+		}
+	}
+
+	if got := (KDFParams{Algorithm: KDFArgon2id, Memory: 1024}).Normalized(); got != (KDFParams{Algorithm: KDFArgon2id, Iterations: Argon2idIterations, Memory: 1024, Parallelism: Argon2idParallelism}) {
+		t.Errorf("Normalized kept unset Argon2id parameters: %+v", got)
+	}
+	if got := (KDFParams{Algorithm: KDFArgon2id}).Name(); got != KDFArgon2id {
+		t.Errorf("Name() = %q, want %q", got, KDFArgon2id)
+	}
+}
+
+func TestArgon2idDerivesKey(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, SaltLength)
+	e := NewAESEncryption()
+	params := KDFParams{Algorithm: KDFArgon2id, Iterations: 1, Memory: 64, Parallelism: 1}
+
+	key, err := e.DeriveKeyWithParams("hunter2", salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := argon2.IDKey([]byte("hunter2"), salt, 1, 64, 1, KeyLength); !bytes.Equal(key, want) {
+		t.Error("the key is not the Argon2id key of the parameters")
+	}
+
+	// Every parameter is part of the key
+	for _, changed := range []KDFParams{
+		{Algorithm: KDFArgon2id, Iterations: 2, Memory: 64, Parallelism: 1},
+		{Algorithm: KDFArgon2id, Iterations: 1, Memory: 128, Parallelism: 1},
+		{Algorithm: KDFArgon2id, Iterations: 1, Memory: 64, Parallelism: 2},
+		{Algorithm: KDFPBKDF2, Iterations: KDFIterations},
+	} {
+		other, err := e.DeriveKeyWithParams("hunter2", salt, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(key, other) {
+			t.Errorf("%+v derives the same key as %+v", changed, params)
+		}
+	}
+}
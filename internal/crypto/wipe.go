@@ -0,0 +1,8 @@
+package crypto
+
+// Wipe overwrites b with zeros, so that keys and plaintext do not linger in
+// memory after use. Copies of the data, such as strings made from b, are not
+// affected.
+func Wipe(b []byte) {
+	clear(b)
+}
@@ -0,0 +1,14 @@
+package crypto
+
+import "testing"
+
+func TestWipe(t *testing.T) {
+	b := []byte("secret key")
+	Wipe(b[:6])
+	if string(b) != "\x00\x00\x00\x00\x00\x00 key" {
+		t.Errorf("Wipe left %q", b)
+	}
+
+	// Wiping nothing is harmless
+	Wipe(nil)
+}
@@ -0,0 +1,101 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Kind string
+
+const (
+	KindNotFound     Kind = "not_found"
+	KindLocked       Kind = "locked"
+	KindConflict     Kind = "conflict"
+	KindInvalidInput Kind = "invalid_input"
+	KindInternal     Kind = "internal"
+)
+
+// Exit codes returned by the CLI for each kind of error
+const (
+	ExitInternal     = 1
+	ExitInvalidInput = 2
+	ExitNotFound     = 3
+	ExitLocked       = 4
+	ExitConflict     = 5
+)
+
+// ExitCoder is implemented by errors that carry a process exit code.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ExitCode() int {
+	switch e.Kind {
+	case KindNotFound:
+		return ExitNotFound
+	case KindLocked:
+		return ExitLocked
+	case KindConflict:
+		return ExitConflict
+	case KindInvalidInput:
+		return ExitInvalidInput
+	default:
+		return ExitInternal
+	}
+}
+
+func newError(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return newError(KindNotFound, format, args...)
+}
+
+func Locked(format string, args ...interface{}) error {
+	return newError(KindLocked, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return newError(KindConflict, format, args...)
+}
+
+func InvalidInput(format string, args ...interface{}) error {
+	return newError(KindInvalidInput, format, args...)
+}
+
+func Internal(format string, args ...interface{}) error {
+	return newError(KindInternal, format, args...)
+}
+
+// KindOf returns the kind of err, or KindInternal if err is not typed.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// ExitCode returns the exit code for err, defaulting to ExitInternal.
+func ExitCode(err error) int {
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	return ExitInternal
+}
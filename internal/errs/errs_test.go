@@ -0,0 +1,64 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+		kind Kind
+	}{
+		{NotFound("entry %s not found", "github"), ExitNotFound, KindNotFound},
+		{Locked("passio is locked"), ExitLocked, KindLocked},
+		{Conflict("entry exists"), ExitConflict, KindConflict},
+		{InvalidInput("bad flag"), ExitInvalidInput, KindInvalidInput},
+		{Internal("disk full"), ExitInternal, KindInternal},
+		{errors.New("untyped"), ExitInternal, KindInternal},
+		{&Error{Kind: "unknown", Err: io.EOF}, ExitInternal, "unknown"},
+	}
+	for _, test := range tests {
+		if got := ExitCode(test.err); got != test.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", test.err, got, test.want)
+		}
+		if got := KindOf(test.err); got != test.kind {
+			t.Errorf("KindOf(%v) = %s, want %s", test.err, got, test.kind)
+		}
+	}
+}
+
+func TestExitCodeOfWrappedError(t *testing.T) {
+	err := fmt.Errorf("get failed: %w", NotFound("entry %s not found", "github"))
+	if got := ExitCode(err); got != ExitNotFound {
+		t.Errorf("ExitCode() = %d for a wrapped not found error", got)
+	}
+	if got := KindOf(err); got != KindNotFound {
+		t.Errorf("KindOf() = %s for a wrapped not found error", got)
+	}
+}
+
+func TestErrorWrapsCause(t *testing.T) {
+	err := Internal("failed to read: %w", io.EOF)
+	if !errors.Is(err, io.EOF) {
+		t.Error("the cause is not reachable with errors.Is")
+	}
+	if err.Error() != "failed to read: EOF" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+// exitError carries its own exit code, as errors from other packages may
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return "exit" }
+func (e exitError) ExitCode() int { return e.code }
+
+func TestExitCoder(t *testing.T) {
+	if got := ExitCode(fmt.Errorf("wrapped: %w", exitError{code: 42})); got != 42 {
+		t.Errorf("ExitCode() = %d, want the ExitCoder's 42", got)
+	}
+}
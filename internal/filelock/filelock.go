@@ -0,0 +1,71 @@
+// Package filelock provides advisory locks on files, held until released or
+// until the process exits.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
+
+// pollInterval is how often a held lock is retried
+const pollInterval = 100 * time.Millisecond
+
+// Lock is an exclusive advisory lock on a file.
+type Lock struct {
+	mu   sync.Mutex
+	file *os.File // nil once released
+}
+
+// Acquire takes an exclusive lock on the file at path, creating it if needed.
+// While another process holds the lock it is retried until timeout has
+// elapsed, after which ErrLocked is returned.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return &Lock{file: file}, nil
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, ErrLocked
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release releases the lock. The lock file is left in place, as removing it
+// would race with processes waiting for it. Releasing a lock again does
+// nothing.
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+
+	if err := unlock(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return file.Close()
+}
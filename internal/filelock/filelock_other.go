@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package filelock
+
+import "os"
+
+// Advisory locks are not available, so locking always succeeds
+func tryLock(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,117 @@
+//go:build unix || windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helperLockEnv names the lock file the test binary tries to acquire when run
+// as a helper process by TestSecondProcessIsLockedOut.
+const helperLockEnv = "FILELOCK_HELPER_PATH"
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(helperLockEnv); path != "" {
+		lock, err := Acquire(path, 0)
+		if errors.Is(err, ErrLocked) {
+			os.Exit(3)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		lock.Release()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// acquireInHelper tries to acquire the lock at path from another process and
+// returns its exit code: 0 if it got the lock and 3 if it was held.
+func acquireInHelper(t *testing.T, path string) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), helperLockEnv+"="+path)
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestSecondProcessIsLockedOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passio.lock")
+
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := acquireInHelper(t, path); code != 3 {
+		t.Fatalf("second process exited with %d while the lock was held, want 3 (locked)", code)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if code := acquireInHelper(t, path); code != 0 {
+		t.Fatalf("second process exited with %d after the lock was released, want 0", code)
+	}
+}
+
+func TestAcquireTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passio.lock")
+
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	start := time.Now()
+	if _, err := Acquire(path, 3*pollInterval); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire of a held lock: err = %v, want ErrLocked", err)
+	}
+	if waited := time.Since(start); waited < 3*pollInterval {
+		t.Errorf("Acquire gave up after %v, before the timeout", waited)
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passio.lock")
+
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(2*pollInterval, func() { lock.Release() })
+
+	second, err := Acquire(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	second.Release()
+}
+
+func TestReleaseTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passio.lock")
+
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("second Release: %v", err)
+	}
+}
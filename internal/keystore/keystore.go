@@ -0,0 +1,102 @@
+// Package keystore keeps derived vault keys in a secret store, such as the
+// keychain of the operating system, so that passio can be unlocked without
+// prompting for the master password.
+package keystore
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// service is the name keys are stored under in the OS keychain
+const service = "passio"
+
+var (
+	// ErrNotFound is returned when no key is stored for an account.
+	ErrNotFound = errors.New("no key stored in keystore")
+)
+
+// KeyStore stores keys by account name.
+type KeyStore interface {
+	Store(account string, key []byte) error
+	Retrieve(account string) ([]byte, error)
+	Delete(account string) error
+}
+
+// SystemKeyStore uses the keychain of the operating system: the macOS
+// Keychain, the Secret Service (libsecret) on Linux or the Windows Credential
+// Manager.
+type SystemKeyStore struct{}
+
+func NewSystemKeyStore() *SystemKeyStore {
+	return &SystemKeyStore{}
+}
+
+func (s *SystemKeyStore) Store(account string, key []byte) error {
+	if err := keyring.Set(service, account, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return fmt.Errorf("failed to store key in OS keychain: %w", err)
+	}
+	return nil
+}
+
+func (s *SystemKeyStore) Retrieve(account string) ([]byte, error) {
+	encoded, err := keyring.Get(service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key from OS keychain: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key from OS keychain: %w", err)
+	}
+	return key, nil
+}
+
+func (s *SystemKeyStore) Delete(account string) error {
+	if err := keyring.Delete(service, account); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete key from OS keychain: %w", err)
+	}
+	return nil
+}
+
+// MemoryKeyStore keeps keys in memory. It is useful where no OS keychain is
+// available.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string][]byte)}
+}
+
+func (s *MemoryKeyStore) Store(account string, key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[account] = append([]byte(nil), key...)
+	return nil
+}
+
+func (s *MemoryKeyStore) Retrieve(account string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[account]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), key...), nil
+}
+
+func (s *MemoryKeyStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, account)
+	return nil
+}
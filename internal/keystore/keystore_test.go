@@ -0,0 +1,48 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+	s := NewMemoryKeyStore()
+	key := []byte("0123456789abcdef")
+
+	if _, err := s.Retrieve("vault"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Retrieve before Store: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Store("vault", key); err != nil {
+		t.Fatal(err)
+	}
+	// The store keeps its own copy of the key
+	key[0] = 'X'
+
+	got, err := s.Retrieve("vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte("0123456789abcdef")) {
+		t.Errorf("Retrieve = %q, want the stored key", got)
+	}
+	got[1] = 'X'
+	if again, _ := s.Retrieve("vault"); !bytes.Equal(again, []byte("0123456789abcdef")) {
+		t.Errorf("changing a retrieved key changed the stored one: %q", again)
+	}
+
+	if _, err := s.Retrieve("other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retrieve of another account: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Delete("vault"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Retrieve("vault"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retrieve after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete("vault"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package otp computes time-based one-time passwords (RFC 6238).
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDigits = 6
+	defaultPeriod = 30 * time.Second
+)
+
+// ErrInvalidSecret is returned for secrets that are neither base32 nor an
+// otpauth:// URI.
+var ErrInvalidSecret = errors.New("invalid TOTP secret")
+
+// TOTP holds the parameters of a time-based one-time password.
+type TOTP struct {
+	Secret    []byte
+	Digits    int
+	Period    time.Duration
+	Algorithm func() hash.Hash
+}
+
+// Parse reads a TOTP secret given either as base32, as shown by most sites
+// when enrolling, or as an otpauth://totp/ URI from a QR code.
+func Parse(secret string) (*TOTP, error) {
+	totp := &TOTP{Digits: defaultDigits, Period: defaultPeriod, Algorithm: sha1.New}
+
+	secret = strings.TrimSpace(secret)
+	if strings.HasPrefix(strings.ToLower(secret), "otpauth://") {
+		u, err := url.Parse(secret)
+		if err != nil || u.Host != "totp" {
+			return nil, fmt.Errorf("%w: not an otpauth://totp/ URI", ErrInvalidSecret)
+		}
+
+		query := u.Query()
+		secret = query.Get("secret")
+		if v := query.Get("digits"); v != "" {
+			digits, err := strconv.Atoi(v)
+			if err != nil || digits < 6 || digits > 10 {
+				return nil, fmt.Errorf("%w: unsupported digits %q", ErrInvalidSecret, v)
+			}
+			totp.Digits = digits
+		}
+		if v := query.Get("period"); v != "" {
+			period, err := strconv.Atoi(v)
+			if err != nil || period <= 0 {
+				return nil, fmt.Errorf("%w: unsupported period %q", ErrInvalidSecret, v)
+			}
+			totp.Period = time.Duration(period) * time.Second
+		}
+		switch strings.ToUpper(query.Get("algorithm")) {
+		case "", "SHA1":
+		case "SHA256":
+			totp.Algorithm = sha256.New
+		case "SHA512":
+			totp.Algorithm = sha512.New
+		default:
+			return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSecret, query.Get("algorithm"))
+		}
+	}
+
+	// Secrets are often shown in groups, in lower case and without padding
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%w: secret is not base32", ErrInvalidSecret)
+	}
+	totp.Secret = key
+
+	return totp, nil
+}
+
+// Code returns the one-time password valid at t.
+func (t *TOTP) Code(at time.Time) string {
+	counter := uint64(at.Unix() / int64(t.Period/time.Second))
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(t.Algorithm, t.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint64(1)
+	for i := 0; i < t.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, uint64(value)%modulo)
+}
+
+// Remaining returns how long the code valid at t stays valid.
+func (t *TOTP) Remaining(at time.Time) time.Duration {
+	period := int64(t.Period / time.Second)
+	return time.Duration(period-at.Unix()%period) * time.Second
+}
@@ -0,0 +1,110 @@
+package otp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// The secrets of the RFC 6238 test vectors, in base32
+const (
+	rfcSecretSHA1   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	rfcSecretSHA256 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+	rfcSecretSHA512 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA"
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		secret string
+		at     int64
+		want   string
+	}{
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 59, "94287082"},
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 1111111109, "07081804"},
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 1111111111, "14050471"},
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 1234567890, "89005924"},
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 2000000000, "69279037"},
+		{"otpauth://totp/test?digits=8&secret=" + rfcSecretSHA1, 20000000000, "65353130"},
+		{"otpauth://totp/test?digits=8&algorithm=SHA256&secret=" + rfcSecretSHA256, 59, "46119246"},
+		{"otpauth://totp/test?digits=8&algorithm=SHA256&secret=" + rfcSecretSHA256, 1111111109, "68084774"},
+		{"otpauth://totp/test?digits=8&algorithm=SHA512&secret=" + rfcSecretSHA512, 59, "90693936"},
+		{"otpauth://totp/test?digits=8&algorithm=SHA512&secret=" + rfcSecretSHA512, 1111111109, "25091201"},
+		// Six digits by default: the last six of the eight-digit codes
+		{rfcSecretSHA1, 59, "287082"},
+		{rfcSecretSHA1, 1111111109, "081804"},
+	}
+
+	for _, tt := range tests {
+		totp, err := Parse(tt.secret)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.secret, err)
+		}
+		if got := totp.Code(time.Unix(tt.at, 0)); got != tt.want {
+			t.Errorf("Code(%d) for %q = %s, want %s", tt.at, tt.secret, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		wantDigits int
+		wantPeriod time.Duration
+		wantErr    bool
+	}{
+		{"base32", "JBSWY3DPEHPK3PXP", 6, 30 * time.Second, false},
+		{"grouped lower case", " jbsw y3dp-ehpk 3pxp ", 6, 30 * time.Second, false},
+		{"padded", "JBSWY3DPEE======", 6, 30 * time.Second, false},
+		{"uri", "otpauth://totp/GitHub:me?secret=JBSWY3DPEHPK3PXP&issuer=GitHub", 6, 30 * time.Second, false},
+		{"uri with parameters", "OTPAUTH://totp/x?secret=JBSWY3DPEHPK3PXP&digits=8&period=60&algorithm=sha256", 8, time.Minute, false},
+		{"empty", "", 0, 0, true},
+		{"not base32", "not-a-secret!", 0, 0, true},
+		{"hotp uri", "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP", 0, 0, true},
+		{"uri without secret", "otpauth://totp/x?digits=6", 0, 0, true},
+		{"too few digits", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=4", 0, 0, true},
+		{"zero period", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=0", 0, 0, true},
+		{"unknown algorithm", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totp, err := Parse(tt.secret)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSecret) {
+					t.Errorf("Parse(%q) err = %v, want ErrInvalidSecret", tt.secret, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.secret, err)
+			}
+			if totp.Digits != tt.wantDigits || totp.Period != tt.wantPeriod {
+				t.Errorf("Parse(%q) = %d digits every %s, want %d every %s", tt.secret, totp.Digits, totp.Period, tt.wantDigits, tt.wantPeriod)
+			}
+		})
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	totp, err := Parse(rfcSecretSHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   int64
+		want time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 29 * time.Second},
+		{29, time.Second},
+		{30, 30 * time.Second},
+		{59, time.Second},
+	}
+	for _, tt := range tests {
+		if got := totp.Remaining(time.Unix(tt.at, 0)); got != tt.want {
+			t.Errorf("Remaining(%d) = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+}
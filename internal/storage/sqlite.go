@@ -1,22 +1,29 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type SQLiteStorage struct {
-	db   *sql.DB
-	mu   sync.RWMutex
-	path string
+	db        *sql.DB
+	mu        sync.RWMutex
+	path      string
+	nameScope NameScope
+	limits    Limits
+	// fullText is set when entries are indexed in the FTS5 table
+	fullText bool
 }
 
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
@@ -25,31 +32,50 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	storage := &SQLiteStorage{db: db, path: dbPath}
+	storage := &SQLiteStorage{db: db, path: dbPath, nameScope: NameScopeGlobal}
+
+	if err := storage.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
 
 	return storage, nil
 }
 
+// entriesSchema creates the entries table under the given name. Entry name
+// uniqueness is enforced by an index that depends on the name scope.
+const entriesSchema = `CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	username TEXT,
+	password BLOB NOT NULL,
+	url TEXT,
+	notes TEXT,
+	tags TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+)`
+
+var entriesIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_entries_name ON entries(name)`,
+	`CREATE INDEX IF NOT EXISTS idx_entries_username ON entries(username)`,
+	`CREATE INDEX IF NOT EXISTS idx_entries_created_at ON entries(created_at)`,
+}
+
+// accessLogSchema creates the append-only access log table
+const accessLogSchema = `CREATE TABLE IF NOT EXISTS access_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp DATETIME NOT NULL,
+	entry_name TEXT NOT NULL,
+	action TEXT NOT NULL
+)`
+
 func (s *SQLiteStorage) Initialize() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS entries (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT UNIQUE NOT NULL,
-			username TEXT,
-			password BLOB NOT NULL,
-			url TEXT,
-			notes TEXT,
-			tags TEXT,
-			created_at DATETIME NOT NULL,
-			updated_at DATETIME NOT NULL
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_entries_name ON entries(name)`,
-		`CREATE INDEX IF NOT EXISTS idx_entries_username ON entries(username)`,
-		`CREATE INDEX IF NOT EXISTS idx_entries_created_at ON entries(created_at)`,
-	}
+	queries := append([]string{fmt.Sprintf(entriesSchema, "entries")}, entriesIndexes...)
+	queries = append(queries, accessLogSchema)
 
 	for _, query := range queries {
 		if _, err := s.db.Exec(query); err != nil {
@@ -57,9 +83,369 @@ func (s *SQLiteStorage) Initialize() error {
 		}
 	}
 
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
+	return s.applyNameScope()
+}
+
+// columnMigrations lists the columns added to the entries table since its
+// initial schema. They are added to existing databases when opened.
+var columnMigrations = []struct {
+	name       string
+	definition string
+}{
+	{"type", "TEXT NOT NULL DEFAULT 'login'"},
+	{"folder", "TEXT NOT NULL DEFAULT ''"},
+	{"custom_fields", "BLOB"},
+	{"aliases", "TEXT NOT NULL DEFAULT '[]'"},
+	{"delete_at", "DATETIME"},
+	{"secure_notes", "BLOB"},
+	{"password_changed_at", "DATETIME"},
+	{"expires_at", "DATETIME"},
+}
+
+func (s *SQLiteStorage) migrate() error {
+	rows, err := s.db.Query(`PRAGMA table_info(entries)`)
+	if err != nil {
+		return fmt.Errorf("failed to read table info: %w", err)
+	}
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	// Nothing to migrate until the database has been initialized
+	if len(columns) == 0 {
+		return nil
+	}
+
+	for _, column := range columnMigrations {
+		if columns[column.name] {
+			continue
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE entries ADD COLUMN %s %s`, column.name, column.definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.name, err)
+		}
+	}
+
+	// Before password changes were recorded, the last update is the best guess
+	if !columns["password_changed_at"] {
+		if _, err := s.db.Exec(`UPDATE entries SET password_changed_at = updated_at`); err != nil {
+			return fmt.Errorf("failed to fill in password change times: %w", err)
+		}
+	}
+
+	if _, err := s.db.Exec(accessLogSchema); err != nil {
+		return fmt.Errorf("failed to create access log: %w", err)
+	}
+
+	if err := s.dropInlineNameConstraint(); err != nil {
+		return err
+	}
+
+	return s.migrateFullText()
+}
+
+// fullTextSchema indexes the searchable columns of the entries table. The
+// index holds no copy of the text and is kept in sync by fullTextTriggers.
+const fullTextSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS entries_fts USING fts5(
+	name, username, url, notes,
+	content = 'entries', content_rowid = 'id'
+)`
+
+var fullTextTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS entries_fts_insert AFTER INSERT ON entries BEGIN
+		INSERT INTO entries_fts(rowid, name, username, url, notes)
+		VALUES (new.id, new.name, new.username, new.url, new.notes);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS entries_fts_delete AFTER DELETE ON entries BEGIN
+		INSERT INTO entries_fts(entries_fts, rowid, name, username, url, notes)
+		VALUES ('delete', old.id, old.name, old.username, old.url, old.notes);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS entries_fts_update AFTER UPDATE ON entries BEGIN
+		INSERT INTO entries_fts(entries_fts, rowid, name, username, url, notes)
+		VALUES ('delete', old.id, old.name, old.username, old.url, old.notes);
+		INSERT INTO entries_fts(rowid, name, username, url, notes)
+		VALUES (new.id, new.name, new.username, new.url, new.notes);
+	END`,
+}
+
+var fullTextTriggerNames = []string{"entries_fts_insert", "entries_fts_delete", "entries_fts_update"}
+
+// migrateFullText sets up the full-text index when SQLite was built with
+// FTS5 (the sqlite_fts5 build tag). The index is rebuilt whenever its
+// triggers were missing, as entries may have changed without it. Without
+// FTS5 the triggers of an index created by another build are dropped, since
+// they would make every write fail.
+func (s *SQLiteStorage) migrateFullText() error {
+	var available bool
+	if err := s.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil {
+		return fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+
+	if !available {
+		s.fullText = false
+		for _, name := range fullTextTriggerNames {
+			if _, err := s.db.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				return fmt.Errorf("failed to drop full-text trigger: %w", err)
+			}
+		}
+		return nil
+	}
+
+	var triggers int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'entries_fts_%'`
+	if err := s.db.QueryRow(query).Scan(&triggers); err != nil {
+		return fmt.Errorf("failed to check full-text triggers: %w", err)
+	}
+
+	if triggers < len(fullTextTriggers) {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		queries := append([]string{fullTextSchema}, fullTextTriggers...)
+		queries = append(queries, `INSERT INTO entries_fts(entries_fts) VALUES ('rebuild')`)
+		for _, query := range queries {
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to create full-text index: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	s.fullText = true
 	return nil
 }
 
+// fullTextQuery turns a search query into an FTS5 query matching entries
+// that contain every word, each as a prefix of a token.
+func fullTextQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// dropInlineNameConstraint rebuilds the entries table of databases created
+// with a UNIQUE constraint on the name column, which cannot be dropped in
+// place. Name uniqueness is enforced by the name scope index instead.
+func (s *SQLiteStorage) dropInlineNameConstraint() error {
+	var inline int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_index_list('entries') WHERE origin = 'u'`).Scan(&inline)
+	if err != nil {
+		return fmt.Errorf("failed to read index list: %w", err)
+	}
+	if inline == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := []string{fmt.Sprintf(entriesSchema, "entries_new")}
+	for _, column := range columnMigrations {
+		queries = append(queries, fmt.Sprintf(`ALTER TABLE entries_new ADD COLUMN %s %s`, column.name, column.definition))
+	}
+	queries = append(queries,
+		`INSERT INTO entries_new (`+entryColumns+`) SELECT `+entryColumns+` FROM entries`,
+		`DROP TABLE entries`,
+		`ALTER TABLE entries_new RENAME TO entries`,
+	)
+	queries = append(queries, entriesIndexes...)
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to rebuild entries table: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) SetLimits(limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limits = limits
+}
+
+// entryLimits returns the limits entries are validated against.
+func (s *SQLiteStorage) entryLimits() Limits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.limits
+}
+
+// SetNameScope sets whether entry names must be unique across the vault or
+// only within a folder, and updates the unique index accordingly.
+func (s *SQLiteStorage) SetNameScope(scope NameScope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch scope {
+	case "":
+		scope = NameScopeGlobal
+	case NameScopeGlobal, NameScopeFolder:
+	default:
+		return fmt.Errorf("unsupported name scope: %s", scope)
+	}
+
+	previous := s.nameScope
+	s.nameScope = scope
+	if err := s.applyNameScope(); err != nil {
+		s.nameScope = previous
+		return err
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) applyNameScope() error {
+	var tables int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'entries'`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to check entries table: %w", err)
+	}
+	// The index is created once the database is initialized
+	if tables == 0 {
+		return nil
+	}
+
+	columns := "name"
+	if s.nameScope == NameScopeFolder {
+		columns = "name, folder"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DROP INDEX IF EXISTS idx_entries_unique_name`); err != nil {
+		return fmt.Errorf("failed to drop name index: %w", err)
+	}
+
+	query := fmt.Sprintf(`CREATE UNIQUE INDEX idx_entries_unique_name ON entries(%s)`, columns)
+	if _, err := tx.Exec(query); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("entry names are not unique within the %s scope: %w", s.nameScope, ErrEntryExists)
+		}
+		return fmt.Errorf("failed to create name index: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+const entryColumns = `id, name, username, password, url, notes, tags, created_at, updated_at, type, folder, custom_fields, aliases, delete_at, secure_notes, password_changed_at, expires_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row rowScanner) (*Entry, error) {
+	var entry Entry
+	var tagsJSON, aliasesJSON string
+	var deleteAt, passwordChangedAt, expiresAt sql.NullTime
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.Name,
+		&entry.Username,
+		&entry.Password,
+		&entry.URL,
+		&entry.Notes,
+		&tagsJSON,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+		&entry.Type,
+		&entry.Folder,
+		&entry.CustomFields,
+		&aliasesJSON,
+		&deleteAt,
+		&entry.SecureNotes,
+		&passwordChangedAt,
+		&expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleteAt.Valid {
+		entry.DeleteAt = &deleteAt.Time
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = &expiresAt.Time
+	}
+
+	entry.PasswordChangedAt = entry.UpdatedAt
+	if passwordChangedAt.Valid {
+		entry.PasswordChangedAt = passwordChangedAt.Time
+	}
+
+	if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(aliasesJSON), &entry.Aliases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal aliases: %w", err)
+	}
+
+	return &entry, nil
+}
+
+func (s *SQLiteStorage) queryEntries(query string, args ...interface{}) ([]*Entry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
 func (s *SQLiteStorage) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -67,7 +453,7 @@ func (s *SQLiteStorage) Close() error {
 }
 
 func (s *SQLiteStorage) AddEntry(entry *Entry) error {
-	if err := ValidateEntry(entry); err != nil {
+	if err := ValidateEntry(entry, s.entryLimits()); err != nil {
 		return err
 	}
 
@@ -79,9 +465,19 @@ func (s *SQLiteStorage) AddEntry(entry *Entry) error {
 		return fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
+	if err := checkNameNotAlias(s.db, entry.Name); err != nil {
+		return err
+	}
+	aliases, err := marshalAliases(s.db, entry)
+	if err != nil {
+		return err
+	}
+
+	entry.PasswordChangedAt = entry.passwordChangedAt()
+
 	query := `
-		INSERT INTO entries (name, username, password, url, notes, tags, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO entries (name, username, password, url, notes, tags, created_at, updated_at, type, folder, custom_fields, aliases, delete_at, secure_notes, password_changed_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := s.db.Exec(query,
 		entry.Name,
@@ -92,6 +488,14 @@ func (s *SQLiteStorage) AddEntry(entry *Entry) error {
 		string(tags),
 		entry.CreatedAt,
 		entry.UpdatedAt,
+		entry.entryType(),
+		entry.Folder,
+		entry.CustomFields,
+		aliases,
+		entry.DeleteAt,
+		entry.SecureNotes,
+		entry.PasswordChangedAt,
+		entry.ExpiresAt,
 	)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
@@ -110,44 +514,269 @@ func (s *SQLiteStorage) AddEntry(entry *Entry) error {
 	return err
 }
 
+// bulkInsertRows is the number of entries inserted per statement by
+// AddEntries, keeping the number of bound parameters well below SQLite's limit.
+const bulkInsertRows = 500
+
+func (s *SQLiteStorage) AddEntries(entries []*Entry) error {
+	for _, entry := range entries {
+		if err := ValidateEntry(entry, s.entryLimits()); err != nil {
+			return fmt.Errorf("%w: %s", err, entry.Name)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkBatchAliases(tx, entries); err != nil {
+		return err
+	}
+
+	const columns = `name, username, password, url, notes, tags, created_at, updated_at, type, folder, custom_fields, aliases, delete_at, secure_notes, password_changed_at, expires_at`
+	const placeholders = `(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for start := 0; start < len(entries); start += bulkInsertRows {
+		batch := entries[start:min(start+bulkInsertRows, len(entries))]
+
+		values := make([]string, 0, len(batch))
+		args := make([]interface{}, 0, len(batch)*16)
+		for _, entry := range batch {
+			tags, err := json.Marshal(entry.Tags)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+
+			aliases, err := marshalAliases(tx, entry)
+			if err != nil {
+				return err
+			}
+
+			entry.PasswordChangedAt = entry.passwordChangedAt()
+
+			values = append(values, placeholders)
+			args = append(args,
+				entry.Name,
+				entry.Username,
+				entry.Password,
+				entry.URL,
+				entry.Notes,
+				string(tags),
+				entry.CreatedAt,
+				entry.UpdatedAt,
+				entry.entryType(),
+				entry.Folder,
+				entry.CustomFields,
+				aliases,
+				entry.DeleteAt,
+				entry.SecureNotes,
+				entry.PasswordChangedAt,
+				entry.ExpiresAt,
+			)
+		}
+
+		query := `INSERT INTO entries (` + columns + `) VALUES ` + strings.Join(values, ", ")
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return ErrEntryExists
+			}
+			return fmt.Errorf("failed to add entries: %w", err)
+		}
+
+		// Rows inserted by one statement get consecutive IDs
+		last, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		for i, entry := range batch {
+			entry.ID = last - int64(len(batch)-1-i)
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (s *SQLiteStorage) GetEntry(name string) (*Entry, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	query := `SELECT id, name, username, password, url, notes, tags, created_at, updated_at FROM entries WHERE name = ?`
+	query := `SELECT ` + entryColumns + ` FROM entries WHERE name = ? LIMIT 2`
 
-	var entry Entry
-	var tagsJSON string
+	entries, err := s.queryEntries(query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
 
-	err := s.db.QueryRow(query, name).Scan(
-		&entry.ID,
-		&entry.Name,
-		&entry.Username,
-		&entry.Password,
-		&entry.URL,
-		&entry.Notes,
-		&tagsJSON,
-		&entry.CreatedAt,
-		&entry.UpdatedAt,
-	)
+	switch len(entries) {
+	case 0:
+		return s.getEntryByAlias(name)
+	case 1:
+		return entries[0], nil
+	default:
+		return nil, ErrEntryAmbiguous
+	}
+}
+
+func (s *SQLiteStorage) FindEntries(name string) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + entryColumns + ` FROM entries WHERE name = ? ORDER BY folder, name`
+	entries, err := s.queryEntries(query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entries: %w", err)
+	}
+	if len(entries) > 0 {
+		return entries, nil
+	}
 
-	if err == sql.ErrNoRows {
+	query = `SELECT ` + entryColumns + ` FROM entries
+		WHERE EXISTS (SELECT 1 FROM json_each(entries.aliases) WHERE value = ?)
+		ORDER BY folder, name`
+	entries, err = s.queryEntries(query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entries by alias: %w", err)
+	}
+	return entries, nil
+}
+
+// getEntryByAlias returns the single entry that has alias among its aliases.
+func (s *SQLiteStorage) getEntryByAlias(alias string) (*Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM entries
+		WHERE EXISTS (SELECT 1 FROM json_each(entries.aliases) WHERE value = ?)
+		ORDER BY name`
+
+	entries, err := s.queryEntries(query, alias)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry by alias: %w", err)
+	}
+
+	switch len(entries) {
+	case 0:
 		return nil, ErrEntryNotFound
+	case 1:
+		return entries[0], nil
+	default:
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name
+		}
+		return nil, fmt.Errorf("%w: alias %s matches %s", ErrEntryAmbiguous, alias, strings.Join(names, ", "))
+	}
+}
+
+// queryRower is implemented by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// checkNameNotAlias checks that the name of a new entry is not an alias of
+// another entry.
+func checkNameNotAlias(db queryRower, name string) error {
+	var owner string
+	err := db.QueryRow(`SELECT name FROM entries
+		WHERE EXISTS (SELECT 1 FROM json_each(entries.aliases) WHERE value = ?)
+		LIMIT 1`, name).Scan(&owner)
+	if err == nil {
+		return fmt.Errorf("%w: %s is already an alias of %s", ErrEntryExists, name, owner)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check name %s: %w", name, err)
+	}
+	return nil
+}
+
+// marshalAliases checks that none of the entry's aliases is already the name
+// or an alias of another entry and returns them encoded for storage.
+func marshalAliases(db queryRower, entry *Entry) (string, error) {
+	query := `SELECT name FROM entries
+		WHERE id != ? AND (name = ? OR EXISTS (SELECT 1 FROM json_each(entries.aliases) WHERE value = ?))
+		LIMIT 1`
+
+	for _, alias := range entry.Aliases {
+		var owner string
+		err := db.QueryRow(query, entry.ID, alias, alias).Scan(&owner)
+		if err == nil {
+			return "", fmt.Errorf("%w: alias %s is already used by %s", ErrEntryExists, alias, owner)
+		}
+		if err != sql.ErrNoRows {
+			return "", fmt.Errorf("failed to check alias %s: %w", alias, err)
+		}
 	}
 
+	aliases := entry.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	data, err := json.Marshal(aliases)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get entry: %w", err)
+		return "", fmt.Errorf("failed to marshal aliases: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	return string(data), nil
+}
+
+// checkBatchAliases applies the checks of checkNameNotAlias to the entries
+// of one AddEntries call, reading the stored aliases once rather than per
+// entry, and the checks of marshalAliases between the entries, which cannot
+// see each other in the database before they are inserted.
+func checkBatchAliases(db *sql.Tx, entries []*Entry) error {
+	rows, err := db.Query(`SELECT entries.name, json_each.value FROM entries, json_each(entries.aliases)`)
+	if err != nil {
+		return fmt.Errorf("failed to read aliases: %w", err)
+	}
+	stored := make(map[string]string)
+	for rows.Next() {
+		var owner, alias string
+		if err := rows.Scan(&owner, &alias); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan alias: %w", err)
+		}
+		stored[alias] = owner
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read aliases: %w", err)
 	}
 
-	return &entry, nil
+	names := make(map[string]*Entry, len(entries))
+	aliases := make(map[string]*Entry)
+
+	for _, entry := range entries {
+		if owner, ok := stored[entry.Name]; ok {
+			return fmt.Errorf("%w: %s is already an alias of %s", ErrEntryExists, entry.Name, owner)
+		}
+		if owner, ok := aliases[entry.Name]; ok {
+			return fmt.Errorf("%w: %s is already an alias of %s", ErrEntryExists, entry.Name, owner.Name)
+		}
+		for _, alias := range entry.Aliases {
+			owner, ok := names[alias]
+			if !ok {
+				owner, ok = aliases[alias]
+			}
+			if ok && owner != entry {
+				return fmt.Errorf("%w: alias %s is already used by %s", ErrEntryExists, alias, owner.Name)
+			}
+		}
+
+		names[entry.Name] = entry
+		for _, alias := range entry.Aliases {
+			aliases[alias] = entry
+		}
+	}
+
+	return nil
 }
 
 func (s *SQLiteStorage) UpdateEntry(entry *Entry) error {
-	if err := ValidateEntry(entry); err != nil {
+	if err := ValidateEntry(entry, s.entryLimits()); err != nil {
 		return err
 	}
 
@@ -159,22 +788,42 @@ func (s *SQLiteStorage) UpdateEntry(entry *Entry) error {
 		return fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
+	aliases, err := marshalAliases(s.db, entry)
+	if err != nil {
+		return err
+	}
+
+	// The password change time only moves when a new password is stored
+	now := time.Now()
 	query := `
 		UPDATE entries
-		SET username = ?, password = ?, url = ?, notes = ?, tags = ?, updated_at = ?
-		WHERE name = ?
+		SET password_changed_at = CASE WHEN password IS ? THEN password_changed_at ELSE ? END,
+			username = ?, password = ?, url = ?, notes = ?, tags = ?, updated_at = ?, type = ?, folder = ?, custom_fields = ?, aliases = ?, delete_at = ?, secure_notes = ?, expires_at = ?
+		WHERE id = ?
 	`
 
 	result, err := s.db.Exec(query,
+		entry.Password,
+		now,
 		entry.Username,
 		entry.Password,
 		entry.URL,
 		entry.Notes,
 		string(tags),
-		time.Now(),
-		entry.Name,
+		now,
+		entry.entryType(),
+		entry.Folder,
+		entry.CustomFields,
+		aliases,
+		entry.DeleteAt,
+		entry.SecureNotes,
+		entry.ExpiresAt,
+		entry.ID,
 	)
 	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrEntryExists
+		}
 		return fmt.Errorf("failed to update entry: %w", err)
 	}
 
@@ -193,6 +842,14 @@ func (s *SQLiteStorage) DeleteEntry(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM entries WHERE name = ?`, name).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count entries: %w", err)
+	}
+	if count > 1 {
+		return ErrEntryAmbiguous
+	}
+
 	query := `DELETE FROM entries WHERE name = ?`
 
 	result, err := s.db.Exec(query, name)
@@ -211,100 +868,101 @@ func (s *SQLiteStorage) DeleteEntry(name string) error {
 	return nil
 }
 
-func (s *SQLiteStorage) ListEntries() ([]*Entry, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *SQLiteStorage) UpdateSecrets(entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	query := `SELECT id, name, username, password, url, notes, tags, created_at, updated_at
-			 FROM entries ORDER BY name`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	rows, err := s.db.Query(query)
+	stmt, err := tx.Prepare(`UPDATE entries SET password = ?, custom_fields = ?, notes = ?, secure_notes = ? WHERE id = ?`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query entries: %w", err)
+		return fmt.Errorf("failed to prepare update: %w", err)
 	}
-	defer rows.Close()
+	defer stmt.Close()
 
-	var entries []*Entry
-	for rows.Next() {
-		var entry Entry
-		var tagsJSON string
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Name,
-			&entry.Username,
-			&entry.Password,
-			&entry.URL,
-			&entry.Notes,
-			&tagsJSON,
-			&entry.CreatedAt,
-			&entry.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan entry: %w", err)
+	for _, entry := range entries {
+		if len(entry.Password) == 0 {
+			return ErrEntryPasswordIsReq
 		}
 
-		if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		result, err := stmt.Exec(entry.Password, entry.CustomFields, entry.Notes, entry.SecureNotes, entry.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update secrets: %w", err)
 		}
 
-		entries = append(entries, &entry)
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return ErrEntryNotFound
+		}
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating entries: %w", err)
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) ListEntries() ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + entryColumns + ` FROM entries ORDER BY name`
+
+	entries, err := s.queryEntries(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
 	}
 
 	return entries, nil
 }
 
-func (s *SQLiteStorage) SearchEntries(query string) ([]*Entry, error) {
+// SearchEntries matches query against the full-text index where available.
+// Tags are not indexed and, like every field without the index, are matched
+// as a substring.
+func (s *SQLiteStorage) SearchEntries(query string, fields ...SearchField) ([]*Entry, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if len(fields) == 0 {
+		fields = DefaultSearchFields
+	}
+
+	useFullText := s.fullText && strings.TrimSpace(query) != ""
+	var indexed, conditions []string
+	var args []interface{}
+	for _, field := range slices.Compact(slices.Sorted(slices.Values(fields))) {
+		if _, err := ParseSearchField(string(field)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+		}
+		if useFullText && field != SearchTags {
+			indexed = append(indexed, string(field))
+			continue
+		}
+		conditions = append(conditions, string(field)+" LIKE ?")
+		args = append(args, "%"+query+"%")
+	}
+
+	if len(indexed) > 0 {
+		conditions = append(conditions, "id IN (SELECT rowid FROM entries_fts WHERE entries_fts MATCH ?)")
+		args = append(args, "{"+strings.Join(indexed, " ")+"} : ("+fullTextQuery(query)+")")
+	}
+
 	sqlQuery := `
-		SELECT id, name, username, password, url, notes, tags, created_at, updated_at
+		SELECT ` + entryColumns + `
 		FROM entries
-		WHERE name LIKE ? OR username LIKE ? OR url LIKE ? OR notes LIKE ?
+		WHERE ` + strings.Join(conditions, " OR ") + `
 		ORDER BY name
 	`
 
-	searchPattern := "%" + query + "%"
-
-	rows, err := s.db.Query(sqlQuery, searchPattern, searchPattern, searchPattern, searchPattern)
+	entries, err := s.queryEntries(sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search entries: %w", err)
 	}
 
-	defer rows.Close()
-
-	var entries []*Entry
-	for rows.Next() {
-		var entry Entry
-		var tagsJSON string
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Name,
-			&entry.Username,
-			&entry.Password,
-			&entry.URL,
-			&entry.Notes,
-			&tagsJSON,
-			&entry.CreatedAt,
-			&entry.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan entry: %w", err)
-		}
-
-		if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-		}
-
-		entries = append(entries, &entry)
-	}
-
 	return entries, nil
 }
 
@@ -313,45 +971,17 @@ func (s *SQLiteStorage) GetEntriesByTag(tag string) ([]*Entry, error) {
 	defer s.mu.RUnlock()
 
 	query := `
-		SELECT id, name, username, password, url, notes, tags, created_at, updated_at
+		SELECT ` + entryColumns + `
 		FROM entries
 		WHERE tags LIKE ?
 		ORDER BY name
 	`
 
 	searchPattern := "%\"" + tag + "\"%"
-	rows, err := s.db.Query(query, searchPattern)
+	entries, err := s.queryEntries(query, searchPattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entries by tag: %w", err)
 	}
-	defer rows.Close()
-
-	var entries []*Entry
-	for rows.Next() {
-		var entry Entry
-		var tagsJSON string
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Name,
-			&entry.Username,
-			&entry.Password,
-			&entry.URL,
-			&entry.Notes,
-			&tagsJSON,
-			&entry.CreatedAt,
-			&entry.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan entry: %w", err)
-		}
-
-		if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-		}
-
-		entries = append(entries, &entry)
-	}
 
 	return entries, nil
 }
@@ -368,10 +998,19 @@ func (s *SQLiteStorage) GetStats() (*StorageStats, error) {
 		return nil, fmt.Errorf("failed to get total entries: %w", err)
 	}
 
-	oldestAndNewestQuery := `SELECT MIN(created_at), MAX(created_at) FROM entries`
-	err = s.db.QueryRow(oldestAndNewestQuery).Scan(&stats.OldestEntry, &stats.NewestEntry)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get oldest and newest entries: %w", err)
+	// An empty vault has no oldest entry or average age
+	if stats.TotalEntries == 0 {
+		return stats, nil
+	}
+
+	// MIN and MAX would return the times as text, which cannot be scanned
+	oldestQuery := `SELECT created_at FROM entries ORDER BY created_at LIMIT 1`
+	newestQuery := `SELECT created_at FROM entries ORDER BY created_at DESC LIMIT 1`
+	if err := s.db.QueryRow(oldestQuery).Scan(&stats.OldestEntry); err != nil {
+		return nil, fmt.Errorf("failed to get oldest entry: %w", err)
+	}
+	if err := s.db.QueryRow(newestQuery).Scan(&stats.NewestEntry); err != nil {
+		return nil, fmt.Errorf("failed to get newest entry: %w", err)
 	}
 
 	passwordAgeQuery := `SELECT updated_at FROM entries`
@@ -399,23 +1038,235 @@ func (s *SQLiteStorage) GetStats() (*StorageStats, error) {
 	return stats, nil
 }
 
+func (s *SQLiteStorage) LogAccess(entryName string, action AccessAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `INSERT INTO access_log (timestamp, entry_name, action) VALUES (?, ?, ?)`
+	if _, err := s.db.Exec(query, time.Now(), entryName, string(action)); err != nil {
+		return fmt.Errorf("failed to log access: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) ListAccessLog() ([]*AccessRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT id, timestamp, entry_name, action FROM access_log ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access log: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*AccessRecord
+	for rows.Next() {
+		var record AccessRecord
+		var action string
+		if err := rows.Scan(&record.ID, &record.Timestamp, &record.EntryName, &action); err != nil {
+			return nil, fmt.Errorf("failed to scan access record: %w", err)
+		}
+		record.Action = AccessAction(action)
+		records = append(records, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access log: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *SQLiteStorage) ClearAccessLog() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`DELETE FROM access_log`); err != nil {
+		return fmt.Errorf("failed to clear access log: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) Compact() (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.fileSize()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return 0, 0, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	// Fold any write-ahead log back into the database file
+	if _, err := s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return 0, 0, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+
+	after, err := s.fileSize()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return before, after, nil
+}
+
+func (s *SQLiteStorage) Reindex() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, query := range entriesIndexes {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	// Recreate the name index, which may have been dropped or corrupted
+	if err := s.applyNameScope(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`REINDEX`); err != nil {
+		return fmt.Errorf("failed to rebuild indexes: %w", err)
+	}
+
+	if s.fullText {
+		if _, err := s.db.Exec(`INSERT INTO entries_fts(entries_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to rebuild full-text index: %w", err)
+		}
+	}
+
+	if _, err := s.db.Exec(`ANALYZE`); err != nil {
+		return fmt.Errorf("failed to analyze database: %w", err)
+	}
+
+	return nil
+}
+
+// fileSize returns the size of the database file including its write-ahead log.
+func (s *SQLiteStorage) fileSize() (int64, error) {
+	var total int64
+	for _, path := range []string{s.path, s.path + "-wal"} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat database file: %w", err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// maxIntegrityErrors is the most problems IntegrityCheck reports
+const maxIntegrityErrors = 10
+
+func (s *SQLiteStorage) IntegrityCheck() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`PRAGMA integrity_check(` + fmt.Sprint(maxIntegrityErrors) + `)`)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("failed to read integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Backup writes a consistent snapshot of the database to path using the
+// SQLite online backup API, which is safe while other connections write to
+// the database. VACUUM INTO is used if the driver connection does not
+// support it; it cannot run inside a transaction.
 func (s *SQLiteStorage) Backup(path string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	backup, err := s.db.Begin()
+	err := s.onlineBackup(path)
+	if errors.Is(err, errBackupUnsupported) {
+		if _, err := s.db.Exec(`VACUUM INTO ?`, path); err != nil {
+			return fmt.Errorf("failed to backup database: %w", err)
+		}
+		return nil
+	}
+
+	return err
+}
+
+var errBackupUnsupported = errors.New("online backup not supported")
+
+func (s *SQLiteStorage) onlineBackup(path string) error {
+	ctx := context.Background()
+
+	dest, err := sql.Open("sqlite3", path)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to open backup database: %w", err)
 	}
-	defer backup.Rollback()
+	defer dest.Close()
 
-	query := `VACUUM INTO ?`
-	_, err = backup.Exec(query, path)
+	destConn, err := dest.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to backup database: %w", err)
+		return fmt.Errorf("failed to connect to backup database: %w", err)
 	}
+	defer destConn.Close()
 
-	return backup.Commit()
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			destSQLite, ok := destDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errBackupUnsupported
+			}
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errBackupUnsupported
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %w", err)
+			}
+
+			// Copy all pages in a single step so the snapshot is consistent
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return fmt.Errorf("failed to backup database: %w", err)
+			}
+
+			if err := backup.Finish(); err != nil {
+				return fmt.Errorf("failed to finish backup: %w", err)
+			}
+
+			return nil
+		})
+	})
 }
 
 func (s *SQLiteStorage) Restore(path string) error {
@@ -437,7 +1288,55 @@ func (s *SQLiteStorage) Restore(path string) error {
 
 	s.db = db
 
-	return nil
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+
+	return s.applyNameScope()
+}
+
+func (s *SQLiteStorage) RestoreTo(path, target string) (Storage, error) {
+	s.mu.RLock()
+	scope := s.nameScope
+	limits := s.limits
+	current := s.path
+	s.mu.RUnlock()
+
+	if sameFile(current, target) {
+		return nil, fmt.Errorf("%w: target is the current database", ErrInvalidOperation)
+	}
+	if _, err := os.Stat(target); err == nil {
+		return nil, fmt.Errorf("%w: target %s already exists", ErrInvalidOperation, target)
+	}
+
+	if err := copyFile(path, target); err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	restored, err := NewSQLiteStorage(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open restored database: %w", err)
+	}
+
+	if err := restored.SetNameScope(scope); err != nil {
+		restored.Close()
+		return nil, err
+	}
+	restored.SetLimits(limits)
+
+	return restored, nil
+}
+
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
 }
 
 func copyFile(src, dst string) error {
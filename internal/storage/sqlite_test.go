@@ -0,0 +1,946 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStorage returns an initialized SQLite storage in a temporary
+// directory, closed when the test ends.
+func newTestStorage(t testing.TB) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "passio.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// addEntry adds an entry with the given name and password to s.
+func addEntry(t *testing.T, s Storage, name, password string) *Entry {
+	t.Helper()
+	entry := NewEntry(name, "user", []byte(password))
+	if err := s.AddEntry(entry); err != nil {
+		t.Fatalf("AddEntry(%s): %v", name, err)
+	}
+	return entry
+}
+
+// getEntry returns the entry called name from s.
+func getEntry(t *testing.T, s Storage, name string) *Entry {
+	t.Helper()
+	entry, err := s.GetEntry(name)
+	if err != nil {
+		t.Fatalf("GetEntry(%s): %v", name, err)
+	}
+	return entry
+}
+
+func TestPasswordChangedAt(t *testing.T) {
+	s := newTestStorage(t)
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	entry := NewEntry("github", "alice", []byte("ciphertext-1"))
+	entry.CreatedAt, entry.UpdatedAt = created, created
+	if err := s.AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := getEntry(t, s, "github").PasswordChangedAt; !got.Equal(created) {
+		t.Fatalf("PasswordChangedAt of a new entry = %v, want the creation time %v", got, created)
+	}
+
+	// Updating other fields, as update --touch does, keeps the change time
+	entry = getEntry(t, s, "github")
+	entry.URL = "https://github.com"
+	if err := s.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	entry = getEntry(t, s, "github")
+	if !entry.UpdatedAt.After(created) {
+		t.Errorf("UpdatedAt = %v, want after %v", entry.UpdatedAt, created)
+	}
+	if !entry.PasswordChangedAt.Equal(created) {
+		t.Errorf("PasswordChangedAt after a metadata update = %v, want %v", entry.PasswordChangedAt, created)
+	}
+
+	// A new password moves it
+	entry.Password = []byte("ciphertext-2")
+	if err := s.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := getEntry(t, s, "github").PasswordChangedAt; !got.After(created) {
+		t.Errorf("PasswordChangedAt after a password change = %v, want after %v", got, created)
+	}
+}
+
+func TestAddEntriesKeepsPasswordChangedAt(t *testing.T) {
+	s := newTestStorage(t)
+	created := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	changed := created.Add(24 * time.Hour)
+
+	rotated := NewEntry("rotated", "alice", []byte("ciphertext"))
+	rotated.CreatedAt, rotated.PasswordChangedAt = created, changed
+	original := NewEntry("original", "bob", []byte("ciphertext"))
+	original.CreatedAt = created
+
+	if err := s.AddEntries([]*Entry{rotated, original}); err != nil {
+		t.Fatal(err)
+	}
+	if got := getEntry(t, s, "rotated").PasswordChangedAt; !got.Equal(changed) {
+		t.Errorf("PasswordChangedAt of rotated = %v, want %v", got, changed)
+	}
+	if got := getEntry(t, s, "original").PasswordChangedAt; !got.Equal(created) {
+		t.Errorf("PasswordChangedAt of original = %v, want the creation time %v", got, created)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	s := newTestStorage(t)
+	expires := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	single := NewEntry("single", "alice", []byte("ciphertext"))
+	single.ExpiresAt = &expires
+	if err := s.AddEntry(single); err != nil {
+		t.Fatal(err)
+	}
+	batched := NewEntry("batched", "bob", []byte("ciphertext"))
+	batched.ExpiresAt = &expires
+	if err := s.AddEntries([]*Entry{batched, NewEntry("none", "carol", []byte("ciphertext"))}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"single", "batched"} {
+		if got := getEntry(t, s, name).ExpiresAt; got == nil || !got.Equal(expires) {
+			t.Errorf("ExpiresAt of %s = %v, want %v", name, got, expires)
+		}
+	}
+	if got := getEntry(t, s, "none").ExpiresAt; got != nil {
+		t.Errorf("ExpiresAt of none = %v, want nil", got)
+	}
+
+	entry := getEntry(t, s, "single")
+	entry.ExpiresAt = nil
+	if err := s.UpdateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := getEntry(t, s, "single").ExpiresAt; got != nil {
+		t.Errorf("ExpiresAt after clearing it = %v, want nil", got)
+	}
+}
+
+func TestAliasCollisions(t *testing.T) {
+	withAliases := func(name string, aliases ...string) *Entry {
+		entry := NewEntry(name, "user", []byte("ciphertext"))
+		entry.Aliases = aliases
+		return entry
+	}
+
+	tests := []struct {
+		name    string
+		entries []*Entry
+	}{
+		{"name is a stored alias", []*Entry{withAliases("gh")}},
+		{"alias is a stored name", []*Entry{withAliases("hub", "github")}},
+		{"alias is a stored alias", []*Entry{withAliases("hub", "gh")}},
+		{"alias is a name in the batch", []*Entry{withAliases("gitlab"), withAliases("hub", "gitlab")}},
+		{"name is an alias in the batch", []*Entry{withAliases("hub", "gl"), withAliases("gl")}},
+		{"alias is an alias in the batch", []*Entry{withAliases("hub", "code"), withAliases("gitlab", "code")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			if err := s.AddEntry(withAliases("github", "gh")); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(tt.entries) == 1 {
+				if err := s.AddEntry(tt.entries[0]); !errors.Is(err, ErrEntryExists) {
+					t.Errorf("AddEntry: err = %v, want ErrEntryExists", err)
+				}
+			}
+			if err := s.AddEntries(tt.entries); !errors.Is(err, ErrEntryExists) {
+				t.Errorf("AddEntries: err = %v, want ErrEntryExists", err)
+			}
+			assertNames(t, entryNames(t, s), "github")
+		})
+	}
+}
+
+// baselineSchema is the entries table of the first passio release
+const baselineSchema = `CREATE TABLE entries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT UNIQUE NOT NULL,
+	username TEXT,
+	password BLOB NOT NULL,
+	url TEXT,
+	notes TEXT,
+	tags TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+)`
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passio.db")
+	created := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	updated := created.Add(30 * 24 * time.Hour)
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO entries (name, username, password, url, notes, tags, created_at, updated_at)
+		VALUES ('github', 'alice', x'0102', 'https://github.com', 'old notes', '["dev"]', ?, ?)`, created, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("opening a baseline database: %v", err)
+	}
+
+	entry := getEntry(t, s, "github")
+	if entry.Type != EntryTypeLogin || entry.Folder != "" || len(entry.Aliases) != 0 {
+		t.Errorf("migrated entry has type %q, folder %q and aliases %v, want login, none and none", entry.Type, entry.Folder, entry.Aliases)
+	}
+	if entry.Notes != "old notes" || len(entry.Tags) != 1 || entry.Tags[0] != "dev" {
+		t.Errorf("migrated entry lost its notes or tags: %q, %v", entry.Notes, entry.Tags)
+	}
+	if !entry.PasswordChangedAt.Equal(updated) {
+		t.Errorf("PasswordChangedAt = %v, want the last update %v", entry.PasswordChangedAt, updated)
+	}
+
+	// The inline UNIQUE constraint is gone, so names may repeat across folders
+	if err := s.SetNameScope(NameScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	other := NewEntry("github", "bob", []byte("ciphertext"))
+	other.Folder = "work"
+	if err := s.AddEntry(other); err != nil {
+		t.Fatalf("adding github to another folder after migration: %v", err)
+	}
+
+	// Opening the migrated database again changes nothing
+	s.Close()
+	s, err = NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("reopening a migrated database: %v", err)
+	}
+	defer s.Close()
+	entries, err := s.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ListEntries after reopening returned %d entries, want 2", len(entries))
+	}
+}
+
+func TestNameScope(t *testing.T) {
+	tests := []struct {
+		scope        NameScope
+		otherFolder  error
+		sameFolder   error
+		wantEntries  int
+		switchGlobal error
+	}{
+		{NameScopeGlobal, ErrEntryExists, ErrEntryExists, 1, nil},
+		{NameScopeFolder, nil, ErrEntryExists, 2, ErrEntryExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope), func(t *testing.T) {
+			s := newTestStorage(t)
+			if err := s.SetNameScope(tt.scope); err != nil {
+				t.Fatal(err)
+			}
+
+			work := NewEntry("github", "alice", []byte("ciphertext"))
+			work.Folder = "work"
+			if err := s.AddEntry(work); err != nil {
+				t.Fatal(err)
+			}
+
+			personal := NewEntry("github", "bob", []byte("ciphertext"))
+			personal.Folder = "personal"
+			if err := s.AddEntry(personal); !errors.Is(err, tt.otherFolder) {
+				t.Errorf("adding github to another folder: err = %v, want %v", err, tt.otherFolder)
+			}
+
+			again := NewEntry("github", "carol", []byte("ciphertext"))
+			again.Folder = "work"
+			if err := s.AddEntry(again); !errors.Is(err, tt.sameFolder) {
+				t.Errorf("adding github to the same folder: err = %v, want %v", err, tt.sameFolder)
+			}
+
+			if names := entryNames(t, s); len(names) != tt.wantEntries {
+				t.Errorf("vault holds %v, want %d entries", names, tt.wantEntries)
+			}
+
+			// Names repeated across folders prevent switching to a global scope
+			if err := s.SetNameScope(NameScopeGlobal); !errors.Is(err, tt.switchGlobal) {
+				t.Errorf("SetNameScope(global): err = %v, want %v", err, tt.switchGlobal)
+			}
+		})
+	}
+
+	s := newTestStorage(t)
+	if err := s.SetNameScope("project"); err == nil {
+		t.Error("SetNameScope accepted an unsupported scope")
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	s := newTestStorage(t)
+
+	stats, err := s.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats of an empty vault: %v", err)
+	}
+	if stats.TotalEntries != 0 {
+		t.Errorf("TotalEntries = %d, want 0", stats.TotalEntries)
+	}
+
+	oldest := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+	newest := time.Now().Add(-2 * 24 * time.Hour).Truncate(time.Second)
+	for i, created := range []time.Time{newest, oldest} {
+		entry := NewEntry(fmt.Sprintf("entry%d", i), "user", []byte("ciphertext"))
+		entry.CreatedAt, entry.UpdatedAt = created, created
+		if err := s.AddEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err = s.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalEntries != 2 {
+		t.Errorf("TotalEntries = %d, want 2", stats.TotalEntries)
+	}
+	if !stats.OldestEntry.Equal(oldest) || !stats.NewestEntry.Equal(newest) {
+		t.Errorf("oldest and newest = %v and %v, want %v and %v", stats.OldestEntry, stats.NewestEntry, oldest, newest)
+	}
+	if stats.AveragePassAge < 5.9 || stats.AveragePassAge > 6.1 {
+		t.Errorf("AveragePassAge = %.2f days, want 6", stats.AveragePassAge)
+	}
+}
+
+// searchNames returns the names of the entries matching query in fields.
+func searchNames(t *testing.T, s Storage, query string, fields ...SearchField) []string {
+	t.Helper()
+	entries, err := s.SearchEntries(query, fields...)
+	if err != nil {
+		t.Fatalf("SearchEntries(%q): %v", query, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func assertNames(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+}
+
+// The index is only used with the sqlite_fts5 build tag, so run the tests
+// with and without it. Queries match both as FTS5 prefixes and as substrings.
+func TestSearchEntries(t *testing.T) {
+	s := newTestStorage(t)
+	github := addEntry(t, s, "github", "hunter2")
+	gitlab := addEntry(t, s, "gitlab", "hunter2")
+	gitlab.URL = "https://gitlab.example.com"
+	gitlab.Notes = "work account"
+	if err := s.UpdateEntry(gitlab); err != nil {
+		t.Fatal(err)
+	}
+	bank := addEntry(t, s, "bank", "hunter2")
+	bank.Tags = []string{"finance"}
+	if err := s.UpdateEntry(bank); err != nil {
+		t.Fatal(err)
+	}
+
+	assertNames(t, searchNames(t, s, "git"), "github", "gitlab")
+	assertNames(t, searchNames(t, s, "work"), "gitlab")
+	assertNames(t, searchNames(t, s, "gitlab", SearchURL), "gitlab")
+	assertNames(t, searchNames(t, s, "work", SearchName), []string{}...)
+	assertNames(t, searchNames(t, s, "finance", SearchTags), "bank")
+	assertNames(t, searchNames(t, s, "nothing"), []string{}...)
+
+	if _, err := s.SearchEntries("git", "password"); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("searching the password field: %v, want %v", err, ErrInvalidOperation)
+	}
+
+	// Updates and deletes are reflected in the index
+	github.Notes = "personal account"
+	github.Username = "octocat"
+	if err := s.UpdateEntry(github); err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, searchNames(t, s, "account"), "github", "gitlab")
+	assertNames(t, searchNames(t, s, "octocat"), "github")
+	assertNames(t, searchNames(t, s, "user", SearchUsername), "bank", "gitlab")
+
+	if err := s.DeleteEntry("gitlab"); err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, searchNames(t, s, "git"), "github")
+	assertNames(t, searchNames(t, s, "work"), []string{}...)
+}
+
+func TestFullTextTriggers(t *testing.T) {
+	s := newTestStorage(t)
+
+	var triggers int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'entries_fts_%'`).Scan(&triggers); err != nil {
+		t.Fatal(err)
+	}
+	want := 0
+	if s.fullText {
+		want = len(fullTextTriggers)
+	}
+	if triggers != want {
+		t.Errorf("%d full-text triggers with full-text search %v, want %d", triggers, s.fullText, want)
+	}
+	if !s.fullText {
+		// Triggers left by a build with FTS5 would make every write fail
+		if _, err := s.db.Exec(fullTextTriggers[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.migrateFullText(); err != nil {
+			t.Fatal(err)
+		}
+		addEntry(t, s, "github", "hunter2")
+		t.Skip("SQLite was built without FTS5; run with -tags sqlite_fts5")
+	}
+
+	// Entries written while the triggers were missing are indexed once they
+	// are restored
+	for _, name := range fullTextTriggerNames {
+		if _, err := s.db.Exec(`DROP TRIGGER ` + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addEntry(t, s, "github", "hunter2")
+	if err := s.migrateFullText(); err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, searchNames(t, s, "github"), "github")
+
+	var indexed int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM entries_fts WHERE entries_fts MATCH 'github'`).Scan(&indexed); err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 1 {
+		t.Errorf("%d index rows match the entry, want 1", indexed)
+	}
+}
+
+func TestFullTextQuery(t *testing.T) {
+	tests := map[string]string{
+		"git":           `"git"*`,
+		"  work  acct ": `"work"* "acct"*`,
+		`say "hi"`:      `"say"* """hi"""*`,
+	}
+	for query, want := range tests {
+		if got := fullTextQuery(query); got != want {
+			t.Errorf("fullTextQuery(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
+
+// newEntries returns n entries named prefix-0 to prefix-(n-1).
+func newEntries(prefix string, n int) []*Entry {
+	entries := make([]*Entry, n)
+	for i := range entries {
+		entries[i] = NewEntry(fmt.Sprintf("%s-%d", prefix, i), "user", []byte(fmt.Sprintf("password-%d", i)))
+	}
+	return entries
+}
+
+func TestAddEntriesAcrossBatches(t *testing.T) {
+	s := newTestStorage(t)
+	addEntry(t, s, "existing", "hunter2")
+
+	for _, n := range []int{0, 1, bulkInsertRows, 2*bulkInsertRows + 7} {
+		entries := newEntries(fmt.Sprint("batch", n), n)
+		if err := s.AddEntries(entries); err != nil {
+			t.Fatalf("AddEntries(%d entries): %v", n, err)
+		}
+
+		// Every entry gets the ID of its own row
+		for _, entry := range entries {
+			stored := getEntry(t, s, entry.Name)
+			if stored.ID != entry.ID || string(stored.Password) != string(entry.Password) {
+				t.Fatalf("%s stored as ID %d with %q, but was given ID %d and %q",
+					entry.Name, stored.ID, stored.Password, entry.ID, entry.Password)
+			}
+		}
+	}
+
+	all, err := s.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + 1 + bulkInsertRows + 2*bulkInsertRows + 7; len(all) != want {
+		t.Errorf("%d entries stored, want %d", len(all), want)
+	}
+}
+
+func TestAddEntriesIsAtomic(t *testing.T) {
+	s := newTestStorage(t)
+	addEntry(t, s, "existing", "hunter2")
+
+	// The conflict is in the last batch, after earlier batches were inserted
+	entries := newEntries("import", bulkInsertRows+10)
+	entries[len(entries)-1].Name = "existing"
+	if err := s.AddEntries(entries); !errors.Is(err, ErrEntryExists) {
+		t.Fatalf("AddEntries with a duplicate name: %v, want %v", err, ErrEntryExists)
+	}
+
+	// An invalid entry is rejected before anything is written
+	entries = newEntries("invalid", 3)
+	entries[2].Password = nil
+	if err := s.AddEntries(entries); err == nil {
+		t.Fatal("AddEntries accepted an entry without a password")
+	}
+
+	all, err := s.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("%d entries stored after failed imports, want only the existing one", len(all))
+	}
+}
+
+// benchmarkSizes are the numbers of entries the storage benchmarks use
+var benchmarkSizes = []int{100, 1000, 10000}
+
+// BenchmarkAddEntries compares adding entries one at a time, as imports did
+// before AddEntries, with a single bulk AddEntries call.
+func BenchmarkAddEntries(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("AddEntry/%d", n), func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				s := newTestStorage(b)
+				entries := newEntries("bench", n)
+				b.StartTimer()
+
+				for _, entry := range entries {
+					if err := s.AddEntry(entry); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("AddEntries/%d", n), func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				s := newTestStorage(b)
+				entries := newEntries("bench", n)
+				b.StartTimer()
+
+				if err := s.AddEntries(entries); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkListAndSearch measures listing and searching vaults of growing
+// size.
+func BenchmarkListAndSearch(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		s := newTestStorage(b)
+		if err := s.AddEntries(newEntries("bench", n)); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("ListEntries/%d", n), func(b *testing.B) {
+			for range b.N {
+				if _, err := s.ListEntries(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("SearchEntries/%d", n), func(b *testing.B) {
+			for range b.N {
+				if _, err := s.SearchEntries("bench-42"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	s := newTestStorage(t)
+
+	records, err := s.ListAccessLog()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("ListAccessLog of a new vault = %v, %v, want no records", records, err)
+	}
+
+	start := time.Now().Add(-time.Second)
+	logged := []struct {
+		name   string
+		action AccessAction
+	}{
+		{"github", AccessReveal},
+		{"gitlab", AccessUpdate},
+		{"github", AccessDelete},
+	}
+	for _, l := range logged {
+		if err := s.LogAccess(l.name, l.action); err != nil {
+			t.Fatalf("LogAccess(%s, %s): %v", l.name, l.action, err)
+		}
+	}
+
+	records, err = s.ListAccessLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(logged) {
+		t.Fatalf("ListAccessLog returned %d records, want %d", len(records), len(logged))
+	}
+	for i, record := range records {
+		if record.EntryName != logged[i].name || record.Action != logged[i].action {
+			t.Errorf("record %d = %s %s, want %s %s", i, record.EntryName, record.Action, logged[i].name, logged[i].action)
+		}
+		if record.Timestamp.Before(start) || record.Timestamp.After(time.Now()) {
+			t.Errorf("record %d has timestamp %v, want the time it was logged", i, record.Timestamp)
+		}
+	}
+
+	// Deleting an entry keeps its history
+	addEntry(t, s, "github", "ciphertext")
+	if err := s.DeleteEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+	if records, err = s.ListAccessLog(); err != nil || len(records) != len(logged) {
+		t.Errorf("ListAccessLog after deleting an entry = %d records, %v, want %d", len(records), err, len(logged))
+	}
+
+	if err := s.ClearAccessLog(); err != nil {
+		t.Fatalf("ClearAccessLog: %v", err)
+	}
+	if records, err = s.ListAccessLog(); err != nil || len(records) != 0 {
+		t.Errorf("ListAccessLog after clearing = %v, %v, want no records", records, err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := newTestStorage(t)
+	entries := newEntries("entry", 500)
+	for _, entry := range entries {
+		entry.Notes = strings.Repeat("n", 1000)
+	}
+	if err := s.AddEntries(entries); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries[1:] {
+		if err := s.DeleteEntry(entry.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, after, err := s.Compact()
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if after >= before {
+		t.Errorf("Compact shrank the database from %d to %d bytes, want it smaller", before, after)
+	}
+	if size, err := s.fileSize(); err != nil || size != after {
+		t.Errorf("database is %d bytes after Compact, want the reported %d (err %v)", size, after, err)
+	}
+	assertNames(t, entryNames(t, s), entries[0].Name)
+}
+
+func TestReindex(t *testing.T) {
+	s := newTestStorage(t)
+	addEntry(t, s, "github", "ciphertext")
+
+	indexes := []string{"idx_entries_name", "idx_entries_username", "idx_entries_created_at", "idx_entries_unique_name"}
+	for _, index := range indexes {
+		if _, err := s.db.Exec(`DROP INDEX ` + index); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Reindex(); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+
+	for _, index := range indexes {
+		var count int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, index).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("index %s missing after Reindex", index)
+		}
+	}
+
+	// Query planner statistics are refreshed
+	var stats int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_stat1`).Scan(&stats); err != nil {
+		t.Fatalf("no query statistics after Reindex: %v", err)
+	}
+	if stats == 0 {
+		t.Error("sqlite_stat1 is empty after Reindex")
+	}
+
+	// The recreated name index enforces unique names again
+	if err := s.AddEntry(NewEntry("github", "bob", []byte("ciphertext"))); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("adding a duplicate after Reindex: err = %v, want %v", err, ErrEntryExists)
+	}
+	assertNames(t, searchNames(t, s, "git"), "github")
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	s := newTestStorage(t)
+	addEntry(t, s, "github", "hunter2")
+	addEntry(t, s, "gitlab", "swordfish")
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := s.Backup(backupPath); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	// Changes after the backup are undone by restoring it
+	if err := s.DeleteEntry("github"); err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, s, "bank", "letmein")
+
+	backup, err := NewSQLiteStorage(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backup.IntegrityCheck(); err != nil {
+		t.Errorf("IntegrityCheck of the backup: %v", err)
+	}
+	assertNames(t, entryNames(t, backup), "github", "gitlab")
+	backup.Close()
+
+	if err := s.Restore(backupPath); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	assertNames(t, entryNames(t, s), "github", "gitlab")
+	if got := getEntry(t, s, "github"); string(got.Password) != "hunter2" {
+		t.Errorf("restored password = %q", got.Password)
+	}
+
+	// The restored database is usable
+	addEntry(t, s, "bank", "letmein")
+	assertNames(t, searchNames(t, s, "bank"), "bank")
+}
+
+func TestBackupReplacesEarlierBackup(t *testing.T) {
+	s := newTestStorage(t)
+	addEntry(t, s, "github", "hunter2")
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := s.Backup(backupPath); err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, s, "gitlab", "swordfish")
+
+	// A second backup to the same path overwrites the first
+	if err := s.Backup(backupPath); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := NewSQLiteStorage(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	assertNames(t, entryNames(t, backup), "github", "gitlab")
+}
+
+// entryNames returns the names of every entry in s.
+func entryNames(t *testing.T, s Storage) []string {
+	t.Helper()
+	entries, err := s.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func TestRestoreTo(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.SetNameScope(NameScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, s, "github", "hunter2")
+
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := s.Backup(backupPath); err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, s, "gitlab", "swordfish")
+
+	target := filepath.Join(dir, "restored.db")
+	restored, err := s.RestoreTo(backupPath, target)
+	if err != nil {
+		t.Fatalf("RestoreTo: %v", err)
+	}
+	defer restored.Close()
+
+	assertNames(t, entryNames(t, restored), "github")
+	assertNames(t, entryNames(t, s), "github", "gitlab")
+
+	// The restored storage keeps the name scope of the current one
+	work := NewEntry("github", "user", []byte("hunter3"))
+	work.Folder = "work"
+	if err := restored.AddEntry(work); err != nil {
+		t.Errorf("the restored storage does not scope names by folder: %v", err)
+	}
+
+	if _, err := s.RestoreTo(backupPath, target); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("RestoreTo an existing file: %v, want %v", err, ErrInvalidOperation)
+	}
+	if _, err := s.RestoreTo(backupPath, s.path); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("RestoreTo the current database: %v, want %v", err, ErrInvalidOperation)
+	}
+	assertNames(t, entryNames(t, s), "github", "gitlab")
+}
+
+func TestIntegrityCheck(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.AddEntries(newEntries("entry", 200)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.IntegrityCheck(); err != nil {
+		t.Fatalf("IntegrityCheck of a healthy database: %v", err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := s.Backup(backupPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite the last page with junk past its b-tree page header, so the
+	// database still opens
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageSize := int(data[16])<<8 | int(data[17])
+	for i := len(data) - pageSize + 8; i < len(data); i++ {
+		data[i] = 0x5a
+	}
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt, err := NewSQLiteStorage(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corrupt.Close()
+	if err := corrupt.IntegrityCheck(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("IntegrityCheck of a corrupt database: %v, want %v", err, ErrCorrupt)
+	}
+}
+
+func TestOpenNonDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junk.db")
+	if err := os.WriteFile(path, []byte(strings.Repeat("not a database ", 512)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := NewSQLiteStorage(path); err == nil {
+		s.Close()
+		t.Error("opened a file that is not a database")
+	}
+}
+
+func TestValidateEntryLimits(t *testing.T) {
+	limits := Limits{MaxNameLength: 8, MaxPasswordLength: 16, MaxNotesLength: 32}
+
+	tests := []struct {
+		name    string
+		entry   *Entry
+		limits  Limits
+		wantErr error
+	}{
+		{"within limits", &Entry{Name: "github", Password: []byte("hunter2"), Notes: "notes"}, limits, nil},
+		{"at the limits", &Entry{Name: strings.Repeat("n", 8), Password: make([]byte, 16), Notes: strings.Repeat("x", 32)}, limits, nil},
+		{"long name", &Entry{Name: strings.Repeat("n", 9), Password: []byte("hunter2")}, limits, ErrEntryTooLarge},
+		{"long password", &Entry{Name: "github", Password: make([]byte, 17)}, limits, ErrEntryTooLarge},
+		{"long notes", &Entry{Name: "github", Password: []byte("hunter2"), Notes: strings.Repeat("x", 33)}, limits, ErrEntryTooLarge},
+		{"long encrypted notes", &Entry{Name: "github", Password: []byte("hunter2"), SecureNotes: make([]byte, 33)}, limits, ErrEntryTooLarge},
+		{"zero limits are the defaults", &Entry{Name: "github", Password: make([]byte, DefaultLimits.MaxPasswordLength)}, Limits{}, nil},
+		{"beyond the default", &Entry{Name: "github", Password: make([]byte, DefaultLimits.MaxPasswordLength+1)}, Limits{}, ErrEntryTooLarge},
+		{"no name", &Entry{Password: []byte("hunter2")}, limits, ErrEntryNameIsReq},
+		{"no password", &Entry{Name: "github"}, limits, ErrEntryPasswordIsReq},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEntry(tt.entry, tt.limits)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("ValidateEntry = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	s := newTestStorage(t)
+	s.SetLimits(Limits{MaxNotesLength: 8})
+
+	entry := addEntry(t, s, "github", "hunter2")
+	entry.Notes = "too long for the limit"
+	if err := s.UpdateEntry(entry); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("UpdateEntry with long notes: err = %v, want ErrEntryTooLarge", err)
+	}
+
+	long := NewEntry("gitlab", "user", []byte("hunter2"))
+	long.Notes = "too long for the limit"
+	if err := s.AddEntry(long); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("AddEntry with long notes: err = %v, want ErrEntryTooLarge", err)
+	}
+	err := s.AddEntries([]*Entry{NewEntry("mail", "user", []byte("hunter2")), long})
+	if !errors.Is(err, ErrEntryTooLarge) || !strings.Contains(err.Error(), "gitlab") {
+		t.Errorf("AddEntries with long notes: err = %v, want ErrEntryTooLarge naming gitlab", err)
+	}
+	assertNames(t, entryNames(t, s), "github")
+
+	s.SetLimits(Limits{})
+	if err := s.AddEntry(long); err != nil {
+		t.Errorf("AddEntry with the default limits: %v", err)
+	}
+}
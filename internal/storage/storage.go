@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -13,18 +14,121 @@ var (
 	ErrInvalidOperation   = errors.New("invalid operation")
 	ErrEntryNameIsReq     = errors.New("entry name is required")
 	ErrEntryPasswordIsReq = errors.New("entry password is required")
+	ErrEntryAmbiguous     = errors.New("entry name matches more than one entry")
+	ErrEntryTooLarge      = errors.New("entry field too large")
+	ErrCorrupt            = errors.New("database is corrupt")
 )
 
+// NameScope controls where entry names must be unique
+type NameScope string
+
+const (
+	NameScopeGlobal NameScope = "global"
+	NameScopeFolder NameScope = "folder"
+)
+
+// SearchField names an entry field SearchEntries can match against
+type SearchField string
+
+const (
+	SearchName     SearchField = "name"
+	SearchUsername SearchField = "username"
+	SearchURL      SearchField = "url"
+	SearchNotes    SearchField = "notes"
+	SearchTags     SearchField = "tags"
+)
+
+// DefaultSearchFields are searched when SearchEntries is given no fields
+var DefaultSearchFields = []SearchField{SearchName, SearchUsername, SearchURL, SearchNotes}
+
+// ParseSearchField returns the search field called name.
+func ParseSearchField(name string) (SearchField, error) {
+	switch field := SearchField(name); field {
+	case SearchName, SearchUsername, SearchURL, SearchNotes, SearchTags:
+		return field, nil
+	default:
+		return "", fmt.Errorf("unknown search field %q (use name, username, url, notes or tags)", name)
+	}
+}
+
+type EntryType string
+
+const (
+	// EntryTypeLogin is a regular entry whose password can be revealed
+	EntryTypeLogin EntryType = "login"
+	// EntryTypeWriteOnly is an entry whose secret can only be verified, never revealed
+	EntryTypeWriteOnly EntryType = "write-only"
+)
+
+// AccessAction is a sensitive operation recorded in the access log
+type AccessAction string
+
+const (
+	AccessUpdate AccessAction = "update"
+	AccessDelete AccessAction = "delete"
+	// AccessReveal records that an entry's password was shown, viewed or copied
+	AccessReveal AccessAction = "reveal"
+)
+
+// AccessRecord is a single access log entry. It never holds secret values.
+type AccessRecord struct {
+	ID        int64        `json:"id"`
+	Timestamp time.Time    `json:"timestamp"`
+	EntryName string       `json:"entry_name"`
+	Action    AccessAction `json:"action"`
+}
+
 type Entry struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Username  string    `json:"username"`
-	Password  []byte    `json:"password"` // Encrypted password
-	URL       string    `json:"url"`
-	Notes     string    `json:"notes"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+	Password []byte    `json:"password"` // Encrypted password
+	URL      string    `json:"url"`
+	Notes    string    `json:"notes"`
+	Tags     []string  `json:"tags"`
+	Aliases  []string  `json:"aliases"`
+	Type     EntryType `json:"type"`
+	Folder   string    `json:"folder"`
+	// Encrypted JSON object of custom key-value fields
+	CustomFields []byte `json:"custom_fields,omitempty"`
+	// Encrypted notes, which replace Notes once moved by 'pm encrypt-notes'
+	SecureNotes []byte    `json:"secure_notes,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Time the password was last set. UpdateEntry only moves it when the
+	// stored password changes, unlike UpdatedAt.
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	// Time after which the entry is deleted by 'pm prune --expired-ttl'
+	DeleteAt *time.Time `json:"delete_at,omitempty"`
+	// Time after which the password counts as expired, overriding the
+	// password_expiration setting for this entry
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IsWriteOnly reports whether the entry's secret must never be revealed.
+func (e *Entry) IsWriteOnly() bool {
+	return e.Type == EntryTypeWriteOnly
+}
+
+// IsPastTTL reports whether the entry has a deletion time before now.
+func (e *Entry) IsPastTTL(now time.Time) bool {
+	return e.DeleteAt != nil && !e.DeleteAt.After(now)
+}
+
+// passwordChangedAt returns the password change time to store for a new
+// entry: PasswordChangedAt, or else the creation time.
+func (e *Entry) passwordChangedAt() time.Time {
+	if e.PasswordChangedAt.IsZero() {
+		return e.CreatedAt
+	}
+	return e.PasswordChangedAt
+}
+
+func (e *Entry) entryType() EntryType {
+	if e.Type == "" {
+		return EntryTypeLogin
+	}
+	return e.Type
 }
 
 type Storage interface {
@@ -32,20 +136,59 @@ type Storage interface {
 	Initialize() error
 	Close() error
 
+	// SetNameScope sets whether entry names are unique globally or per folder
+	SetNameScope(scope NameScope) error
+	// SetLimits sets the maximum sizes of the fields of added and updated entries
+	SetLimits(limits Limits)
+
 	// CRUD
 	AddEntry(entry *Entry) error
+	// AddEntries adds all entries in a single transaction. Every entry is
+	// validated first and nothing is added if any of them is invalid.
+	AddEntries(entries []*Entry) error
+	// GetEntry returns the entry with the given name, or else the entry that
+	// has it as an alias
 	GetEntry(name string) (*Entry, error)
+	// FindEntries returns every entry with the given name, or else every entry
+	// that has it as an alias, ordered by folder and name
+	FindEntries(name string) ([]*Entry, error)
 	UpdateEntry(entry *Entry) error
 	DeleteEntry(name string) error
 
+	// UpdateSecrets stores the encrypted password, custom fields and notes,
+	// both plain and encrypted, of the given entries, matched by ID, in a
+	// single transaction. Other fields and the modification time are left
+	// untouched.
+	UpdateSecrets(entries []*Entry) error
+
 	// Query
 	ListEntries() ([]*Entry, error)
-	SearchEntries(query string) ([]*Entry, error)
+	// SearchEntries returns the entries matching query in any of the given
+	// fields, or in DefaultSearchFields if none are given
+	SearchEntries(query string, fields ...SearchField) ([]*Entry, error)
 	GetEntriesByTag(tag string) ([]*Entry, error)
 
 	// Backup and restore
 	Backup(path string) error
 	Restore(path string) error
+	// RestoreTo restores the backup at path into a new database at target,
+	// leaving the current database untouched, and returns a handle to it
+	RestoreTo(path, target string) (Storage, error)
+
+	// Access log
+	LogAccess(entryName string, action AccessAction) error
+	ListAccessLog() ([]*AccessRecord, error)
+	ClearAccessLog() error
+
+	// Maintenance
+	// Compact reclaims unused space and returns the size before and after
+	Compact() (before int64, after int64, err error)
+	// Reindex rebuilds the indexes derived from the entries and refreshes
+	// the statistics used to plan queries
+	Reindex() error
+	// IntegrityCheck checks the database for corruption. Problems found are
+	// reported in an error wrapping ErrCorrupt.
+	IntegrityCheck() error
 
 	// Stats
 	GetStats() (*StorageStats, error)
@@ -71,7 +214,40 @@ type SearchOptions struct {
 	Offset    int      `json:"offset"`
 }
 
-func ValidateEntry(entry *Entry) error {
+// Limits are the maximum sizes in bytes of entry fields. Encrypted fields are
+// limited by their stored, encrypted size. A zero limit is replaced by the one
+// in DefaultLimits.
+type Limits struct {
+	MaxNameLength     int
+	MaxPasswordLength int
+	MaxNotesLength    int
+}
+
+// DefaultLimits are generous enough for any real entry, but keep a malformed
+// import from filling the vault.
+var DefaultLimits = Limits{
+	MaxNameLength:     1024,
+	MaxPasswordLength: 4096,
+	MaxNotesLength:    64 * 1024,
+}
+
+// Normalized returns l with zero limits replaced by DefaultLimits.
+func (l Limits) Normalized() Limits {
+	if l.MaxNameLength == 0 {
+		l.MaxNameLength = DefaultLimits.MaxNameLength
+	}
+	if l.MaxPasswordLength == 0 {
+		l.MaxPasswordLength = DefaultLimits.MaxPasswordLength
+	}
+	if l.MaxNotesLength == 0 {
+		l.MaxNotesLength = DefaultLimits.MaxNotesLength
+	}
+	return l
+}
+
+// ValidateEntry checks that entry has a name and password and that its
+// fields are within limits.
+func ValidateEntry(entry *Entry, limits Limits) error {
 	if entry == nil {
 		return ErrInvalidEntry
 	}
@@ -84,6 +260,21 @@ func ValidateEntry(entry *Entry) error {
 		return ErrEntryPasswordIsReq
 	}
 
+	limits = limits.Normalized()
+	for _, field := range []struct {
+		name      string
+		size, max int
+	}{
+		{"name", len(entry.Name), limits.MaxNameLength},
+		{"password", len(entry.Password), limits.MaxPasswordLength},
+		{"notes", len(entry.Notes), limits.MaxNotesLength},
+		{"encrypted notes", len(entry.SecureNotes), limits.MaxNotesLength},
+	} {
+		if field.size > field.max {
+			return fmt.Errorf("%w: %s is %d bytes, more than the limit of %d", ErrEntryTooLarge, field.name, field.size, field.max)
+		}
+	}
+
 	return nil
 }
 
@@ -94,6 +285,8 @@ func NewEntry(name, username string, password []byte) *Entry {
 		Username:  username,
 		Password:  password,
 		Tags:      make([]string, 0),
+		Aliases:   make([]string, 0),
+		Type:      EntryTypeLogin,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}